@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/cloud"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/network"
+	"github.com/alexperezortuno/cloudrip/internal/interfaces/cli"
+	"github.com/rs/zerolog"
+)
+
+// runCloudRanges descarga los archivos de rangos cloud para usarlos offline
+// con -cloud-ranges-dir
+func runCloudRanges(logger zerolog.Logger, args []string) {
+	rangesConfig, err := cli.ParseCloudRangesFlags(args, cloud.DefaultFeeds)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Error parseando flags de cloud-ranges")
+	}
+
+	binder, err := network.NewBinder(domain.ScannerConfig{
+		SourceIP:  rangesConfig.SourceIP,
+		Interface: rangesConfig.Interface,
+	})
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Error configurando red de origen")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	feeds := map[string]string{
+		cloud.ProviderAWS:    rangesConfig.AWSURL,
+		cloud.ProviderGCP:    rangesConfig.GCPURL,
+		cloud.ProviderAzure:  rangesConfig.AzureURL,
+		cloud.ProviderOracle: rangesConfig.OracleURL,
+	}
+	if rangesConfig.AzureURL == "" {
+		logger.Warn().Msg("Sin -azure-url: copie ServiceTags_Public_*.json al directorio manualmente")
+	}
+
+	refresher := cloud.NewRefresher(binder.HTTPClient(rangesConfig.Timeout), logger)
+	sources, err := refresher.Refresh(ctx, rangesConfig.Dir, feeds)
+	if err != nil {
+		logger.Error().Err(err).Int("updated", len(sources)).Msg("Actualización de rangos cloud incompleta")
+		os.Exit(1)
+	}
+
+	logger.Info().Str("dir", rangesConfig.Dir).Int("updated", len(sources)).Msg("Rangos cloud actualizados")
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/fixturedns"
+	"github.com/alexperezortuno/cloudrip/internal/interfaces/cli"
+	"github.com/rs/zerolog"
+)
+
+// runFixtureDNS sirve una zona local hasta recibir SIGINT/SIGTERM
+func runFixtureDNS(logger zerolog.Logger, args []string) {
+	fixtureConfig, err := cli.ParseFixtureDNSFlags(args)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Error parseando flags de fixture-dns")
+	}
+
+	zone, err := fixturedns.LoadZoneFile(fixtureConfig.ZoneFile, fixtureConfig.Origin)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Error cargando zona")
+	}
+
+	server := fixturedns.NewServer(zone, logger)
+	server.SetAllowAXFR(!fixtureConfig.NoAXFR)
+	for _, spec := range fixtureConfig.Failures {
+		failure, err := fixturedns.ParseFailure(spec)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Regla de falla inválida")
+		}
+		server.AddFailure(failure)
+	}
+
+	if err := server.Start(fixtureConfig.Listen); err != nil {
+		logger.Fatal().Err(err).Msg("Error iniciando servidor DNS de fixtures")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	<-ctx.Done()
+
+	if err := server.Close(); err != nil {
+		logger.Warn().Err(err).Msg("Error deteniendo servidor DNS de fixtures")
+	}
+}
@@ -6,22 +6,51 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/core/ports"
 	"github.com/alexperezortuno/cloudrip/internal/core/service"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/cdn"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/cloud"
 	"github.com/alexperezortuno/cloudrip/internal/infrastructure/cloudflare"
 	"github.com/alexperezortuno/cloudrip/internal/infrastructure/config"
 	"github.com/alexperezortuno/cloudrip/internal/infrastructure/dns"
 	"github.com/alexperezortuno/cloudrip/internal/infrastructure/file"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/httpprobe"
 	"github.com/alexperezortuno/cloudrip/internal/infrastructure/logging"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/network"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/pcap"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/portscan"
 	"github.com/alexperezortuno/cloudrip/internal/infrastructure/progress"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/techdetect"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/tlsprobe"
 	"github.com/alexperezortuno/cloudrip/internal/interfaces/cli"
+	"github.com/rs/zerolog"
 )
 
 func main() {
 	// Configurar logging
 	logger := logging.NewLogger()
 
+	// Subcomandos
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fixture-dns":
+			runFixtureDNS(logger, os.Args[2:])
+			return
+		case "cloud-ranges":
+			runCloudRanges(logger, os.Args[2:])
+			return
+		case "ranges":
+			runRanges(logger, os.Args[2:])
+			return
+		case "remediate":
+			runRemediate(logger, os.Args[2:])
+			return
+		}
+	}
+
 	// Parsear flags de CLI
 	cliConfig, err := cli.ParseFlags()
 	if err != nil {
@@ -76,8 +105,145 @@ func main() {
 	defer cancel()
 
 	// Inicializar dependencias
-	dnsResolver := dns.NewResolver(logger)
-	cloudflareService := cloudflare.NewService(logger)
+	binder, err := network.NewBinder(*cfg)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Error configurando red de origen")
+	}
+
+	networkResolver := dns.NewResolver(logger, binder)
+	if cfg.Resolver != "" {
+		networkResolver.SetNameserver(cfg.Resolver)
+	}
+
+	var pcapWriter *pcap.Writer
+	if cfg.PcapFile != "" {
+		pcapWriter, err = pcap.Create(cfg.PcapFile)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Error creando archivo pcap")
+		}
+		networkResolver.SetCapture(pcapWriter)
+	}
+
+	var dnsResolver ports.DNSResolver = networkResolver
+	if cfg.ReplayDNS != "" {
+		replayer, err := dns.NewReplayer(cfg.ReplayDNS, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Error cargando transcript DNS")
+		}
+		dnsResolver = replayer
+		// Un replay no debe tocar la red ni depender de la caché local: los
+		// rangos son los incluidos en el binario o los de -ranges-file
+		cfg.NoFetchCF = true
+		cfg.CacheDir = ""
+	}
+
+	// El transcript guarda las respuestas reales; las fallas inyectadas se
+	// aplican por encima y no quedan grabadas
+	var recorder *dns.Recorder
+	if cfg.RecordDNS != "" {
+		recorder, err = dns.NewRecorder(dnsResolver, cfg.RecordDNS, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Error creando transcript DNS")
+		}
+		dnsResolver = recorder
+	}
+
+	var faultInjector *dns.FaultInjector
+	if cfg.Faults != "" {
+		faultConfig, err := dns.ParseFaultSpec(cfg.Faults)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Especificación de fallas inválida")
+		}
+		faultInjector = dns.NewFaultInjector(dnsResolver, faultConfig, logger)
+		dnsResolver = faultInjector
+		logger.Warn().Str("faults", cfg.Faults).Msg("Inyección de fallas DNS activa")
+	}
+
+	cloudflareService := cloudflare.NewService(logger, binder)
+	cloudflareService.SetCache(cfg.CacheDir, cfg.RangesMaxAge)
+	if cfg.RangesFile != "" {
+		cloudflareService.SetRangesFile(cfg.RangesFile)
+	}
+
+	providerRegistry, err := cdn.NewRegistry(logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Error cargando proveedores CDN")
+	}
+	for _, path := range cfg.ProvidersFiles {
+		if err := providerRegistry.LoadFile(path); err != nil {
+			logger.Fatal().Err(err).Str("path", path).Msg("Error cargando archivo de proveedores")
+		}
+	}
+	warnUnknownProviders(logger, providerRegistry, cfg.IncludeProviders)
+
+	var cloudClassifier ports.CloudClassifier
+	if cfg.CloudRangesDir != "" {
+		classifier, err := cloud.LoadDir(cfg.CloudRangesDir, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Error cargando rangos cloud")
+		}
+		for _, source := range classifier.Sources() {
+			logger.Info().
+				Str("provider", source.Provider).
+				Str("version", source.Version).
+				Int("prefixes", source.Prefixes).
+				Msg("Rangos cloud cargados")
+		}
+		cloudClassifier = classifier
+	}
+
+	var scannerOpts []service.ScannerOption
+	if cfg.HTTPProbe || cfg.VerifyOrigins || cfg.Favicons || cfg.VHosts {
+		prober := httpprobe.NewProber(binder.DialContext, cfg.ProbeTimeout, logger)
+		scannerOpts = append(scannerOpts,
+			service.WithHTTPProber(prober),
+			service.WithOriginVerifier(prober),
+			service.WithFaviconFetcher(prober),
+		)
+	}
+
+	if cfg.Enrich || cfg.Tech {
+		// Prober propio: las visitas tienen su propio timeout por petición
+		visitor := httpprobe.NewProber(binder.DialContext, cfg.EnrichTimeout, logger)
+		if cfg.Tech {
+			detector, err := techdetect.NewDetector(logger)
+			if err != nil {
+				logger.Fatal().Err(err).Msg("Error cargando reglas de tecnologías")
+			}
+			if cfg.TechRules != "" {
+				if err := detector.LoadFile(cfg.TechRules); err != nil {
+					logger.Fatal().Err(err).Str("path", cfg.TechRules).Msg("Error cargando archivo de reglas de tecnologías")
+				}
+			}
+			visitor.SetTechDetector(detector)
+		}
+		scannerOpts = append(scannerOpts, service.WithWebProber(visitor))
+	}
+
+	if cfg.TLSProbe || cfg.Sweep {
+		scannerOpts = append(scannerOpts, service.WithTLSProber(tlsprobe.NewProber(binder.DialContext, cfg.ProbeTimeout, logger)))
+	}
+
+	if cfg.PortScan {
+		scanPorts, err := portscan.ParsePorts(cfg.ScanPorts)
+		if err != nil {
+			logger.Fatal().Err(err).Str("scan_ports", cfg.ScanPorts).Msg("Puertos inválidos")
+		}
+		scannerOpts = append(scannerOpts, service.WithPortScanner(portscan.NewProber(binder.DialContext, scanPorts, cfg.PortConcurrency, cfg.PortTimeout, logger)))
+	}
+
+	if cfg.CFAudit {
+		token := cfg.CFAPIToken
+		if token == "" {
+			token = os.Getenv(cloudflare.TokenEnv)
+		}
+		if token == "" {
+			logger.Fatal().Msg("La auditoría de cuenta requiere -cf-api-token o " + cloudflare.TokenEnv)
+		}
+		account := cloudflare.NewAccountClient(cfg.CFAPIURL, token, binder.HTTPClient(30*time.Second), logger)
+		scannerOpts = append(scannerOpts, service.WithCloudflareAccount(account))
+	}
+
 	fileRepo := file.NewRepository(logger)
 	progressReporter := progress.NewReporter()
 	metricsCollector := service.NewMetricsCollector()
@@ -87,11 +253,14 @@ func main() {
 	scanner := service.NewScanner(
 		dnsResolver,
 		cloudflareService,
+		providerRegistry,
+		cloudClassifier,
 		fileRepo,
 		progressReporter,
 		metricsCollector,
 		healthChecker,
 		logger,
+		scannerOpts...,
 	)
 
 	// Iniciar servidor HTTP en segundo plano
@@ -100,6 +269,33 @@ func main() {
 	// Ejecutar escaneo
 	//startTime := time.Now()
 	result, err := scanner.Scan(ctx, *cfg)
+
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			logger.Error().Err(err).Msg("Error guardando transcript DNS")
+		}
+	}
+
+	if faultInjector != nil {
+		stats := faultInjector.Stats()
+		logger.Info().
+			Int("queries", stats.Queries).
+			Int("timeouts", stats.Timeouts).
+			Int("servfails", stats.ServFails).
+			Int("truncated", stats.Truncated).
+			Int("dropped", stats.Dropped).
+			Int("retries", metricsCollector.GetMetrics().Retries).
+			Msg("Fallas DNS inyectadas")
+	}
+
+	if pcapWriter != nil {
+		if err := pcapWriter.Close(); err != nil {
+			logger.Error().Err(err).Msg("Error guardando pcap")
+		} else {
+			logger.Info().Str("path", cfg.PcapFile).Int("packets", pcapWriter.Packets()).Msg("Captura DNS guardada")
+		}
+	}
+
 	if err != nil {
 		logger.Error().Err(err).Msg("Error durante el escaneo")
 		os.Exit(1)
@@ -119,6 +315,19 @@ func main() {
 //	}
 //}
 
+// warnUnknownProviders advierte sobre nombres de -include-providers que no
+// están en el registro (probablemente un error de tipeo)
+func warnUnknownProviders(logger zerolog.Logger, registry *cdn.Registry, names []string) {
+	for _, name := range names {
+		if name == domain.IncludeAllProviders {
+			continue
+		}
+		if _, ok := registry.Provider(name); !ok {
+			logger.Warn().Str("provider", name).Strs("known", registry.Providers()).Msg("Proveedor desconocido en include-providers")
+		}
+	}
+}
+
 func showDefaultMetrics() {
 	fmt.Println("📊 Métricas por defecto:")
 	fmt.Println("------------------------")
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/cdn"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/cloudflare"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/iptrie"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/network"
+	"github.com/alexperezortuno/cloudrip/internal/interfaces/cli"
+	"github.com/rs/zerolog"
+)
+
+// Códigos de salida de ranges, al estilo de grep/diff: 1 indica IPs fuera de
+// los rangos (check) o diferencias (diff); 2 indica un error
+const (
+	rangesExitMismatch = 1
+	rangesExitError    = 2
+)
+
+// rangesService es la parte de cloudflare.Service que usa ranges
+type rangesService interface {
+	GetRanges(ctx context.Context, noFetch bool) (domain.CFRanges, error)
+	Fetch(ctx context.Context) (domain.CFRanges, error)
+	Cached() (domain.CFRanges, bool)
+	LoadFile(path string) (domain.CFRanges, error)
+	Compile(ranges domain.CFRanges) *iptrie.Trie[string]
+}
+
+// rangesCommand resuelve los rangos efectivos de cada proveedor igual que un escaneo
+type rangesCommand struct {
+	config     *cli.RangesConfig
+	cloudflare rangesService
+	registry   *cdn.Registry
+	out        io.Writer
+	logger     zerolog.Logger
+}
+
+// rangeCheck es el resultado de verificar una IP
+type rangeCheck struct {
+	IP       string `json:"ip"`
+	Match    bool   `json:"match"`
+	Provider string `json:"provider,omitempty"`
+	Prefix   string `json:"prefix,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// runRanges imprime, verifica o compara rangos de proveedores sin escanear
+func runRanges(logger zerolog.Logger, args []string) {
+	rangesConfig, err := cli.ParseRangesFlags(args)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error parseando flags de ranges")
+		os.Exit(rangesExitError)
+	}
+
+	binder, err := network.NewBinder(domain.ScannerConfig{})
+	if err != nil {
+		logger.Error().Err(err).Msg("Error configurando red de origen")
+		os.Exit(rangesExitError)
+	}
+
+	cloudflareService := cloudflare.NewService(logger, binder)
+	cloudflareService.SetCache(rangesConfig.CacheDir, rangesConfig.MaxAge)
+	if rangesConfig.RangesFile != "" {
+		cloudflareService.SetRangesFile(rangesConfig.RangesFile)
+	}
+
+	registry, err := cdn.NewRegistry(logger)
+	if err != nil {
+		logger.Error().Err(err).Msg("Error cargando proveedores CDN")
+		os.Exit(rangesExitError)
+	}
+	for _, path := range rangesConfig.ProvidersFiles {
+		if err := registry.LoadFile(path); err != nil {
+			logger.Error().Err(err).Str("path", path).Msg("Error cargando archivo de proveedores")
+			os.Exit(rangesExitError)
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	cmd := &rangesCommand{
+		config:     rangesConfig,
+		cloudflare: cloudflareService,
+		registry:   registry,
+		out:        os.Stdout,
+		logger:     logger,
+	}
+
+	var code int
+	switch rangesConfig.Action {
+	case cli.RangesShow:
+		code, err = cmd.show(ctx)
+	case cli.RangesCheck:
+		code, err = cmd.check(ctx)
+	case cli.RangesDiff:
+		code, err = cmd.diff(ctx)
+	}
+	if err != nil {
+		logger.Error().Err(err).Str("action", rangesConfig.Action).Msg("Error en ranges")
+		os.Exit(rangesExitError)
+	}
+	os.Exit(code)
+}
+
+// effective retorna los rangos que usaría un escaneo para el proveedor. Como
+// en el escaneo, si no se pueden obtener los rangos de Cloudflare (y no hay
+// -ranges-file) se usan los incluidos en el binario.
+func (c *rangesCommand) effective(ctx context.Context, provider string) (domain.CFRanges, error) {
+	if provider == domain.ProviderCloudflare {
+		ranges, err := c.cloudflare.GetRanges(ctx, c.config.NoFetch)
+		switch {
+		case err == nil:
+			c.registry.SetRanges(domain.ProviderCloudflare, ranges)
+			return ranges, nil
+		case c.config.RangesFile != "":
+			return domain.CFRanges{}, err
+		}
+		c.logger.Warn().Err(err).Msg("Error obteniendo rangos Cloudflare, usando defaults")
+		ranges, ok := c.registry.Ranges(provider)
+		if !ok {
+			return domain.CFRanges{}, err
+		}
+		ranges.Source.Source = domain.RangesSourceDefault
+		return ranges, nil
+	}
+
+	ranges, ok := c.registry.Ranges(provider)
+	if !ok {
+		return domain.CFRanges{}, fmt.Errorf("proveedor desconocido: %s", provider)
+	}
+	return ranges, nil
+}
+
+// providers retorna los proveedores seleccionados por -provider
+func (c *rangesCommand) providers() []string {
+	if c.config.Provider == domain.IncludeAllProviders {
+		return c.registry.Providers()
+	}
+	return []string{strings.ToLower(c.config.Provider)}
+}
+
+func (c *rangesCommand) show(ctx context.Context) (int, error) {
+	var all []domain.CFRanges
+	for _, provider := range c.providers() {
+		ranges, err := c.effective(ctx, provider)
+		if err != nil {
+			return 0, err
+		}
+		all = append(all, ranges)
+	}
+
+	if c.config.Format == "json" {
+		return 0, c.writeJSON(all)
+	}
+
+	for _, ranges := range all {
+		fmt.Fprintf(c.out, "# %s\n", describeSource(ranges.Source))
+		for _, cidr := range append(append([]string(nil), ranges.IPv4...), ranges.IPv6...) {
+			fmt.Fprintln(c.out, cidr)
+		}
+	}
+	return 0, nil
+}
+
+func (c *rangesCommand) check(ctx context.Context) (int, error) {
+	ips := c.config.Args
+	if len(ips) == 0 || (len(ips) == 1 && ips[0] == "-") {
+		var err error
+		if ips, err = readIPs(os.Stdin); err != nil {
+			return 0, err
+		}
+	}
+
+	// Con un proveedor concreto se compara solo contra sus rangos, aunque
+	// otro proveedor tenga un prefijo más específico
+	var single *domain.CFRanges
+	var trie *iptrie.Trie[string]
+	if c.config.Provider == domain.IncludeAllProviders {
+		if _, err := c.effective(ctx, domain.ProviderCloudflare); err != nil {
+			return 0, err
+		}
+	} else {
+		ranges, err := c.effective(ctx, strings.ToLower(c.config.Provider))
+		if err != nil {
+			return 0, err
+		}
+		single = &ranges
+		trie = c.cloudflare.Compile(ranges)
+	}
+
+	code := 0
+	checks := make([]rangeCheck, 0, len(ips))
+	for _, ip := range ips {
+		result := rangeCheck{IP: ip}
+		addr, err := netip.ParseAddr(ip)
+		switch {
+		case err != nil:
+			result.Error = "IP inválida"
+		case single != nil:
+			if prefix, _, ok := trie.Lookup(addr); ok {
+				result.Match, result.Provider, result.Prefix = true, single.Source.Provider, prefix.String()
+			}
+		default:
+			result.Provider, result.Prefix, result.Match = c.registry.ClassifyIP(addr.Unmap().String())
+		}
+		if !result.Match {
+			code = rangesExitMismatch
+		}
+		checks = append(checks, result)
+	}
+
+	if c.config.Format == "json" {
+		return code, c.writeJSON(checks)
+	}
+
+	for _, result := range checks {
+		switch {
+		case result.Error != "":
+			fmt.Fprintf(c.out, "%s\t%s\n", result.IP, result.Error)
+		case result.Match:
+			fmt.Fprintf(c.out, "%s\t%s\t%s\n", result.IP, result.Provider, result.Prefix)
+		default:
+			fmt.Fprintf(c.out, "%s\t-\n", result.IP)
+		}
+	}
+	return code, nil
+}
+
+func (c *rangesCommand) diff(ctx context.Context) (int, error) {
+	from, err := c.resolve(ctx, c.config.From)
+	if err != nil {
+		return 0, fmt.Errorf("resolviendo -from: %w", err)
+	}
+	to, err := c.resolve(ctx, c.config.To)
+	if err != nil {
+		return 0, fmt.Errorf("resolviendo -to: %w", err)
+	}
+
+	diff := cdn.Diff(from, to)
+	code := 0
+	if diff.Changed() {
+		code = rangesExitMismatch
+	}
+
+	if c.config.Format == "json" {
+		return code, c.writeJSON(diff)
+	}
+
+	fmt.Fprintf(c.out, "--- %s\n", describeSource(diff.From))
+	fmt.Fprintf(c.out, "+++ %s\n", describeSource(diff.To))
+	for _, cidr := range diff.Added {
+		fmt.Fprintf(c.out, "+ %s\n", cidr)
+	}
+	for _, cidr := range diff.Removed {
+		fmt.Fprintf(c.out, "- %s\n", cidr)
+	}
+	fmt.Fprintf(c.out, "# %d agregados, %d eliminados, %d sin cambios\n", len(diff.Added), len(diff.Removed), diff.Unchanged)
+	return code, nil
+}
+
+// resolve interpreta un origen de diff: cache, fetch, effective o un archivo
+func (c *rangesCommand) resolve(ctx context.Context, spec string) (domain.CFRanges, error) {
+	provider := strings.ToLower(c.config.Provider)
+
+	switch spec {
+	case "effective":
+		return c.effective(ctx, provider)
+	case "cache", "fetch":
+		if provider != domain.ProviderCloudflare {
+			return domain.CFRanges{}, fmt.Errorf("%s solo está disponible para cloudflare", spec)
+		}
+		if spec == "fetch" {
+			return c.cloudflare.Fetch(ctx)
+		}
+		ranges, ok := c.cloudflare.Cached()
+		if !ok {
+			return domain.CFRanges{}, fmt.Errorf("no hay rangos en caché en %s", c.config.CacheDir)
+		}
+		return ranges, nil
+	default:
+		ranges, err := c.cloudflare.LoadFile(spec)
+		if err != nil {
+			return domain.CFRanges{}, err
+		}
+		ranges.Source.Provider = provider
+		return ranges, nil
+	}
+}
+
+func (c *rangesCommand) writeJSON(v any) error {
+	encoder := json.NewEncoder(c.out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("escribiendo JSON: %w", err)
+	}
+	return nil
+}
+
+// readIPs lee IPs de r, una o más por línea separadas por espacios o comas
+func readIPs(r io.Reader) ([]string, error) {
+	var ips []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ips = append(ips, strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("leyendo IPs: %w", err)
+	}
+	return ips, nil
+}
+
+func describeSource(source domain.RangeSource) string {
+	parts := []string{source.Provider, "source=" + source.Source}
+	if source.Version != "" {
+		parts = append(parts, "version="+source.Version)
+	}
+	if !source.FetchedAt.IsZero() {
+		parts = append(parts, "fetched_at="+source.FetchedAt.Format(time.RFC3339))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/cdn"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/cloudflare"
+	"github.com/alexperezortuno/cloudrip/internal/interfaces/cli"
+	"github.com/rs/zerolog"
+)
+
+func TestReadIPs(t *testing.T) {
+	input := `# comentario
+192.0.2.1
+198.51.100.1, 198.51.100.2
+
+2001:db8::1	2001:db8::2 ,192.0.2.3
+  # otro comentario
+no-es-una-ip
+`
+	ips, err := readIPs(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"192.0.2.1", "198.51.100.1", "198.51.100.2", "2001:db8::1", "2001:db8::2", "192.0.2.3", "no-es-una-ip"}
+	if !slices.Equal(ips, want) {
+		t.Errorf("readIPs = %v, esperado %v", ips, want)
+	}
+
+	if ips, err := readIPs(strings.NewReader("")); err != nil || len(ips) != 0 {
+		t.Errorf("readIPs vacío = %v %v", ips, err)
+	}
+}
+
+// failingRanges simula un servicio sin API, caché ni archivo utilizable
+type failingRanges struct {
+	*cloudflare.Service
+}
+
+func (failingRanges) GetRanges(context.Context, bool) (domain.CFRanges, error) {
+	return domain.CFRanges{}, errors.New("sin rangos")
+}
+
+func newRangesCommand(t *testing.T, config *cli.RangesConfig) (*rangesCommand, *bytes.Buffer) {
+	t.Helper()
+	registry, err := cdn.NewRegistry(zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	return &rangesCommand{
+		config:     config,
+		cloudflare: failingRanges{cloudflare.NewService(zerolog.Nop(), nil)},
+		registry:   registry,
+		out:        out,
+		logger:     zerolog.Nop(),
+	}, out
+}
+
+func TestEffectiveFallsBackToDefaults(t *testing.T) {
+	cmd, out := newRangesCommand(t, &cli.RangesConfig{
+		Action:   cli.RangesCheck,
+		Provider: domain.ProviderCloudflare,
+		Args:     []string{"104.16.132.229", "192.0.2.1"},
+	})
+
+	ranges, err := cmd.effective(context.Background(), domain.ProviderCloudflare)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ranges.Source.Source != domain.RangesSourceDefault || len(ranges.IPv4) == 0 {
+		t.Errorf("effective = %+v, esperado los rangos incluidos", ranges.Source)
+	}
+
+	code, err := cmd.check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != rangesExitMismatch {
+		t.Errorf("código = %d, esperado %d", code, rangesExitMismatch)
+	}
+	if want := "104.16.132.229\tcloudflare\t104.16.0.0/13\n192.0.2.1\t-\n"; out.String() != want {
+		t.Errorf("salida = %q, esperado %q", out.String(), want)
+	}
+}
+
+func TestEffectiveRangesFileError(t *testing.T) {
+	// Con -ranges-file explícito no se cae a los defaults, igual que el escaneo
+	cmd, _ := newRangesCommand(t, &cli.RangesConfig{
+		Action:     cli.RangesShow,
+		Provider:   domain.ProviderCloudflare,
+		RangesFile: "rangos.json",
+	})
+	if _, err := cmd.show(context.Background()); err == nil {
+		t.Error("se esperaba error con -ranges-file")
+	}
+}
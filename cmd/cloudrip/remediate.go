@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/cloudflare"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/file"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/network"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/remediate"
+	"github.com/alexperezortuno/cloudrip/internal/interfaces/cli"
+	"github.com/rs/zerolog"
+)
+
+// runRemediate genera reglas de firewall y servidor web que permiten solo a
+// Cloudflare llegar a los orígenes expuestos de un escaneo
+func runRemediate(logger zerolog.Logger, args []string) {
+	remediateConfig, err := cli.ParseRemediateFlags(args)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Error parseando flags de remediate")
+	}
+
+	result, err := file.NewRepository(logger).LoadResults(remediateConfig.Results)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Error cargando resultados")
+	}
+
+	origins := remediate.Origins(result)
+	if len(origins) == 0 {
+		logger.Info().Str("results", remediateConfig.Results).Msg("No hay orígenes expuestos; no se generan reglas")
+		return
+	}
+
+	binder, err := network.NewBinder(domain.ScannerConfig{})
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Error configurando red de origen")
+	}
+
+	cloudflareService := cloudflare.NewService(logger, binder)
+	cloudflareService.SetCache(remediateConfig.CacheDir, remediateConfig.MaxAge)
+	if remediateConfig.RangesFile != "" {
+		cloudflareService.SetRangesFile(remediateConfig.RangesFile)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	ranges, err := cloudflareService.GetRanges(ctx, remediateConfig.NoFetch)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Error obteniendo rangos Cloudflare")
+	}
+
+	plan := remediate.Plan{
+		Domain:        result.Domain,
+		Ranges:        ranges,
+		Ports:         remediateConfig.Ports,
+		Origins:       origins,
+		SecurityGroup: remediateConfig.SecurityGroup,
+		GeneratedAt:   time.Now(),
+	}
+
+	logger.Info().
+		Int("origins", len(origins)).
+		Str("ranges_source", ranges.Source.Source).
+		Str("ranges_version", ranges.Source.Version).
+		Msg("Generando reglas de bloqueo de orígenes")
+
+	if len(remediateConfig.Formats) > 1 {
+		if err := os.MkdirAll(remediateConfig.Output, 0o755); err != nil {
+			logger.Fatal().Err(err).Msg("Error creando directorio de salida")
+		}
+	}
+
+	for _, format := range remediateConfig.Formats {
+		var buf bytes.Buffer
+		if err := remediate.Render(&buf, format, plan); err != nil {
+			logger.Fatal().Err(err).Str("format", format).Msg("Error generando reglas")
+		}
+
+		path := remediateConfig.Output
+		if len(remediateConfig.Formats) > 1 {
+			path = filepath.Join(path, remediate.FileName(format))
+		}
+		if path == "" {
+			_, err = os.Stdout.Write(buf.Bytes())
+		} else {
+			err = os.WriteFile(path, buf.Bytes(), 0o644)
+		}
+		if err != nil {
+			logger.Fatal().Err(err).Str("format", format).Msg("Error escribiendo reglas")
+		}
+		if path != "" {
+			logger.Info().Str("format", format).Str("path", path).Msg("Reglas generadas")
+		}
+	}
+}
@@ -1,43 +1,457 @@
 package domain
 
 import (
+	"net/netip"
 	"time"
 )
 
 // ResultEntry representa un resultado de escaneo
 type ResultEntry struct {
-	FQDN string `json:"fqdn"`
-	IP   string `json:"ip"`
-	Type string `json:"type"`
+	FQDN          string           `json:"fqdn"`
+	IP            string           `json:"ip"`
+	Type          string           `json:"type"`
+	Provider      string           `json:"provider,omitempty"`
+	Prefix        string           `json:"prefix,omitempty"`
+	Proxied       bool             `json:"proxied"`
+	CNAME         string           `json:"cname,omitempty"`
+	CNAMEProvider string           `json:"cname_provider,omitempty"` // sugerido por el destino del CNAME; no implica Proxied
+	Product       string           `json:"product,omitempty"`
+	Cloud         *CloudInfo       `json:"cloud,omitempty"`
+	HTTP          *HTTPFingerprint `json:"http,omitempty"`
+	Mismatch      string           `json:"mismatch,omitempty"`
+	Evidence      []string         `json:"evidence,omitempty"`
+	Ports         []int            `json:"ports,omitempty"`
+	Web           []WebProbe       `json:"web,omitempty"`
+}
+
+// WebProbe es la visita a un host por un esquema siguiendo redirecciones:
+// estado y página finales, cadena de saltos y tiempo total
+type WebProbe struct {
+	Scheme        string       `json:"scheme"`
+	Status        int          `json:"status"`
+	FinalURL      string       `json:"final_url"`
+	Redirects     []string     `json:"redirects,omitempty"`
+	Title         string       `json:"title,omitempty"`
+	Server        string       `json:"server,omitempty"`
+	ContentLength int64        `json:"content_length"`
+	ResponseMS    int64        `json:"response_ms"`
+	Tech          []Technology `json:"tech,omitempty"`
+}
+
+// Technology es una tecnología detectada en la respuesta, con su versión si
+// la respuesta la expone
+type Technology struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+// String retorna nombre/versión, o solo el nombre sin versión
+func (t Technology) String() string {
+	if t.Version == "" {
+		return t.Name
+	}
+	return t.Name + "/" + t.Version
+}
+
+// HTTPFingerprint es el CDN/WAF detectado por las cabeceras de una respuesta
+type HTTPFingerprint struct {
+	Scheme   string   `json:"scheme"`
+	Status   int      `json:"status"`
+	Provider string   `json:"provider,omitempty"`
+	Signals  []string `json:"signals,omitempty"`
+	Server   string   `json:"server,omitempty"`
+}
+
+// Productos de Cloudflare detectados por el destino del CNAME
+const (
+	CFProductTunnel       = "tunnel"        // *.cfargotunnel.com
+	CFProductPages        = "pages"         // *.pages.dev
+	CFProductWorkers      = "workers"       // *.workers.dev
+	CFProductPartialCNAME = "partial-cname" // *.cdn.cloudflare.net
+	CFProductSaaS         = "saas"          // custom hostname de Cloudflare for SaaS (heurística)
+)
+
+// HostSummary resume la exposición de un host considerando todas sus
+// respuestas, incluidas las que la política deja fuera de la salida
+type HostSummary struct {
+	FQDN      string   `json:"fqdn"`
+	Status    string   `json:"status"`
+	Total     int      `json:"total"`
+	Proxied   int      `json:"proxied"`
+	Providers []string `json:"providers,omitempty"`
+	Products  []string `json:"products,omitempty"`
+	Tech      []string `json:"tech,omitempty"`
+	Note      string   `json:"note,omitempty"`
+}
+
+// Estados de exposición de un host
+const (
+	HostProxied = "proxied" // todas las IPs pertenecen a un CDN/WAF
+	HostMixed   = "mixed"   // algunas IPs están expuestas
+	HostExposed = "exposed" // ninguna IP pasa por un CDN/WAF
+)
+
+// CloudInfo atribuye una IP a un proveedor cloud (ej: aws, us-east-1, EC2)
+type CloudInfo struct {
+	Provider string `json:"provider"`
+	Region   string `json:"region,omitempty"`
+	Service  string `json:"service,omitempty"`
+	Prefix   string `json:"prefix,omitempty"`
 }
 
 // CFRanges representa los rangos de IP de Cloudflare
 type CFRanges struct {
-	IPv4 []string `json:"ipv4_cidrs"`
-	IPv6 []string `json:"ipv6_cidrs"`
+	IPv4   []string    `json:"ipv4_cidrs"`
+	IPv6   []string    `json:"ipv6_cidrs"`
+	Source RangeSource `json:"source"`
+}
+
+// RangeSource indica de dónde salieron los rangos usados en un escaneo
+type RangeSource struct {
+	Provider  string    `json:"provider"`
+	Source    string    `json:"source"`
+	Version   string    `json:"version,omitempty"`
+	FetchedAt time.Time `json:"fetched_at,omitzero"`
 }
 
+// Orígenes posibles de los rangos
+const (
+	RangesSourceAPI      = "api"
+	RangesSourceCache    = "cache"
+	RangesSourceFile     = "file"
+	RangesSourceDefault  = "default"
+	RangesSourceRegistry = "registry"
+)
+
+// CDNProvider describe un proveedor CDN/WAF: sus rangos IP y los sufijos
+// CNAME que delatan su uso
+type CDNProvider struct {
+	Name          string   `yaml:"name" json:"name"`
+	IPv4          []string `yaml:"ipv4" json:"ipv4"`
+	IPv6          []string `yaml:"ipv6" json:"ipv6"`
+	CNAMESuffixes []string `yaml:"cname_suffixes" json:"cname_suffixes"`
+}
+
+// IncludeAllProviders en IncludeProviders conserva las IPs de todos los proveedores
+const IncludeAllProviders = "all"
+
+// ProviderCloudflare es el nombre de Cloudflare en el registro de proveedores
+const ProviderCloudflare = "cloudflare"
+
 // ScannerConfig contiene la configuración del escaneo
 type ScannerConfig struct {
-	Domain      string        `yaml:"domain" json:"domain"`
-	Wordlist    string        `yaml:"wordlist" json:"wordlist"`
-	Threads     int           `yaml:"threads" json:"threads"`
-	Retries     int           `yaml:"retries" json:"retries"`
-	Backoff     time.Duration `yaml:"backoff" json:"backoff"`
-	Timeout     time.Duration `yaml:"timeout" json:"timeout"`
-	Delay       time.Duration `yaml:"delay" json:"delay"`
-	FollowCNAME bool          `yaml:"follow_cname" json:"follow_cname"`
-	IncludeCF   bool          `yaml:"include_cf" json:"include_cf"`
-	NoFetchCF   bool          `yaml:"no_fetch_cf" json:"no_fetch_cf"`
-	Output      string        `yaml:"output" json:"output"`
-	OutputFmt   string        `yaml:"output_format" json:"output_format"`
+	Domain           string             `yaml:"domain" json:"domain"`
+	Wordlist         string             `yaml:"wordlist" json:"wordlist"`
+	Threads          int                `yaml:"threads" json:"threads"`
+	Retries          int                `yaml:"retries" json:"retries"`
+	Backoff          time.Duration      `yaml:"backoff" json:"backoff"`
+	Timeout          time.Duration      `yaml:"timeout" json:"timeout"`
+	Delay            time.Duration      `yaml:"delay" json:"delay"`
+	FollowCNAME      bool               `yaml:"follow_cname" json:"follow_cname"`
+	IncludeCF        bool               `yaml:"include_cf" json:"include_cf"`
+	IncludeProviders []string           `yaml:"include_providers" json:"include_providers"`
+	ProvidersFiles   []string           `yaml:"providers_files" json:"providers_files"`
+	CloudRangesDir   string             `yaml:"cloud_ranges_dir" json:"cloud_ranges_dir"`
+	HTTPProbe        bool               `yaml:"http_probe" json:"http_probe"`
+	ProbeTimeout     time.Duration      `yaml:"probe_timeout" json:"probe_timeout"`
+	Enrich           bool               `yaml:"enrich" json:"enrich"`
+	EnrichThreads    int                `yaml:"enrich_threads" json:"enrich_threads"`
+	EnrichTimeout    time.Duration      `yaml:"enrich_timeout" json:"enrich_timeout"`
+	Tech             bool               `yaml:"tech" json:"tech"`
+	TechRules        string             `yaml:"tech_rules" json:"tech_rules"`
+	VerifyOrigins    bool               `yaml:"verify_origins" json:"verify_origins"`
+	MaxCandidates    int                `yaml:"max_candidates" json:"max_candidates"`
+	SPF              bool               `yaml:"spf" json:"spf"`
+	SPFMaxLookups    int                `yaml:"spf_max_lookups" json:"spf_max_lookups"`
+	Favicons         bool               `yaml:"favicons" json:"favicons"`
+	Score            bool               `yaml:"score" json:"score"`
+	ScoreWeights     map[string]float64 `yaml:"score_weights" json:"score_weights,omitempty"`
+	TopCandidates    int                `yaml:"top_candidates" json:"top_candidates"`
+	TLSProbe         bool               `yaml:"tls_probe" json:"tls_probe"`
+	VHosts           bool               `yaml:"vhosts" json:"vhosts"`
+	VHostMax         int                `yaml:"vhost_max" json:"vhost_max"`
+	Sweep            bool               `yaml:"sweep" json:"sweep"`
+	SweepPrefix      int                `yaml:"sweep_prefix" json:"sweep_prefix"`
+	SweepMax         int                `yaml:"sweep_max" json:"sweep_max"`
+	SweepRate        int                `yaml:"sweep_rate" json:"sweep_rate"`
+	PortScan         bool               `yaml:"port_scan" json:"port_scan"`
+	ScanPorts        string             `yaml:"scan_ports" json:"scan_ports"`
+	PortConcurrency  int                `yaml:"port_concurrency" json:"port_concurrency"`
+	PortTimeout      time.Duration      `yaml:"port_timeout" json:"port_timeout"`
+	TLSPorts         []int              `yaml:"tls_ports" json:"tls_ports"`
+	CFAudit          bool               `yaml:"cf_audit" json:"cf_audit"`
+	CFAPIURL         string             `yaml:"cf_api_url" json:"cf_api_url"`
+	CFAPIToken       string             `yaml:"cf_api_token" json:"-"`
+	NoFetchCF        bool               `yaml:"no_fetch_cf" json:"no_fetch_cf"`
+	CacheDir         string             `yaml:"cache_dir" json:"cache_dir"`
+	RangesMaxAge     time.Duration      `yaml:"ranges_max_age" json:"ranges_max_age"`
+	RangesFile       string             `yaml:"ranges_file" json:"ranges_file"`
+	Output           string             `yaml:"output" json:"output"`
+	OutputFmt        string             `yaml:"output_format" json:"output_format"`
+	Resolver         string             `yaml:"resolver" json:"resolver"`
+	SourceIP         string             `yaml:"source_ip" json:"source_ip"`
+	Interface        string             `yaml:"interface" json:"interface"`
+	IPFamily         string             `yaml:"ip_family" json:"ip_family"`
+	RecordDNS        string             `yaml:"record_dns" json:"record_dns"`
+	ReplayDNS        string             `yaml:"replay_dns" json:"replay_dns"`
+	PcapFile         string             `yaml:"pcap_file" json:"pcap_file"`
+	Faults           string             `yaml:"faults" json:"faults"`
+}
+
+// Familias de direcciones soportadas por el escaneo
+const (
+	IPFamilyAny  = "any"
+	IPFamilyIPv4 = "ipv4"
+	IPFamilyIPv6 = "ipv6"
+)
+
+// AllowsFamily indica si addr pertenece a la familia (any, ipv4 o ipv6); las
+// IPv4 mapeadas en IPv6 cuentan como IPv4
+func AllowsFamily(family string, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	switch family {
+	case IPFamilyIPv4:
+		return addr.Is4()
+	case IPFamilyIPv6:
+		return addr.Is6()
+	default:
+		return true
+	}
 }
 
 // ScanResult representa el resultado completo del escaneo
 type ScanResult struct {
+	Domain     string                   `json:"domain"`
 	TotalFound int                      `json:"total_found"`
 	Duration   time.Duration            `json:"duration"`
+	Ranges     RangeSource              `json:"ranges"`
+	Hosts      []HostSummary            `json:"hosts"`
 	Results    map[string][]ResultEntry `json:"results"`
+	Answers    map[string][]ResultEntry `json:"-"`
+	Audit      *AccountAudit            `json:"audit,omitempty"`
+	Origins    []OriginCheck            `json:"origins,omitempty"`
+	Certs      []CertCheck              `json:"certificates,omitempty"`
+	Favicons   []FaviconCheck           `json:"favicons,omitempty"`
+	Leads      []OriginLead             `json:"leads,omitempty"`
+	Candidates []OriginScore            `json:"candidates,omitempty"`
+	Sweep      []SweepHit               `json:"sweep,omitempty"`
+	Ports      []PortScan               `json:"ports,omitempty"`
+	VHosts     []VHostCheck             `json:"vhosts,omitempty"`
+}
+
+// VHost es un nombre que el origen sirve con una respuesta distinta a la de
+// un host cualquiera. Hidden indica que el DNS no lo apunta a ese origen.
+type VHost struct {
+	Name     string  `json:"name"`
+	Status   int     `json:"status"`
+	Title    string  `json:"title,omitempty"`
+	Baseline float64 `json:"baseline_score"` // similitud con la respuesta por defecto
+	Cluster  int     `json:"cluster"`        // nombres con respuestas parecidas comparten grupo
+	Hidden   bool    `json:"hidden"`
+}
+
+// VHostCheck son los virtual hosts encontrados en un origen confirmado, con
+// la respuesta por defecto (hosts aleatorios) como referencia
+type VHostCheck struct {
+	IP     string  `json:"ip"`
+	Port   int     `json:"port,omitempty"`
+	Status int     `json:"status"`
+	Title  string  `json:"title,omitempty"`
+	VHosts []VHost `json:"vhosts,omitempty"`
+}
+
+// PortScan son los puertos TCP abiertos de una IP candidata
+type PortScan struct {
+	IP   string `json:"ip"`
+	Open []int  `json:"open"`
+}
+
+// SweepHit es una IP vecina de un origen confirmado cuyo certificado nombra
+// al dominio
+type SweepHit struct {
+	Seed     string    `json:"seed"`
+	Netblock string    `json:"netblock"`
+	Cert     CertCheck `json:"certificate"`
+}
+
+// Señales del puntaje de origen
+const (
+	SignalHTTP    = "http"    // similitud con el sitio servido por el CDN
+	SignalTLS     = "tls"     // el certificado nombra al host o al dominio
+	SignalFavicon = "favicon" // sirve el favicon del host
+	SignalSPF     = "spf"     // aparece en el SPF o los MX del dominio
+	SignalPTR     = "ptr"     // su PTR pertenece al dominio
+	SignalDNS     = "dns"     // algún host del dominio resuelve directo a ella
+	SignalHosting = "hosting" // pertenece a un proveedor cloud/hosting
+)
+
+// DefaultScoreWeights retorna los pesos por defecto de cada señal
+func DefaultScoreWeights() map[string]float64 {
+	return map[string]float64{
+		SignalHTTP:    4,
+		SignalTLS:     3,
+		SignalFavicon: 2,
+		SignalSPF:     1.5,
+		SignalPTR:     1,
+		SignalDNS:     1,
+		SignalHosting: 0.5,
+	}
+}
+
+// ScoreSignal es el aporte de una señal al puntaje; Value va de 0 a 1
+type ScoreSignal struct {
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+	Weight float64 `json:"weight"`
+	Detail string  `json:"detail,omitempty"`
+}
+
+// OriginScore estima qué tan probable es que IP sea el origen de FQDN. Score
+// es el promedio ponderado (0 a 1) de las señales evaluadas en el escaneo.
+type OriginScore struct {
+	FQDN    string        `json:"fqdn"`
+	IP      string        `json:"ip"`
+	Score   float64       `json:"score"`
+	Signals []ScoreSignal `json:"signals"`
+}
+
+// Fuentes de pistas de origen
+const (
+	LeadSPF = "spf"
+	LeadMX  = "mx"
+)
+
+// OriginLead es una IP o un rango obtenido de registros DNS del dominio, con
+// la cadena de procedencia. Los rangos chicos se expanden en IPs candidatas.
+type OriginLead struct {
+	IP     string   `json:"ip,omitempty"`
+	Prefix string   `json:"prefix,omitempty"`
+	IPs    []string `json:"ips,omitempty"`
+	Source string   `json:"source"`
+	Chain  []string `json:"chain"`
+}
+
+// Favicon es un ícono con su hash mmh3 (compatible con Shodan) y SHA-256
+type Favicon struct {
+	Path   string `json:"path"`
+	MMH3   int32  `json:"mmh3"`
+	SHA256 string `json:"sha256"`
+	Size   int    `json:"size"`
+}
+
+// FaviconMatch es una IP candidata que sirve un ícono del sitio
+type FaviconMatch struct {
+	IP      string  `json:"ip"`
+	Favicon Favicon `json:"favicon"`
+}
+
+// FaviconCheck son los íconos de un host obtenidos a través del CDN y las
+// IPs candidatas que sirven alguno de ellos
+type FaviconCheck struct {
+	FQDN     string         `json:"fqdn"`
+	Via      string         `json:"via"`
+	Favicons []Favicon      `json:"favicons"`
+	Matches  []FaviconMatch `json:"matches,omitempty"`
+}
+
+// Coincidencias de un certificado con el dominio objetivo, de más a menos fuerte
+const (
+	// CertMatchDefault: el certificado por defecto (sin SNI) nombra al dominio
+	CertMatchDefault = "default"
+	// CertMatchSNI: solo el certificado pedido con SNI nombra al dominio
+	CertMatchSNI = "sni"
+)
+
+// CertInfo son los campos de un certificado usados para el matching
+type CertInfo struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	Serial    string    `json:"serial"`
+	SANs      []string  `json:"sans,omitempty"`
+	SPKI      string    `json:"spki_sha256"`
+	NotBefore time.Time `json:"not_before,omitzero"`
+	NotAfter  time.Time `json:"not_after,omitzero"`
+}
+
+// CertCheck es el certificado que presenta una IP candidata, con y sin SNI,
+// comparado con el dominio objetivo
+type CertCheck struct {
+	IP      string     `json:"ip"`
+	Port    int        `json:"port"`
+	SNI     string     `json:"sni,omitempty"` // vacío: handshake sin SNI
+	Chain   []CertInfo `json:"chain"`
+	Match   string     `json:"match,omitempty"`
+	Names   []string   `json:"names,omitempty"`   // SANs que cubren el dominio
+	Related []string   `json:"related,omitempty"` // SANs de otros dominios
+}
+
+// Veredictos de la verificación de orígenes
+const (
+	OriginConfirmed = "confirmed"
+	OriginLikely    = "likely"
+	OriginUnrelated = "unrelated"
+)
+
+// PageSnapshot resume una respuesta HTTP para compararla con otra
+type PageSnapshot struct {
+	Scheme  string   `json:"scheme"`
+	Status  int      `json:"status"`
+	Title   string   `json:"title,omitempty"`
+	Headers []string `json:"headers,omitempty"`
+	Simhash uint64   `json:"simhash"`
+	Length  int      `json:"length"`
+}
+
+// OriginCheck compara el sitio servido por el CDN con el que responde una IP
+// candidata usando el mismo Host/SNI
+type OriginCheck struct {
+	FQDN     string  `json:"fqdn"`
+	IP       string  `json:"ip"`
+	Port     int     `json:"port,omitempty"` // 0: puertos estándar
+	Via      string  `json:"via"`            // IP del CDN usada como referencia
+	Score    float64 `json:"score"`
+	Verdict  string  `json:"verdict"`
+	Status   int     `json:"status"`
+	Title    string  `json:"title,omitempty"`
+	Baseline string  `json:"baseline_title,omitempty"`
+}
+
+// DNSRecord es un registro DNS de una zona según la API de Cloudflare
+type DNSRecord struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	Proxied bool   `json:"proxied"`
+	TTL     int    `json:"ttl"`
+}
+
+// ZoneInventory son los registros DNS declarados en una zona
+type ZoneInventory struct {
+	Zone    string      `json:"zone"`
+	Records []DNSRecord `json:"records"`
+}
+
+// AccountAudit compara el inventario de la zona con lo encontrado por el escaneo
+type AccountAudit struct {
+	Zone    string `json:"zone"`
+	Records int    `json:"records"`
+	// GreyClouded son registros A/AAAA/CNAME sin proxy: exponen el origen
+	GreyClouded []AuditFinding `json:"grey_clouded"`
+	// ProxiedExposed son registros con proxy que resuelven a IPs fuera de Cloudflare
+	ProxiedExposed []AuditFinding `json:"proxied_exposed"`
+	// NotInZone son nombres encontrados que no están declarados en la zona
+	NotInZone []AuditFinding `json:"not_in_zone"`
+	// Error indica que la auditoría falló; el resto de los resultados se conserva
+	Error string `json:"error,omitempty"`
+}
+
+// AuditFinding es un hallazgo de la auditoría de cuenta
+type AuditFinding struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type,omitempty"`
+	Content string   `json:"content,omitempty"`
+	IPs     []string `json:"ips,omitempty"`
+	Found   bool     `json:"found"`
 }
 
 // Job representa un trabajo de escaneo
@@ -61,6 +475,7 @@ type Metrics struct {
 	SuccessCount  int                `json:"success_count"`
 	ErrorCount    int                `json:"error_count"`
 	DNSQueries    int                `json:"dns_queries"`
+	Retries       int                `json:"retries"`
 	WorkerStats   map[int]WorkerStat `json:"worker_stats"`
 }
 
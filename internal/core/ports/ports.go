@@ -16,19 +16,78 @@ type ScannerService interface {
 // CloudflareService define las operaciones con Cloudflare
 type CloudflareService interface {
 	GetRanges(ctx context.Context, noFetch bool) (domain.CFRanges, error)
-	IsCloudflareIP(ip string, ranges domain.CFRanges) bool
+	ProductFromCNAME(target string) (product string, ok bool)
+}
+
+// ProviderRegistry clasifica IPs y CNAMEs por proveedor CDN/WAF
+type ProviderRegistry interface {
+	Register(provider domain.CDNProvider)
+	SetRanges(name string, ranges domain.CFRanges)
+	ClassifyIP(ip string) (provider string, prefix string, ok bool)
+	ClassifyCNAME(target string) (provider string, ok bool)
+	Providers() []string
+}
+
+// CloudClassifier atribuye IPs a proveedores cloud (AWS, GCP, Azure, Oracle)
+type CloudClassifier interface {
+	ClassifyIP(ip string) (domain.CloudInfo, bool)
+}
+
+// HTTPProber confirma por cabeceras HTTP qué CDN/WAF sirve una IP
+type HTTPProber interface {
+	Probe(ctx context.Context, ip, host string) (domain.HTTPFingerprint, error)
+}
+
+// WebProber visita un host en una IP por un esquema (https o http) siguiendo
+// las redirecciones
+type WebProber interface {
+	Visit(ctx context.Context, ip, host, scheme string) (domain.WebProbe, error)
+}
+
+// OriginVerifier obtiene y compara respuestas HTTP de una IP con un Host/SNI.
+// Con port 0 se usan los puertos estándar (HTTPS 443 y luego HTTP 80).
+type OriginVerifier interface {
+	Snapshot(ctx context.Context, ip string, port int, host string) (domain.PageSnapshot, error)
+	Compare(baseline, candidate domain.PageSnapshot) float64
+}
+
+// FaviconFetcher obtiene los íconos que sirve una IP para un host
+type FaviconFetcher interface {
+	Favicons(ctx context.Context, ip, host string) ([]domain.Favicon, error)
+}
+
+// TLSProber obtiene la cadena de certificados de una IP, con o sin SNI
+type TLSProber interface {
+	Certificates(ctx context.Context, ip string, port int, serverName string) ([]domain.CertInfo, error)
+}
+
+// PortScanner detecta puertos TCP abiertos en una IP
+type PortScanner interface {
+	OpenPorts(ctx context.Context, ip string) ([]int, error)
+}
+
+// CloudflareAccount lee el inventario DNS de una cuenta Cloudflare
+type CloudflareAccount interface {
+	// Zone retorna la zona que contiene name; valida token y zona
+	Zone(ctx context.Context, name string) (string, error)
+	Inventory(ctx context.Context, zone string) (domain.ZoneInventory, error)
 }
 
 // DNSResolver define las operaciones de resolución DNS
 type DNSResolver interface {
 	LookupIP(ctx context.Context, fqdn string) ([]string, error)
 	LookupCNAME(ctx context.Context, fqdn string) (string, error)
+	LookupTXT(ctx context.Context, fqdn string) ([]string, error)
+	// LookupMX retorna los hosts de correo ordenados por preferencia
+	LookupMX(ctx context.Context, fqdn string) ([]string, error)
+	// LookupAddr retorna los nombres PTR de una IP, sin punto final
+	LookupAddr(ctx context.Context, ip string) ([]string, error)
 }
 
 // FileRepository maneja operaciones de archivo
 type FileRepository interface {
 	LoadWordlist(path string) ([]string, error)
-	SaveResults(results map[string][]domain.ResultEntry, config domain.ScannerConfig) error
+	SaveResults(result *domain.ScanResult, config domain.ScannerConfig) error
 	LoadConfig(path string) (*domain.ScannerConfig, error)
 	SaveConfig(config *domain.ScannerConfig, path string) error
 }
@@ -55,6 +114,7 @@ type MetricsCollector interface {
 	IncrementSuccess()
 	IncrementError()
 	IncrementDNSQuery()
+	IncrementRetry()
 	RecordWorkerActivity(workerID int)
 	GetMetrics() domain.Metrics
 }
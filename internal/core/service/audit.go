@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// proxiable son los tipos de registro que Cloudflare puede poner tras su proxy
+var proxiable = map[string]bool{"A": true, "AAAA": true, "CNAME": true}
+
+// runAudit ejecuta la auditoría de cuenta. Un error (token revocado, API
+// caída) no descarta el escaneo: queda registrado en la auditoría.
+func (s *Scanner) runAudit(ctx context.Context, config domain.ScannerConfig, answers map[string][]domain.ResultEntry) *domain.AccountAudit {
+	audit, err := s.auditAccount(ctx, config, answers)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("Error auditando cuenta Cloudflare")
+		return &domain.AccountAudit{Zone: config.Domain, Error: err.Error()}
+	}
+	s.logAudit(audit)
+	return audit
+}
+
+// auditAccount compara el inventario de la zona en la API de Cloudflare con
+// las respuestas del escaneo. Los registros con proxy que el escaneo no
+// encontró se resuelven aquí para verificar a dónde apuntan.
+func (s *Scanner) auditAccount(ctx context.Context, config domain.ScannerConfig, answers map[string][]domain.ResultEntry) (*domain.AccountAudit, error) {
+	inventory, err := s.cloudflareAccount.Inventory(ctx, config.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	audit := &domain.AccountAudit{
+		Zone:           inventory.Zone,
+		Records:        len(inventory.Records),
+		GreyClouded:    []domain.AuditFinding{},
+		ProxiedExposed: []domain.AuditFinding{},
+		NotInZone:      []domain.AuditFinding{},
+	}
+
+	declared := make(map[string]bool, len(inventory.Records))
+	var wildcards []string
+	checked := make(map[string]bool)
+
+	for _, record := range inventory.Records {
+		declared[record.Name] = true
+		if strings.HasPrefix(record.Name, "*.") {
+			wildcards = append(wildcards, strings.TrimPrefix(record.Name, "*"))
+		}
+		if !proxiable[record.Type] {
+			continue
+		}
+
+		entries, found := answers[record.Name]
+		if !record.Proxied {
+			audit.GreyClouded = append(audit.GreyClouded, domain.AuditFinding{
+				Name:    record.Name,
+				Type:    record.Type,
+				Content: record.Content,
+				IPs:     answerIPs(entries, ""),
+				Found:   found,
+			})
+			continue
+		}
+
+		if checked[record.Name] {
+			continue
+		}
+		checked[record.Name] = true
+
+		var exposed []string
+		switch {
+		case found:
+			exposed = answerIPs(entries, domain.ProviderCloudflare)
+		case !strings.HasPrefix(record.Name, "*."):
+			exposed = s.resolveOutside(ctx, config, record.Name)
+		}
+		if len(exposed) > 0 {
+			audit.ProxiedExposed = append(audit.ProxiedExposed, domain.AuditFinding{
+				Name:    record.Name,
+				Type:    record.Type,
+				Content: record.Content,
+				IPs:     exposed,
+				Found:   found,
+			})
+		}
+	}
+
+	for fqdn, entries := range answers {
+		if declared[strings.ToLower(fqdn)] || coveredByWildcard(strings.ToLower(fqdn), wildcards) {
+			continue
+		}
+		audit.NotInZone = append(audit.NotInZone, domain.AuditFinding{
+			Name:  fqdn,
+			IPs:   answerIPs(entries, ""),
+			Found: true,
+		})
+	}
+
+	for _, findings := range [][]domain.AuditFinding{audit.GreyClouded, audit.ProxiedExposed, audit.NotInZone} {
+		sort.Slice(findings, func(i, j int) bool {
+			if findings[i].Name != findings[j].Name {
+				return findings[i].Name < findings[j].Name
+			}
+			return findings[i].Content < findings[j].Content
+		})
+	}
+	return audit, nil
+}
+
+// resolveOutside resuelve un nombre y retorna sus IPs fuera de Cloudflare
+func (s *Scanner) resolveOutside(ctx context.Context, config domain.ScannerConfig, fqdn string) []string {
+	lookupCtx, cancel := timeoutContext(ctx, config.Timeout)
+	defer cancel()
+
+	ips, err := s.dnsResolver.LookupIP(lookupCtx, fqdn)
+	if err != nil {
+		s.logger.Debug().Err(err).Str("fqdn", fqdn).Msg("Error resolviendo registro de la zona")
+		return nil
+	}
+
+	var outside []string
+	for _, ip := range ips {
+		if provider, _, ok := s.providerRegistry.ClassifyIP(ip); !ok || provider != domain.ProviderCloudflare {
+			outside = append(outside, ip)
+		}
+	}
+	return outside
+}
+
+// answerIPs retorna las IPs de las respuestas, excluyendo las del proveedor
+// indicado
+func answerIPs(entries []domain.ResultEntry, exclude string) []string {
+	var ips []string
+	for _, entry := range entries {
+		if entry.IP != "" && (exclude == "" || entry.Provider != exclude) {
+			ips = append(ips, entry.IP)
+		}
+	}
+	return ips
+}
+
+// coveredByWildcard indica si algún wildcard declarado (guardado como sufijo
+// ".example.com") cubre el nombre
+func coveredByWildcard(fqdn string, wildcards []string) bool {
+	for _, suffix := range wildcards {
+		if strings.HasSuffix(fqdn, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// logAudit resume la auditoría en el log
+func (s *Scanner) logAudit(audit *domain.AccountAudit) {
+	s.logger.Info().
+		Str("zone", audit.Zone).
+		Int("records", audit.Records).
+		Int("grey_clouded", len(audit.GreyClouded)).
+		Int("proxied_exposed", len(audit.ProxiedExposed)).
+		Int("not_in_zone", len(audit.NotInZone)).
+		Msg("Auditoría de cuenta Cloudflare")
+
+	for _, finding := range audit.ProxiedExposed {
+		s.logger.Warn().
+			Str("fqdn", finding.Name).
+			Str("type", finding.Type).
+			Strs("ips", finding.IPs).
+			Msg("Registro con proxy resuelve fuera de Cloudflare")
+	}
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+func TestAuditAccount(t *testing.T) {
+	records := []domain.DNSRecord{
+		// Sin proxy: encontrado y no encontrado por el escaneo
+		{Name: "admin.example.com", Type: "A", Content: "203.0.113.10"},
+		{Name: "vpn.example.com", Type: "A", Content: "203.0.113.11"},
+		// Con proxy y respuestas fuera de Cloudflare; dos registros A del
+		// mismo nombre producen un solo hallazgo
+		{Name: "www.example.com", Type: "A", Content: "104.16.1.1", Proxied: true},
+		{Name: "www.example.com", Type: "A", Content: "203.0.113.20", Proxied: true},
+		// Con proxy y solo respuestas de Cloudflare
+		{Name: "shop.example.com", Type: "CNAME", Content: "shops.example.net", Proxied: true},
+		// Con proxy no encontrado: se resuelve aparte
+		{Name: "legacy.example.com", Type: "A", Content: "203.0.113.30", Proxied: true},
+		{Name: "api.example.com", Type: "AAAA", Content: "2606:4700::1", Proxied: true},
+		// Con proxy y wildcard: no se puede resolver
+		{Name: "*.apps.example.com", Type: "A", Content: "203.0.113.40", Proxied: true},
+		// No proxiables: solo cuentan como declarados
+		{Name: "example.com", Type: "MX", Content: "mx.example.com"},
+		{Name: "mail.example.com", Type: "TXT", Content: "v=spf1 -all"},
+	}
+	answers := map[string][]domain.ResultEntry{
+		"admin.example.com": {{FQDN: "admin.example.com", IP: "203.0.113.10"}},
+		"www.example.com": {
+			{FQDN: "www.example.com", IP: "104.16.1.1", Provider: domain.ProviderCloudflare},
+			{FQDN: "www.example.com", IP: "203.0.113.20"},
+		},
+		"shop.example.com": {{FQDN: "shop.example.com", IP: "104.16.2.2", Provider: domain.ProviderCloudflare}},
+		// Fuera de la zona, salvo el cubierto por el wildcard
+		"ghost.example.com":        {{FQDN: "ghost.example.com", IP: "198.51.100.7"}},
+		"preview.apps.example.com": {{FQDN: "preview.apps.example.com", IP: "104.16.3.3", Provider: domain.ProviderCloudflare}},
+	}
+	resolver := &fakeResolver{ips: map[string][]string{
+		"legacy.example.com": {"104.16.4.4", "203.0.113.30"},
+		"api.example.com":    {"2606:4700::1"},
+	}}
+	registry := &fakeRegistry{prefixes: map[string]string{
+		"104.16.0.0/13":  domain.ProviderCloudflare,
+		"2606:4700::/32": domain.ProviderCloudflare,
+	}}
+	scanner := newTestScanner(resolver, registry)
+	scanner.cloudflareAccount = &fakeAccount{inventory: domain.ZoneInventory{Zone: "example.com", Records: records}}
+
+	// -timeout 0 no limita las consultas de registros no encontrados
+	config := domain.ScannerConfig{Domain: "example.com"}
+	audit, err := scanner.auditAccount(context.Background(), config, answers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audit.Zone != "example.com" || audit.Records != len(records) || audit.Error != "" {
+		t.Errorf("audit = %s %d %q", audit.Zone, audit.Records, audit.Error)
+	}
+
+	tests := []struct {
+		section  string
+		findings []domain.AuditFinding
+		want     []domain.AuditFinding
+	}{
+		{"grey_clouded", audit.GreyClouded, []domain.AuditFinding{
+			{Name: "admin.example.com", Type: "A", Content: "203.0.113.10", IPs: []string{"203.0.113.10"}, Found: true},
+			{Name: "vpn.example.com", Type: "A", Content: "203.0.113.11"},
+		}},
+		{"proxied_exposed", audit.ProxiedExposed, []domain.AuditFinding{
+			{Name: "legacy.example.com", Type: "A", Content: "203.0.113.30", IPs: []string{"203.0.113.30"}},
+			{Name: "www.example.com", Type: "A", Content: "104.16.1.1", IPs: []string{"203.0.113.20"}, Found: true},
+		}},
+		{"not_in_zone", audit.NotInZone, []domain.AuditFinding{
+			{Name: "ghost.example.com", IPs: []string{"198.51.100.7"}, Found: true},
+		}},
+	}
+	for _, tt := range tests {
+		if !slices.EqualFunc(tt.findings, tt.want, equalFinding) {
+			t.Errorf("%s = %+v, esperado %+v", tt.section, tt.findings, tt.want)
+		}
+	}
+
+	// Los wildcards con proxy no se consultan
+	if n := resolver.queries["A *.apps.example.com"]; n != 0 {
+		t.Errorf("se resolvió el wildcard %d veces", n)
+	}
+}
+
+func equalFinding(a, b domain.AuditFinding) bool {
+	return a.Name == b.Name && a.Type == b.Type && a.Content == b.Content && a.Found == b.Found && slices.Equal(a.IPs, b.IPs)
+}
+
+func TestRunAuditError(t *testing.T) {
+	scanner := newTestScanner(&fakeResolver{}, nil)
+	scanner.cloudflareAccount = &fakeAccount{err: errors.New("API retornó status 502")}
+
+	answers := map[string][]domain.ResultEntry{"www.example.com": {{FQDN: "www.example.com", IP: "203.0.113.1"}}}
+	audit := scanner.runAudit(context.Background(), domain.ScannerConfig{Domain: "example.com"}, answers)
+	if audit == nil || audit.Zone != "example.com" || audit.Error != "API retornó status 502" || len(audit.GreyClouded) != 0 {
+		t.Errorf("audit = %+v, esperado el error registrado", audit)
+	}
+}
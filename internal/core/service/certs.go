@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// harvestCertificates obtiene el certificado de cada IP candidata en los
+// puertos HTTPS, sin SNI y con el dominio como SNI, y lo compara con el
+// dominio. Un certificado por defecto que nombra al dominio es la señal de
+// origen más fuerte. Con el escaneo de puertos (open no nil) se consultan los
+// puertos abiertos de cada IP que no son HTTP plano.
+func (s *Scanner) harvestCertificates(ctx context.Context, config domain.ScannerConfig, answers map[string][]domain.ResultEntry, candidates []string, open map[string][]int) []domain.CertCheck {
+	ports := config.TLSPorts
+	if len(ports) == 0 {
+		ports = []int{443}
+	}
+
+	s.logger.Info().
+		Int("candidates", len(candidates)).
+		Ints("ports", ports).
+		Msg("Obteniendo certificados TLS")
+
+	var (
+		mu     sync.Mutex
+		group  = newBoundedGroup(ctx, config.Threads)
+		checks []domain.CertCheck
+	)
+
+dispatch:
+	for _, ip := range candidates {
+		for _, port := range tlsPorts(open, ip, ports) {
+			dispatched := group.Go(func() {
+				found := s.certificatesAt(ctx, config.Domain, ip, port, nil)
+				mu.Lock()
+				checks = append(checks, found...)
+				mu.Unlock()
+			})
+			if !dispatched {
+				break dispatch
+			}
+		}
+	}
+	group.Wait()
+
+	sort.Slice(checks, func(i, j int) bool {
+		if checks[i].IP != checks[j].IP {
+			return checks[i].IP < checks[j].IP
+		}
+		if checks[i].Port != checks[j].Port {
+			return checks[i].Port < checks[j].Port
+		}
+		return checks[i].SNI < checks[j].SNI
+	})
+
+	matches := 0
+	related := make(map[string]bool)
+	for _, check := range checks {
+		for _, name := range check.Related {
+			related[name] = true
+		}
+		if check.Match == "" {
+			continue
+		}
+		matches++
+		s.logger.Warn().
+			Str("ip", check.IP).
+			Int("port", check.Port).
+			Str("match", check.Match).
+			Strs("names", check.Names).
+			Msg("Certificado de IP candidata nombra al dominio")
+	}
+	s.logger.Info().
+		Int("certificates", len(checks)).
+		Int("matches", matches).
+		Int("related_names", len(related)).
+		Msg("Certificados TLS obtenidos")
+
+	return checks
+}
+
+// certificatesAt hace el handshake sin SNI y con SNI; si ambos presentan el
+// mismo certificado solo se conserva el de sin SNI. wait, si no es nil, se
+// llama antes de cada handshake para limitar la tasa; false lo cancela.
+func (s *Scanner) certificatesAt(ctx context.Context, target, ip string, port int, wait func() bool) []domain.CertCheck {
+	var checks []domain.CertCheck
+	for _, sni := range []string{"", target} {
+		if wait != nil && !wait() {
+			break
+		}
+		chain, err := s.tlsProber.Certificates(ctx, ip, port, sni)
+		if err != nil {
+			s.logger.Debug().Err(err).Str("ip", ip).Int("port", port).Str("sni", sni).Msg("Sin certificado TLS")
+			if sni == "" && ctx.Err() == nil && isConnectError(err) {
+				return nil
+			}
+			continue
+		}
+		if len(chain) == 0 {
+			continue
+		}
+		if len(checks) > 0 && sameCert(checks[0].Chain[0], chain[0]) {
+			continue
+		}
+
+		check := domain.CertCheck{IP: ip, Port: port, SNI: sni, Chain: chain}
+		check.Names, check.Related = matchCert(target, chain[0].SANs)
+		if len(check.Names) > 0 {
+			check.Match = domain.CertMatchSNI
+			if sni == "" {
+				check.Match = domain.CertMatchDefault
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// matchCert separa los nombres de un certificado entre los que cubren el
+// dominio objetivo (el propio dominio, subdominios o wildcards, incluido el
+// del padre directo de un subdominio) y los de otros dominios, que se
+// reportan como activos relacionados. Las IPs no cuentan.
+func matchCert(target string, sans []string) (names, related []string) {
+	target = strings.TrimSuffix(strings.ToLower(target), ".")
+
+	seen := make(map[string]bool, len(sans))
+	for _, san := range sans {
+		name := strings.TrimSuffix(strings.ToLower(san), ".")
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if _, err := netip.ParseAddr(name); err == nil {
+			continue
+		}
+
+		bare := strings.TrimPrefix(name, "*.")
+		if bare == target || strings.HasSuffix(bare, "."+target) || wildcardCovers(name, target) {
+			names = append(names, name)
+		} else {
+			related = append(related, name)
+		}
+	}
+	sort.Strings(names)
+	sort.Strings(related)
+	return names, related
+}
+
+// wildcardCovers indica si un wildcard (*.example.com) cubre el nombre: el
+// comodín reemplaza exactamente una etiqueta
+func wildcardCovers(wildcard, name string) bool {
+	parent, ok := strings.CutPrefix(wildcard, "*.")
+	if !ok {
+		return false
+	}
+	label, ok := strings.CutSuffix(name, "."+parent)
+	return ok && label != "" && !strings.Contains(label, ".")
+}
+
+// isConnectError indica que no se pudo abrir la conexión TCP; reintentar con
+// SNI no cambiaría el resultado
+func isConnectError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func sameCert(a, b domain.CertInfo) bool {
+	return a.SPKI == b.SPKI && a.Serial == b.Serial
+}
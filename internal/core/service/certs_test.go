@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/tlsprobe"
+	"github.com/rs/zerolog"
+)
+
+func TestMatchCert(t *testing.T) {
+	tests := []struct {
+		target  string
+		sans    []string
+		names   []string
+		related []string
+	}{
+		{
+			"example.com",
+			[]string{"example.com", "*.example.com", "WWW.Example.com.", "www.example.com", "other.net", "*.cdn.other.net", "192.0.2.1", "2001:db8::1", ""},
+			[]string{"*.example.com", "example.com", "www.example.com"},
+			[]string{"*.cdn.other.net", "other.net"},
+		},
+		// El wildcard del padre cubre al subdominio; el apex no
+		{
+			"app.example.com",
+			[]string{"*.example.com", "example.com", "app.example.com", "api.app.example.com", "*.app.example.com"},
+			[]string{"*.app.example.com", "*.example.com", "api.app.example.com", "app.example.com"},
+			[]string{"example.com"},
+		},
+		// El comodín reemplaza una sola etiqueta
+		{
+			"a.b.example.com",
+			[]string{"*.example.com", "*.b.example.com"},
+			[]string{"*.b.example.com"},
+			[]string{"*.example.com"},
+		},
+		// Un sufijo que no es una etiqueta completa no cubre
+		{
+			"example.com",
+			[]string{"notexample.com", "*.notexample.com"},
+			nil,
+			[]string{"*.notexample.com", "notexample.com"},
+		},
+	}
+	for _, tt := range tests {
+		names, related := matchCert(tt.target, tt.sans)
+		if !slices.Equal(names, tt.names) || !slices.Equal(related, tt.related) {
+			t.Errorf("matchCert(%s, %v) = %v %v, esperado %v %v", tt.target, tt.sans, names, related, tt.names, tt.related)
+		}
+	}
+}
+
+func TestHarvestCertificates(t *testing.T) {
+	server := httptest.NewUnstartedServer(nil)
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := newTestScanner(&fakeResolver{}, nil)
+	WithTLSProber(tlsprobe.NewProber(nil, 5*time.Second, zerolog.Nop()))(scanner)
+
+	tests := []struct {
+		target  string
+		match   string
+		names   []string
+		related []string
+	}{
+		// El certificado por defecto de httptest nombra a example.com y
+		// *.example.com: el wildcard del padre cubre al subdominio
+		{"example.com", domain.CertMatchDefault, []string{"*.example.com", "example.com"}, nil},
+		{"www.example.com", domain.CertMatchDefault, []string{"*.example.com"}, []string{"example.com"}},
+		{"example.net", "", nil, []string{"*.example.com", "example.com"}},
+	}
+	for _, tt := range tests {
+		config := domain.ScannerConfig{Domain: tt.target, Threads: 2, TLSPorts: []int{port}}
+		checks := scanner.harvestCertificates(context.Background(), config, nil, []string{host}, nil)
+
+		// Con y sin SNI se presenta el mismo certificado: se conserva uno
+		if len(checks) != 1 {
+			t.Fatalf("%s: %d certificados, esperado 1", tt.target, len(checks))
+		}
+		check := checks[0]
+		if check.IP != host || check.Port != port || check.SNI != "" {
+			t.Errorf("%s: check = %s:%d sni=%q", tt.target, check.IP, check.Port, check.SNI)
+		}
+		if check.Match != tt.match || !slices.Equal(check.Names, tt.names) || !slices.Equal(check.Related, tt.related) {
+			t.Errorf("%s: match=%q names=%v related=%v, esperado %q %v %v", tt.target, check.Match, check.Names, check.Related, tt.match, tt.names, tt.related)
+		}
+	}
+
+	// Un puerto cerrado no produce certificados
+	config := domain.ScannerConfig{Domain: "example.com", Threads: 1, TLSPorts: []int{port}}
+	server.Close()
+	if checks := scanner.harvestCertificates(context.Background(), config, nil, []string{host}, nil); len(checks) != 0 {
+		t.Errorf("puerto cerrado: %+v", checks)
+	}
+}
@@ -24,7 +24,7 @@ func (rc *ResultCollector) Collect(result domain.ResultEntry) {
 	rc.mu.Lock()
 	defer rc.mu.Unlock()
 
-	if !rc.isDuplicate(result) {
+	if !rc.merge(result) {
 		rc.results[result.FQDN] = append(rc.results[result.FQDN], result)
 		rc.logger.Debug().
 			Str("fqdn", result.FQDN).
@@ -34,16 +34,29 @@ func (rc *ResultCollector) Collect(result domain.ResultEntry) {
 	}
 }
 
-func (rc *ResultCollector) isDuplicate(result domain.ResultEntry) bool {
-	existing, exists := rc.results[result.FQDN]
-	if !exists {
-		return false
-	}
-
-	for _, entry := range existing {
-		if entry.IP == result.IP && entry.Type == result.Type {
-			return true
+// merge completa un resultado ya colectado con la misma IP y tipo; la misma
+// IP puede llegar por la resolución directa y luego por el CNAME, que aporta
+// el destino y el producto. Retorna false si el resultado es nuevo.
+func (rc *ResultCollector) merge(result domain.ResultEntry) bool {
+	existing := rc.results[result.FQDN]
+	for i := range existing {
+		entry := &existing[i]
+		if entry.IP != result.IP || entry.Type != result.Type {
+			continue
+		}
+		if entry.CNAME == "" {
+			entry.CNAME = result.CNAME
+		}
+		if entry.CNAMEProvider == "" {
+			entry.CNAMEProvider = result.CNAMEProvider
+		}
+		if entry.Product == "" {
+			entry.Product = result.Product
+		}
+		if entry.Provider == "" && result.Provider != "" {
+			entry.Provider, entry.Prefix, entry.Proxied = result.Provider, result.Prefix, result.Proxied
 		}
+		return true
 	}
 	return false
 }
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// enrichHosts visita cada par (host, IP) por HTTPS y HTTP siguiendo las
+// redirecciones y guarda en la respuesta el estado, URL final, título,
+// servidor, tamaño y tiempo de cada esquema que respondió
+func (s *Scanner) enrichHosts(ctx context.Context, config domain.ScannerConfig, answers map[string][]domain.ResultEntry) {
+	type target struct{ fqdn, ip string }
+
+	var targets []target
+	for fqdn, entries := range answers {
+		seen := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			if entry.IP != "" && !seen[entry.IP] {
+				seen[entry.IP] = true
+				targets = append(targets, target{fqdn: fqdn, ip: entry.IP})
+			}
+		}
+	}
+
+	workers := config.EnrichThreads
+	if workers <= 0 {
+		workers = max(config.Threads, 1)
+	}
+	s.logger.Info().Int("targets", len(targets)).Int("workers", workers).Msg("Iniciando enriquecimiento HTTP")
+
+	var (
+		mu     sync.Mutex
+		group  = newBoundedGroup(ctx, workers)
+		visits = make(map[target][]domain.WebProbe, len(targets))
+	)
+
+dispatch:
+	for _, t := range targets {
+		for _, scheme := range []string{"https", "http"} {
+			dispatched := group.Go(func() {
+				visit, err := s.webProber.Visit(ctx, t.ip, t.fqdn, scheme)
+				if err != nil {
+					s.logger.Debug().Err(err).Str("fqdn", t.fqdn).Str("ip", t.ip).Str("scheme", scheme).Msg("Visita HTTP sin respuesta")
+					return
+				}
+				mu.Lock()
+				visits[t] = append(visits[t], visit)
+				mu.Unlock()
+			})
+			if !dispatched {
+				break dispatch
+			}
+		}
+	}
+	group.Wait()
+
+	for fqdn, entries := range answers {
+		for i := range entries {
+			web := visits[target{fqdn: fqdn, ip: entries[i].IP}]
+			if len(web) == 0 {
+				continue
+			}
+			// HTTPS primero, sin importar cuál respondió antes
+			if len(web) > 1 && web[0].Scheme != "https" {
+				web[0], web[1] = web[1], web[0]
+			}
+			entries[i].Web = web
+		}
+	}
+
+	s.logger.Info().
+		Int("targets", len(targets)).
+		Int("responded", len(visits)).
+		Msg("Enriquecimiento HTTP completado")
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/httpprobe"
+	"github.com/rs/zerolog"
+)
+
+// webKeys resume las visitas como "esquema título"
+func webKeys(web []domain.WebProbe) []string {
+	keys := make([]string, 0, len(web))
+	for _, visit := range web {
+		keys = append(keys, visit.Scheme+" "+visit.Title)
+	}
+	return keys
+}
+
+func TestEnrichHosts(t *testing.T) {
+	secure := domain.WebProbe{Scheme: "https", Status: http.StatusOK, FinalURL: "https://www.example.com/", Title: "Example"}
+	plain := domain.WebProbe{Scheme: "http", Status: http.StatusMovedPermanently, FinalURL: "http://www.example.com/"}
+	prober := &fakeWebProber{
+		visits: map[string]domain.WebProbe{
+			"192.0.2.1 https": secure,
+			"192.0.2.1 http":  plain,
+			"192.0.2.2 http":  plain,
+		},
+		// HTTPS responde después de HTTP: el resultado igual empieza por HTTPS
+		onVisit: func(ip, host, scheme string) {
+			if scheme == "https" {
+				time.Sleep(20 * time.Millisecond)
+			}
+		},
+	}
+	scanner := newTestScanner(&fakeResolver{}, nil)
+	scanner.webProber = prober
+
+	answers := map[string][]domain.ResultEntry{
+		"www.example.com": {{CNAME: "edge.example.net"}, {IP: "192.0.2.1"}, {IP: "192.0.2.1"}},
+		"api.example.com": {{IP: "192.0.2.2"}},
+		"old.example.com": {{IP: "192.0.2.3"}},
+	}
+	config := domain.ScannerConfig{Domain: "example.com", Threads: 1, EnrichThreads: 4}
+	scanner.enrichHosts(context.Background(), config, answers)
+
+	// Cada par (host, IP) se visita una vez por esquema
+	if len(prober.calls) != 6 {
+		t.Errorf("visitas = %v, esperado 6", prober.calls)
+	}
+	for key, n := range prober.calls {
+		if n != 1 {
+			t.Errorf("%s visitado %d veces", key, n)
+		}
+	}
+
+	www := answers["www.example.com"]
+	if www[0].Web != nil {
+		t.Errorf("entrada sin IP enriquecida: %+v", www[0].Web)
+	}
+	for _, entry := range www[1:] {
+		if got := webKeys(entry.Web); !slices.Equal(got, []string{"https Example", "http "}) {
+			t.Errorf("www %s: web = %+v, esperado https y http", entry.IP, entry.Web)
+		}
+	}
+	if web := answers["api.example.com"][0].Web; !slices.Equal(webKeys(web), []string{"http "}) {
+		t.Errorf("api: web = %+v, esperado solo http", web)
+	}
+	if web := answers["old.example.com"][0].Web; web != nil {
+		t.Errorf("old: web = %+v, esperado sin respuesta", web)
+	}
+}
+
+func TestEnrichHostsRedirects(t *testing.T) {
+	const host = "www.example.com"
+
+	// HTTP redirige al login; HTTPS a /app y de ahí a /app/ en el mismo host
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("<title>Login</title>"))
+	}))
+	t.Cleanup(plain.Close)
+	ip, httpsPort := vhostServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != host {
+			t.Errorf("Host = %q, esperado %q", r.Host, host)
+		}
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "https://"+host+"/app", http.StatusMovedPermanently)
+		case "/app":
+			http.Redirect(w, r, "/app/", http.StatusMovedPermanently)
+		default:
+			w.Header().Set("Server", "nginx")
+			_, _ = w.Write([]byte("<title>Example App</title>"))
+		}
+	})
+	_, httpPortStr, err := net.SplitHostPort(plain.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpPort, err := strconv.Atoi(httpPortStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prober := httpprobe.NewProber(nil, 5*time.Second, zerolog.Nop())
+	prober.SetPorts(httpPort, httpsPort)
+	scanner := newTestScanner(&fakeResolver{}, nil)
+	WithWebProber(prober)(scanner)
+
+	answers := map[string][]domain.ResultEntry{host: {{IP: ip}}}
+	scanner.enrichHosts(context.Background(), domain.ScannerConfig{Domain: "example.com", Threads: 2}, answers)
+
+	web := answers[host][0].Web
+	if len(web) != 2 {
+		t.Fatalf("web = %+v, esperado https y http", web)
+	}
+	secure, insecure := web[0], web[1]
+	if want := []string{"https://" + host + "/app", "https://" + host + "/app/"}; secure.Scheme != "https" || !slices.Equal(secure.Redirects, want) {
+		t.Errorf("https: %s redirects = %v, esperado %v", secure.Scheme, secure.Redirects, want)
+	}
+	if secure.Status != http.StatusOK || secure.FinalURL != "https://"+host+"/app/" || secure.Title != "Example App" || secure.Server != "nginx" {
+		t.Errorf("https: visita inesperada %+v", secure)
+	}
+	if want := []string{"http://" + host + "/login"}; insecure.Scheme != "http" || !slices.Equal(insecure.Redirects, want) {
+		t.Errorf("http: %s redirects = %v, esperado %v", insecure.Scheme, insecure.Redirects, want)
+	}
+	if insecure.Status != http.StatusOK || insecure.FinalURL != "http://"+host+"/login" || insecure.Title != "Login" {
+		t.Errorf("http: visita inesperada %+v", insecure)
+	}
+}
+
+func TestEnrichHostsCancelled(t *testing.T) {
+	prober := &fakeWebProber{}
+	scanner := newTestScanner(&fakeResolver{}, nil)
+	scanner.webProber = prober
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	answers := map[string][]domain.ResultEntry{"www.example.com": {{IP: "192.0.2.1"}}}
+	scanner.enrichHosts(ctx, domain.ScannerConfig{Domain: "example.com", Threads: 2}, answers)
+	if len(prober.calls) != 0 {
+		t.Errorf("visitas tras cancelar: %v", prober.calls)
+	}
+}
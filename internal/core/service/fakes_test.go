@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/rs/zerolog"
+)
+
+// fakeResolver responde desde mapas fijos; los nombres ausentes son NXDOMAIN
+type fakeResolver struct {
+	ips    map[string][]string
+	cnames map[string]string
+	txt    map[string][]string
+	mx     map[string][]string
+	ptr    map[string][]string
+
+	mu      sync.Mutex
+	queries map[string]int // consultas por "tipo nombre"
+}
+
+// count registra la consulta; como un resolver real, falla si el contexto ya
+// venció (ej: un timeout de 0)
+func (f *fakeResolver) count(ctx context.Context, kind, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.queries == nil {
+		f.queries = make(map[string]int)
+	}
+	f.queries[kind+" "+name]++
+	return ctx.Err()
+}
+
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func lookup[V any](m map[string]V, name string) (V, error) {
+	if v, ok := m[strings.TrimSuffix(strings.ToLower(name), ".")]; ok {
+		return v, nil
+	}
+	var zero V
+	return zero, notFound(name)
+}
+
+func (f *fakeResolver) LookupIP(ctx context.Context, fqdn string) ([]string, error) {
+	if err := f.count(ctx, "A", fqdn); err != nil {
+		return nil, err
+	}
+	return lookup(f.ips, fqdn)
+}
+
+func (f *fakeResolver) LookupCNAME(ctx context.Context, fqdn string) (string, error) {
+	if err := f.count(ctx, "CNAME", fqdn); err != nil {
+		return "", err
+	}
+	return lookup(f.cnames, fqdn)
+}
+
+func (f *fakeResolver) LookupTXT(ctx context.Context, fqdn string) ([]string, error) {
+	if err := f.count(ctx, "TXT", fqdn); err != nil {
+		return nil, err
+	}
+	return lookup(f.txt, fqdn)
+}
+
+func (f *fakeResolver) LookupMX(ctx context.Context, fqdn string) ([]string, error) {
+	if err := f.count(ctx, "MX", fqdn); err != nil {
+		return nil, err
+	}
+	return lookup(f.mx, fqdn)
+}
+
+func (f *fakeResolver) LookupAddr(ctx context.Context, ip string) ([]string, error) {
+	if err := f.count(ctx, "PTR", ip); err != nil {
+		return nil, err
+	}
+	return lookup(f.ptr, ip)
+}
+
+// fakeRegistry clasifica por una lista de prefijos (el más largo gana) y
+// sufijos CNAME
+type fakeRegistry struct {
+	prefixes map[string]string // CIDR → proveedor
+	suffixes map[string]string // sufijo → proveedor
+}
+
+func (f *fakeRegistry) Register(domain.CDNProvider)       {}
+func (f *fakeRegistry) SetRanges(string, domain.CFRanges) {}
+func (f *fakeRegistry) Providers() []string               { return nil }
+
+func (f *fakeRegistry) ClassifyCNAME(target string) (string, bool) {
+	for suffix, provider := range f.suffixes {
+		if strings.HasSuffix(target, "."+suffix) {
+			return provider, true
+		}
+	}
+	return "", false
+}
+
+func (f *fakeRegistry) ClassifyIP(ip string) (string, string, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", "", false
+	}
+	var best netip.Prefix
+	provider := ""
+	for cidr, name := range f.prefixes {
+		prefix := netip.MustParsePrefix(cidr)
+		if prefix.Contains(addr.Unmap()) && (provider == "" || prefix.Bits() > best.Bits()) {
+			best, provider = prefix, name
+		}
+	}
+	if provider == "" {
+		return "", "", false
+	}
+	return provider, best.String(), true
+}
+
+// fakeCloudflare reconoce productos por sufijo CNAME
+type fakeCloudflare struct {
+	products map[string]string // sufijo → producto
+}
+
+func (f *fakeCloudflare) GetRanges(context.Context, bool) (domain.CFRanges, error) {
+	return domain.CFRanges{}, nil
+}
+
+func (f *fakeCloudflare) ProductFromCNAME(target string) (string, bool) {
+	for suffix, product := range f.products {
+		if strings.HasSuffix(target, "."+suffix) {
+			return product, true
+		}
+	}
+	return "", false
+}
+
+// newTestScanner arma un Scanner con los fakes y sin etapas opcionales
+func newTestScanner(resolver *fakeResolver, registry *fakeRegistry) *Scanner {
+	if registry == nil {
+		registry = &fakeRegistry{}
+	}
+	metrics := NewMetricsCollector()
+	return NewScanner(resolver, &fakeCloudflare{products: map[string]string{
+		"cfargotunnel.com": domain.CFProductTunnel,
+		"pages.dev":        domain.CFProductPages,
+	}}, registry, nil, nil, nil, metrics, NewHealthChecker(metrics), zerolog.Nop())
+}
+
+// fakeAccount retorna un inventario fijo, o err en ambas consultas
+type fakeAccount struct {
+	inventory domain.ZoneInventory
+	err       error
+}
+
+func (f *fakeAccount) Zone(context.Context, string) (string, error) {
+	return f.inventory.Zone, f.err
+}
+
+func (f *fakeAccount) Inventory(context.Context, string) (domain.ZoneInventory, error) {
+	return f.inventory, f.err
+}
+
+// fakeHTTPProber responde con la huella fija de cada IP; las IPs ausentes no
+// responden
+type fakeHTTPProber struct {
+	fingerprints map[string]domain.HTTPFingerprint
+
+	mu    sync.Mutex
+	calls map[string]int // consultas por "ip host"
+}
+
+func (f *fakeHTTPProber) Probe(_ context.Context, ip, host string) (domain.HTTPFingerprint, error) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.calls[ip+" "+host]++
+	f.mu.Unlock()
+
+	fp, ok := f.fingerprints[ip]
+	if !ok {
+		return domain.HTTPFingerprint{}, errors.New("connection refused")
+	}
+	return fp, nil
+}
+
+// fakeWebProber responde con la visita fija de cada "ip esquema"; los pares
+// ausentes no responden. onVisit se llama antes de responder.
+type fakeWebProber struct {
+	visits  map[string]domain.WebProbe
+	onVisit func(ip, host, scheme string)
+
+	mu    sync.Mutex
+	calls map[string]int // visitas por "ip host esquema"
+}
+
+func (f *fakeWebProber) Visit(_ context.Context, ip, host, scheme string) (domain.WebProbe, error) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.calls[ip+" "+host+" "+scheme]++
+	f.mu.Unlock()
+	if f.onVisit != nil {
+		f.onVisit(ip, host, scheme)
+	}
+
+	visit, ok := f.visits[ip+" "+scheme]
+	if !ok {
+		return domain.WebProbe{}, errors.New("connection refused")
+	}
+	return visit, nil
+}
+
+// fakeVerifier responde con el resumen fijo de cada IP (o "ip:puerto") y
+// puntúa por el título del candidato; onSnapshot se llama en cada consulta
+type fakeVerifier struct {
+	snapshots  map[string]domain.PageSnapshot
+	scores     map[string]float64 // título del candidato → similitud
+	onSnapshot func(ip string, port int, host string)
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *fakeVerifier) Snapshot(_ context.Context, ip string, port int, host string) (domain.PageSnapshot, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	if f.onSnapshot != nil {
+		f.onSnapshot(ip, port, host)
+	}
+
+	key := ip
+	if port != 0 {
+		key = net.JoinHostPort(ip, strconv.Itoa(port))
+	}
+	snapshot, ok := f.snapshots[key]
+	if !ok {
+		return domain.PageSnapshot{}, errors.New("connection refused")
+	}
+	return snapshot, nil
+}
+
+func (f *fakeVerifier) Compare(_, candidate domain.PageSnapshot) float64 {
+	return f.scores[candidate.Title]
+}
+
+// fakeFavicons responde con los íconos fijos de cada IP; las IPs ausentes no
+// responden. onFetch se llama en cada consulta.
+type fakeFavicons struct {
+	favicons map[string][]domain.Favicon
+	onFetch  func(ip, host string)
+
+	mu    sync.Mutex
+	calls map[string]int // consultas por "ip host"
+}
+
+func (f *fakeFavicons) Favicons(_ context.Context, ip, host string) ([]domain.Favicon, error) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.calls[ip+" "+host]++
+	f.mu.Unlock()
+	if f.onFetch != nil {
+		f.onFetch(ip, host)
+	}
+
+	favicons, ok := f.favicons[ip]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	return favicons, nil
+}
+
+func (f *fakeFavicons) total() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, c := range f.calls {
+		n += c
+	}
+	return n
+}
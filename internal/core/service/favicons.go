@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// matchFavicons obtiene los íconos de cada host a través del CDN y busca las
+// IPs candidatas que sirven alguno de ellos. Muchos orígenes sirven el mismo
+// ícono aunque el Host no coincida, así que cada coincidencia se agrega como
+// evidencia a las respuestas de esa IP.
+func (s *Scanner) matchFavicons(ctx context.Context, config domain.ScannerConfig, answers map[string][]domain.ResultEntry, candidates []string) []domain.FaviconCheck {
+	fronted := frontedHosts(answers)
+	if len(candidates) == 0 || len(fronted) == 0 {
+		return nil
+	}
+
+	s.logger.Info().
+		Int("candidates", len(candidates)).
+		Int("fronted_hosts", len(fronted)).
+		Msg("Comparando favicons")
+
+	var checks []domain.FaviconCheck
+
+	for fqdn, front := range fronted {
+		if ctx.Err() != nil {
+			break
+		}
+
+		check, ok := s.frontFavicons(ctx, fqdn, front)
+		if !ok {
+			continue
+		}
+		known := make(map[string]bool, len(check.Favicons))
+		for _, favicon := range check.Favicons {
+			known[favicon.SHA256] = true
+			s.logger.Info().
+				Str("fqdn", fqdn).
+				Str("path", favicon.Path).
+				Int32("mmh3", favicon.MMH3).
+				Msg("Favicon del sitio")
+		}
+
+		var (
+			mu    sync.Mutex
+			group = newBoundedGroup(ctx, config.Threads)
+		)
+		for _, ip := range candidates {
+			dispatched := group.Go(func() {
+				favicons, err := s.faviconFetcher.Favicons(ctx, ip, fqdn)
+				if err != nil {
+					s.logger.Debug().Err(err).Str("fqdn", fqdn).Str("ip", ip).Msg("Candidato sin favicon")
+					return
+				}
+				for _, favicon := range favicons {
+					if !known[favicon.SHA256] {
+						continue
+					}
+					mu.Lock()
+					check.Matches = append(check.Matches, domain.FaviconMatch{IP: ip, Favicon: favicon})
+					mu.Unlock()
+					return
+				}
+			})
+			if !dispatched {
+				break
+			}
+		}
+		group.Wait()
+
+		sort.Slice(check.Matches, func(i, j int) bool {
+			return check.Matches[i].IP < check.Matches[j].IP
+		})
+		checks = append(checks, check)
+	}
+
+	sort.Slice(checks, func(i, j int) bool {
+		return checks[i].FQDN < checks[j].FQDN
+	})
+
+	matches := 0
+	for _, check := range checks {
+		for _, match := range check.Matches {
+			matches++
+			addEvidence(answers, match.IP, "favicon:"+check.FQDN)
+			s.logger.Warn().
+				Str("fqdn", check.FQDN).
+				Str("ip", match.IP).
+				Int32("mmh3", match.Favicon.MMH3).
+				Msg("Candidato sirve el favicon del sitio")
+		}
+	}
+	s.logger.Info().
+		Int("hosts", len(checks)).
+		Int("matches", matches).
+		Msg("Comparación de favicons completada")
+
+	return checks
+}
+
+// frontFavicons obtiene los íconos del host a través de sus IPs de CDN
+func (s *Scanner) frontFavicons(ctx context.Context, fqdn string, front []string) (domain.FaviconCheck, bool) {
+	for _, ip := range front {
+		favicons, err := s.faviconFetcher.Favicons(ctx, ip, fqdn)
+		if err != nil {
+			s.logger.Debug().Err(err).Str("fqdn", fqdn).Str("ip", ip).Msg("Error obteniendo favicon a través del CDN")
+			continue
+		}
+		if len(favicons) > 0 {
+			return domain.FaviconCheck{FQDN: fqdn, Via: ip, Favicons: favicons}, true
+		}
+	}
+	s.logger.Debug().Str("fqdn", fqdn).Msg("Sitio sin favicon")
+	return domain.FaviconCheck{}, false
+}
+
+// addEvidence agrega una evidencia de origen a las respuestas con la IP
+func addEvidence(answers map[string][]domain.ResultEntry, ip, evidence string) {
+	for _, entries := range answers {
+		for i := range entries {
+			if entries[i].IP == ip && !slices.Contains(entries[i].Evidence, evidence) {
+				entries[i].Evidence = append(entries[i].Evidence, evidence)
+			}
+		}
+	}
+}
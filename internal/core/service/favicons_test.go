@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+var (
+	siteIcon  = domain.Favicon{Path: "/favicon.ico", MMH3: 116323821, SHA256: "aa11"}
+	touchIcon = domain.Favicon{Path: "/apple-touch-icon.png", MMH3: -305179312, SHA256: "bb22"}
+	nginxIcon = domain.Favicon{Path: "/favicon.ico", MMH3: -1137974102, SHA256: "cc33"}
+)
+
+func TestMatchFavicons(t *testing.T) {
+	fetcher := &fakeFavicons{favicons: map[string][]domain.Favicon{
+		"104.16.1.1": {siteIcon, touchIcon},
+		// Sitio sin favicon a través del CDN
+		"104.16.2.2":  {},
+		"203.0.113.1": {siteIcon},
+		// Otra ruta, mismo contenido que un ícono del sitio
+		"203.0.113.2": {nginxIcon, {Path: "/static/touch.png", SHA256: "bb22"}},
+		"203.0.113.3": {nginxIcon},
+	}}
+	scanner := newTestScanner(&fakeResolver{}, nil)
+	scanner.faviconFetcher = fetcher
+
+	answers := map[string][]domain.ResultEntry{
+		"www.example.com":   {{FQDN: "www.example.com", IP: "104.16.1.1", Provider: domain.ProviderCloudflare}},
+		"nofav.example.com": {{FQDN: "nofav.example.com", IP: "104.16.2.2", Provider: domain.ProviderCloudflare}},
+		// La misma IP candidata en dos hosts recibe la evidencia en ambos
+		"origin.example.com": {{FQDN: "origin.example.com", IP: "203.0.113.1"}},
+		"mail.example.com":   {{FQDN: "mail.example.com", IP: "203.0.113.1"}, {FQDN: "mail.example.com", IP: "203.0.113.3"}},
+		"dev.example.com":    {{FQDN: "dev.example.com", IP: "203.0.113.2"}},
+	}
+	candidates := []string{"203.0.113.1", "203.0.113.2", "203.0.113.3", "203.0.113.4"}
+	checks := scanner.matchFavicons(context.Background(), domain.ScannerConfig{Threads: 2}, answers, candidates)
+
+	// El host sin favicon no produce comparación ni consulta candidatos
+	if len(checks) != 1 {
+		t.Fatalf("checks = %+v, esperado solo www", checks)
+	}
+	for _, ip := range candidates {
+		if n := fetcher.calls[ip+" nofav.example.com"]; n != 0 {
+			t.Errorf("%s consultado para nofav %d veces", ip, n)
+		}
+	}
+
+	check := checks[0]
+	if check.FQDN != "www.example.com" || check.Via != "104.16.1.1" || len(check.Favicons) != 2 {
+		t.Errorf("check = %+v", check)
+	}
+	want := []domain.FaviconMatch{
+		{IP: "203.0.113.1", Favicon: siteIcon},
+		{IP: "203.0.113.2", Favicon: domain.Favicon{Path: "/static/touch.png", SHA256: "bb22"}},
+	}
+	if !slices.Equal(check.Matches, want) {
+		t.Errorf("matches = %+v, esperado %+v", check.Matches, want)
+	}
+
+	evidence := []struct {
+		fqdn string
+		ip   string
+		want []string
+	}{
+		{"origin.example.com", "203.0.113.1", []string{"favicon:www.example.com"}},
+		{"mail.example.com", "203.0.113.1", []string{"favicon:www.example.com"}},
+		{"mail.example.com", "203.0.113.3", nil},
+		{"dev.example.com", "203.0.113.2", []string{"favicon:www.example.com"}},
+		{"www.example.com", "104.16.1.1", nil},
+	}
+	for _, tt := range evidence {
+		for _, entry := range answers[tt.fqdn] {
+			if entry.IP == tt.ip && !slices.Equal(entry.Evidence, tt.want) {
+				t.Errorf("%s %s: evidencia %v, esperado %v", tt.fqdn, tt.ip, entry.Evidence, tt.want)
+			}
+		}
+	}
+
+	// La evidencia no se duplica
+	addEvidence(answers, "203.0.113.1", "favicon:www.example.com")
+	if got := answers["origin.example.com"][0].Evidence; len(got) != 1 {
+		t.Errorf("evidencia duplicada: %v", got)
+	}
+}
+
+func TestMatchFaviconsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fetcher := &fakeFavicons{
+		favicons: map[string][]domain.Favicon{"104.16.1.1": {siteIcon}},
+		// Se cancela durante la primera consulta a un candidato
+		onFetch: func(ip, _ string) {
+			if ip != "104.16.1.1" {
+				cancel()
+			}
+		},
+	}
+	scanner := newTestScanner(&fakeResolver{}, nil)
+	scanner.faviconFetcher = fetcher
+
+	answers := map[string][]domain.ResultEntry{"www.example.com": {{IP: "104.16.1.1", Provider: domain.ProviderCloudflare}}}
+	candidates := []string{"203.0.113.1", "203.0.113.2", "203.0.113.3", "203.0.113.4"}
+	scanner.matchFavicons(ctx, domain.ScannerConfig{Threads: 1}, answers, candidates)
+
+	// El sitio y un solo candidato: el resto no se despacha
+	if n := fetcher.total(); n != 2 {
+		t.Errorf("%d consultas tras cancelar, esperado 2", n)
+	}
+}
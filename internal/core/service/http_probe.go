@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// probeHTTP confirma por cabeceras HTTP el proveedor de cada respuesta con IP
+// y marca las discrepancias con la clasificación por rangos. Cada par
+// (host, IP) se consulta una sola vez.
+func (s *Scanner) probeHTTP(ctx context.Context, config domain.ScannerConfig, answers map[string][]domain.ResultEntry) {
+	type target struct{ fqdn, ip string }
+
+	var targets []target
+	for fqdn, entries := range answers {
+		seen := make(map[string]bool, len(entries))
+		for _, entry := range entries {
+			if entry.IP != "" && !seen[entry.IP] {
+				seen[entry.IP] = true
+				targets = append(targets, target{fqdn: fqdn, ip: entry.IP})
+			}
+		}
+	}
+
+	s.logger.Info().Int("targets", len(targets)).Msg("Iniciando sondeo HTTP")
+
+	var (
+		mu           sync.Mutex
+		group        = newBoundedGroup(ctx, config.Threads)
+		fingerprints = make(map[target]domain.HTTPFingerprint, len(targets))
+	)
+
+	for _, t := range targets {
+		dispatched := group.Go(func() {
+			fp, err := s.httpProber.Probe(ctx, t.ip, t.fqdn)
+			if err != nil {
+				s.logger.Debug().Err(err).Str("fqdn", t.fqdn).Str("ip", t.ip).Msg("Sondeo HTTP sin respuesta")
+				return
+			}
+			mu.Lock()
+			fingerprints[t] = fp
+			mu.Unlock()
+		})
+		if !dispatched {
+			break
+		}
+	}
+	group.Wait()
+
+	mismatches := 0
+	for fqdn, entries := range answers {
+		for i := range entries {
+			entry := &entries[i]
+			fp, ok := fingerprints[target{fqdn: fqdn, ip: entry.IP}]
+			if !ok {
+				continue
+			}
+			entry.HTTP = &fp
+			if entry.Mismatch = mismatch(entry.Provider, fp.Provider); entry.Mismatch != "" {
+				mismatches++
+				s.logger.Warn().
+					Str("fqdn", fqdn).
+					Str("ip", entry.IP).
+					Str("mismatch", entry.Mismatch).
+					Strs("signals", fp.Signals).
+					Msg("Clasificación por rangos y por cabeceras no coinciden")
+			}
+		}
+	}
+
+	s.logger.Info().
+		Int("responded", len(fingerprints)).
+		Int("mismatches", mismatches).
+		Msg("Sondeo HTTP completado")
+}
+
+// mismatch describe una discrepancia entre el proveedor por rangos/CNAME y el
+// detectado por cabeceras. range=none con cabeceras de un CDN suele indicar
+// BYOIP o rangos desactualizados; header=none con rango de un CDN, un origen
+// alojado en su red o un proxy que elimina las cabeceras.
+func mismatch(rangeProvider, headerProvider string) string {
+	if rangeProvider == headerProvider {
+		return ""
+	}
+	if rangeProvider == "" {
+		rangeProvider = "none"
+	}
+	if headerProvider == "" {
+		headerProvider = "none"
+	}
+	return "range=" + rangeProvider + " header=" + headerProvider
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+func TestProbeHTTPMismatch(t *testing.T) {
+	cloudflare := domain.HTTPFingerprint{Scheme: "https", Status: 200, Provider: domain.ProviderCloudflare, Signals: []string{"cf-ray"}}
+	prober := &fakeHTTPProber{fingerprints: map[string]domain.HTTPFingerprint{
+		"104.16.1.1":   cloudflare,
+		"203.0.113.10": cloudflare,
+		"104.16.2.2":   {Scheme: "https", Status: 200, Server: "nginx"},
+		"203.0.113.20": {Scheme: "http", Status: 200, Server: "nginx"},
+	}}
+	scanner := newTestScanner(&fakeResolver{}, nil)
+	scanner.httpProber = prober
+
+	answers := map[string][]domain.ResultEntry{
+		// Rango y cabeceras coinciden
+		"www.example.com": {{FQDN: "www.example.com", IP: "104.16.1.1", Provider: domain.ProviderCloudflare, Proxied: true}},
+		// Cabeceras de Cloudflare en una IP fuera de sus rangos (BYOIP)
+		"byoip.example.com": {{FQDN: "byoip.example.com", IP: "203.0.113.10"}},
+		// Rango de Cloudflare sin sus cabeceras
+		"bare.example.com": {{FQDN: "bare.example.com", IP: "104.16.2.2", Provider: domain.ProviderCloudflare, Proxied: true}},
+		// Origen sin CDN: sin discrepancia
+		"origin.example.com": {
+			{FQDN: "origin.example.com", IP: "203.0.113.20"},
+			{FQDN: "origin.example.com", IP: "203.0.113.20", Type: "A"},
+		},
+		// Sin respuesta HTTP: sin huella
+		"down.example.com": {{FQDN: "down.example.com", IP: "203.0.113.99"}},
+	}
+	scanner.probeHTTP(context.Background(), domain.ScannerConfig{Threads: 2}, answers)
+
+	tests := []struct {
+		fqdn     string
+		provider string
+		mismatch string
+	}{
+		{"www.example.com", domain.ProviderCloudflare, ""},
+		{"byoip.example.com", domain.ProviderCloudflare, "range=none header=cloudflare"},
+		{"bare.example.com", "", "range=cloudflare header=none"},
+		{"origin.example.com", "", ""},
+	}
+	for _, tt := range tests {
+		for _, entry := range answers[tt.fqdn] {
+			if entry.HTTP == nil {
+				t.Errorf("%s: sin huella HTTP", tt.fqdn)
+				continue
+			}
+			if entry.HTTP.Provider != tt.provider || entry.Mismatch != tt.mismatch {
+				t.Errorf("%s: proveedor %q discrepancia %q, esperado %q %q", tt.fqdn, entry.HTTP.Provider, entry.Mismatch, tt.provider, tt.mismatch)
+			}
+		}
+	}
+	if entry := answers["down.example.com"][0]; entry.HTTP != nil || entry.Mismatch != "" {
+		t.Errorf("down = %+v, sin respuesta no debería tener huella", entry)
+	}
+
+	// Cada par (host, IP) se consulta una sola vez
+	if n := prober.calls["203.0.113.20 origin.example.com"]; n != 1 {
+		t.Errorf("origin consultado %d veces, esperado 1", n)
+	}
+}
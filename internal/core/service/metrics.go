@@ -32,6 +32,7 @@ func (mc *MetricsCollector) Start() {
 	mc.metrics.SuccessCount = 0
 	mc.metrics.ErrorCount = 0
 	mc.metrics.DNSQueries = 0
+	mc.metrics.Retries = 0
 	mc.metrics.WorkerStats = make(map[int]domain.WorkerStat)
 }
 
@@ -65,6 +66,13 @@ func (mc *MetricsCollector) IncrementDNSQuery() {
 	mc.metrics.DNSQueries++
 }
 
+func (mc *MetricsCollector) IncrementRetry() {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	mc.metrics.Retries++
+}
+
 func (mc *MetricsCollector) RecordWorkerActivity(workerID int) {
 	mc.mu.Lock()
 	defer mc.mu.Unlock()
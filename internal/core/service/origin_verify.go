@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// Umbrales de similitud para los veredictos
+const (
+	confirmedScore = 0.85
+	likelyScore    = 0.6
+)
+
+// verifyOrigins compara, para cada host detrás de un CDN, el sitio servido por
+// el CDN con la respuesta de cada IP expuesta pedida con el mismo Host/SNI.
+// Con el escaneo de puertos (open no nil) se consultan los puertos abiertos
+// de cada IP en lugar de los estándar.
+func (s *Scanner) verifyOrigins(ctx context.Context, config domain.ScannerConfig, answers map[string][]domain.ResultEntry, candidates []string, open map[string][]int) []domain.OriginCheck {
+	fronted := frontedHosts(answers)
+	if len(candidates) == 0 || len(fronted) == 0 {
+		s.logger.Info().
+			Int("candidates", len(candidates)).
+			Int("fronted_hosts", len(fronted)).
+			Msg("Sin orígenes candidatos que verificar")
+		return nil
+	}
+
+	s.logger.Info().
+		Int("candidates", len(candidates)).
+		Int("fronted_hosts", len(fronted)).
+		Msg("Verificando orígenes candidatos")
+
+	var (
+		mu     sync.Mutex
+		group  = newBoundedGroup(ctx, config.Threads)
+		checks []domain.OriginCheck
+	)
+
+hosts:
+	for fqdn, front := range fronted {
+		if ctx.Err() != nil {
+			break
+		}
+
+		baseline, via, ok := s.baseline(ctx, fqdn, front)
+		if !ok {
+			continue
+		}
+
+		for _, ip := range candidates {
+			for _, port := range httpPorts(open, ip) {
+				dispatched := group.Go(func() {
+					candidate, err := s.originVerifier.Snapshot(ctx, ip, port, fqdn)
+					if err != nil {
+						s.logger.Debug().Err(err).Str("fqdn", fqdn).Str("ip", ip).Int("port", port).Msg("Candidato sin respuesta HTTP")
+						return
+					}
+
+					score := s.originVerifier.Compare(baseline, candidate)
+					check := domain.OriginCheck{
+						FQDN:     fqdn,
+						IP:       ip,
+						Port:     port,
+						Via:      via,
+						Score:    math.Round(score*1000) / 1000,
+						Verdict:  verdict(score, baseline),
+						Status:   candidate.Status,
+						Title:    candidate.Title,
+						Baseline: baseline.Title,
+					}
+
+					mu.Lock()
+					checks = append(checks, check)
+					mu.Unlock()
+				})
+				if !dispatched {
+					break hosts
+				}
+			}
+		}
+	}
+	group.Wait()
+
+	sort.Slice(checks, func(i, j int) bool {
+		if checks[i].FQDN != checks[j].FQDN {
+			return checks[i].FQDN < checks[j].FQDN
+		}
+		if checks[i].Score != checks[j].Score {
+			return checks[i].Score > checks[j].Score
+		}
+		return checks[i].IP < checks[j].IP
+	})
+
+	counts := make(map[string]int, 3)
+	for _, check := range checks {
+		counts[check.Verdict]++
+		if check.Verdict == domain.OriginConfirmed {
+			s.logger.Warn().
+				Str("fqdn", check.FQDN).
+				Str("ip", check.IP).
+				Float64("score", check.Score).
+				Msg("Origen confirmado")
+		}
+	}
+	s.logger.Info().
+		Int("checks", len(checks)).
+		Int(domain.OriginConfirmed, counts[domain.OriginConfirmed]).
+		Int(domain.OriginLikely, counts[domain.OriginLikely]).
+		Int(domain.OriginUnrelated, counts[domain.OriginUnrelated]).
+		Msg("Verificación de orígenes completada")
+
+	return checks
+}
+
+// baseline obtiene el sitio servido por el CDN probando sus IPs en orden
+func (s *Scanner) baseline(ctx context.Context, fqdn string, front []string) (domain.PageSnapshot, string, bool) {
+	for _, ip := range front {
+		snapshot, err := s.originVerifier.Snapshot(ctx, ip, 0, fqdn)
+		if err == nil {
+			return snapshot, ip, true
+		}
+		s.logger.Debug().Err(err).Str("fqdn", fqdn).Str("ip", ip).Msg("Error obteniendo sitio a través del CDN")
+	}
+	s.logger.Warn().Str("fqdn", fqdn).Msg("No se pudo obtener el sitio a través del CDN; host sin verificar")
+	return domain.PageSnapshot{}, "", false
+}
+
+// verdict clasifica el puntaje. Una referencia de error o vacía (ej: página de
+// bloqueo del WAF) se parece a cualquier error genérico, así que como mucho
+// resulta likely.
+func verdict(score float64, baseline domain.PageSnapshot) string {
+	conclusive := baseline.Status < 400 && baseline.Length > 0
+	switch {
+	case score >= confirmedScore && conclusive:
+		return domain.OriginConfirmed
+	case score >= likelyScore:
+		return domain.OriginLikely
+	default:
+		return domain.OriginUnrelated
+	}
+}
+
+// originCandidates retorna las IPs expuestas (sin CDN/WAF) y las de las
+// pistas DNS, ordenadas y limitadas a limit si es positivo
+func originCandidates(answers map[string][]domain.ResultEntry, leads []domain.OriginLead, limit int) []string {
+	seen := make(map[string]bool)
+	var candidates []string
+	for _, entries := range answers {
+		for _, entry := range entries {
+			if entry.IP != "" && entry.Provider == "" && !seen[entry.IP] {
+				seen[entry.IP] = true
+				candidates = append(candidates, entry.IP)
+			}
+		}
+	}
+	for _, lead := range leads {
+		for _, ip := range append([]string{lead.IP}, lead.IPs...) {
+			if ip != "" && !seen[ip] {
+				seen[ip] = true
+				candidates = append(candidates, ip)
+			}
+		}
+	}
+	sort.Strings(candidates)
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates
+}
+
+// frontedHosts retorna, por host, las IPs de CDN/WAF por las que responde
+func frontedHosts(answers map[string][]domain.ResultEntry) map[string][]string {
+	fronted := make(map[string][]string)
+	for fqdn, entries := range answers {
+		for _, entry := range entries {
+			if entry.IP != "" && entry.Provider != "" {
+				fronted[fqdn] = append(fronted[fqdn], entry.IP)
+			}
+		}
+	}
+	return fronted
+}
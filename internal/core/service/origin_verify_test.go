@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+func TestVerdict(t *testing.T) {
+	site := domain.PageSnapshot{Status: 200, Title: "Shop", Length: 1200}
+	waf := domain.PageSnapshot{Status: 403, Title: "Attention Required!", Length: 800}
+	empty := domain.PageSnapshot{Status: 204}
+
+	tests := []struct {
+		score    float64
+		baseline domain.PageSnapshot
+		want     string
+	}{
+		{0.95, site, domain.OriginConfirmed},
+		{confirmedScore, site, domain.OriginConfirmed},
+		{0.84, site, domain.OriginLikely},
+		{likelyScore, site, domain.OriginLikely},
+		{0.59, site, domain.OriginUnrelated},
+		// Una referencia de error o vacía no confirma nunca
+		{1, waf, domain.OriginLikely},
+		{1, empty, domain.OriginLikely},
+		{0.3, waf, domain.OriginUnrelated},
+	}
+	for _, tt := range tests {
+		if got := verdict(tt.score, tt.baseline); got != tt.want {
+			t.Errorf("verdict(%.2f, %d) = %s, esperado %s", tt.score, tt.baseline.Status, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyOrigins(t *testing.T) {
+	verifier := &fakeVerifier{
+		snapshots: map[string]domain.PageSnapshot{
+			// Referencias a través del CDN
+			"104.16.1.1": {Status: 200, Title: "Shop", Length: 1200},
+			"104.16.2.2": {Status: 403, Title: "Attention Required!", Length: 800},
+			// Candidatos
+			"203.0.113.1":      {Status: 200, Title: "Shop", Length: 1190},
+			"203.0.113.2":      {Status: 200, Title: "Shop staging", Length: 900},
+			"203.0.113.3":      {Status: 200, Title: "Welcome to nginx!", Length: 600},
+			"203.0.113.5:8443": {Status: 200, Title: "Shop", Length: 1190},
+		},
+		scores: map[string]float64{"Shop": 0.95, "Shop staging": 0.7, "Welcome to nginx!": 0.2},
+	}
+	scanner := newTestScanner(&fakeResolver{}, nil)
+	scanner.originVerifier = verifier
+
+	answers := map[string][]domain.ResultEntry{
+		"www.example.com": {{IP: "104.16.1.1", Provider: domain.ProviderCloudflare}},
+		// Detrás de una página de bloqueo del WAF
+		"blocked.example.com": {{IP: "104.16.2.2", Provider: domain.ProviderCloudflare}},
+	}
+	candidates := []string{"203.0.113.1", "203.0.113.2", "203.0.113.3", "203.0.113.4", "203.0.113.5"}
+	open := map[string][]int{
+		"203.0.113.1": {443},
+		"203.0.113.2": {80},
+		"203.0.113.3": {443},
+		"203.0.113.4": {443},
+		"203.0.113.5": {22, 8443},
+	}
+	checks := scanner.verifyOrigins(context.Background(), domain.ScannerConfig{Threads: 3}, answers, candidates, open)
+
+	type key struct {
+		fqdn, ip string
+		port     int
+	}
+	got := make(map[key]string, len(checks))
+	for _, check := range checks {
+		got[key{check.FQDN, check.IP, check.Port}] = check.Verdict
+		if check.Via == "" || check.Baseline == "" {
+			t.Errorf("%s %s: sin referencia: %+v", check.FQDN, check.IP, check)
+		}
+	}
+	want := map[key]string{
+		{"www.example.com", "203.0.113.1", 0}:    domain.OriginConfirmed,
+		{"www.example.com", "203.0.113.2", 0}:    domain.OriginLikely,
+		{"www.example.com", "203.0.113.3", 0}:    domain.OriginUnrelated,
+		{"www.example.com", "203.0.113.5", 8443}: domain.OriginConfirmed,
+		// Mismo candidato, pero la referencia es la página del WAF
+		{"blocked.example.com", "203.0.113.1", 0}:    domain.OriginLikely,
+		{"blocked.example.com", "203.0.113.2", 0}:    domain.OriginLikely,
+		{"blocked.example.com", "203.0.113.3", 0}:    domain.OriginUnrelated,
+		{"blocked.example.com", "203.0.113.5", 8443}: domain.OriginLikely,
+	}
+	if len(got) != len(want) {
+		t.Errorf("checks = %v, esperado %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s %s:%d = %q, esperado %q", k.fqdn, k.ip, k.port, got[k], v)
+		}
+	}
+	// Ordenados por host y puntaje
+	if checks[0].FQDN != "blocked.example.com" || checks[len(checks)-1].Verdict != domain.OriginUnrelated {
+		t.Errorf("orden = %+v", checks)
+	}
+}
+
+func TestVerifyOriginsCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	verifier := &fakeVerifier{
+		snapshots: map[string]domain.PageSnapshot{"104.16.1.1": {Status: 200, Title: "Shop", Length: 1200}},
+		// Se cancela durante la primera consulta a un candidato
+		onSnapshot: func(ip string, _ int, _ string) {
+			if ip != "104.16.1.1" {
+				cancel()
+			}
+		},
+	}
+	scanner := newTestScanner(&fakeResolver{}, nil)
+	scanner.originVerifier = verifier
+
+	answers := map[string][]domain.ResultEntry{"www.example.com": {{IP: "104.16.1.1", Provider: domain.ProviderCloudflare}}}
+	candidates := []string{"203.0.113.1", "203.0.113.2", "203.0.113.3", "203.0.113.4"}
+	scanner.verifyOrigins(ctx, domain.ScannerConfig{Threads: 1}, answers, candidates, nil)
+
+	// La referencia y un solo candidato: el resto no se despacha
+	if verifier.calls != 2 {
+		t.Errorf("%d consultas tras cancelar, esperado 2", verifier.calls)
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// applyPolicy resume cada host con todas sus respuestas y retorna solo las
+// que la política de salida conserva: las IPs expuestas siempre, y las de
+// proveedores CDN/WAF solo si están incluidas (-include-providers, -include-cf)
+func applyPolicy(answers map[string][]domain.ResultEntry, config domain.ScannerConfig) (map[string][]domain.ResultEntry, []domain.HostSummary) {
+	included := includedProviders(config)
+
+	results := make(map[string][]domain.ResultEntry, len(answers))
+	hosts := make([]domain.HostSummary, 0, len(answers))
+
+	for fqdn, entries := range answers {
+		hosts = append(hosts, summarizeHost(fqdn, entries))
+
+		for _, entry := range entries {
+			if includes(included, entry.Provider) {
+				results[fqdn] = append(results[fqdn], entry)
+			}
+		}
+	}
+
+	sort.Slice(hosts, func(i, j int) bool {
+		return hosts[i].FQDN < hosts[j].FQDN
+	})
+	return results, hosts
+}
+
+// summarizeHost clasifica un host como proxied, mixed o exposed
+func summarizeHost(fqdn string, entries []domain.ResultEntry) domain.HostSummary {
+	summary := domain.HostSummary{FQDN: fqdn, Total: len(entries)}
+
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.Product != "" && !seen["product:"+entry.Product] {
+			seen["product:"+entry.Product] = true
+			summary.Products = append(summary.Products, entry.Product)
+		}
+		for _, web := range entry.Web {
+			for _, tech := range web.Tech {
+				if label := tech.String(); !seen["tech:"+label] {
+					seen["tech:"+label] = true
+					summary.Tech = append(summary.Tech, label)
+				}
+			}
+		}
+		if !entry.Proxied {
+			continue
+		}
+		summary.Proxied++
+		if !seen[entry.Provider] {
+			seen[entry.Provider] = true
+			summary.Providers = append(summary.Providers, entry.Provider)
+		}
+	}
+	sort.Strings(summary.Providers)
+	sort.Strings(summary.Products)
+	sort.Strings(summary.Tech)
+
+	switch summary.Proxied {
+	case summary.Total:
+		summary.Status = domain.HostProxied
+	case 0:
+		summary.Status = domain.HostExposed
+	default:
+		summary.Status = domain.HostMixed
+	}
+
+	// Un tunnel conecta el origen hacia Cloudflare: no hay IP que buscar
+	if seen["product:"+domain.CFProductTunnel] {
+		if summary.Status == domain.HostProxied {
+			summary.Note = "cloudflare tunnel: el origen no expone IP (conexión saliente hacia Cloudflare)"
+		} else {
+			summary.Note = "cloudflare tunnel con IPs expuestas además del tunnel"
+		}
+	}
+	return summary
+}
+
+// includes indica si se conservan las IPs del proveedor; las IPs sin
+// proveedor siempre se conservan
+func includes(included map[string]bool, provider string) bool {
+	return provider == "" || included[domain.IncludeAllProviders] || included[provider]
+}
+
+// includedProviders arma el conjunto de proveedores incluidos; -include-cf
+// equivale a incluir cloudflare
+func includedProviders(config domain.ScannerConfig) map[string]bool {
+	included := make(map[string]bool, len(config.IncludeProviders)+1)
+	for _, name := range config.IncludeProviders {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			included[name] = true
+		}
+	}
+	if config.IncludeCF {
+		included[domain.ProviderCloudflare] = true
+	}
+	return included
+}
+
+// countHosts cuenta los hosts por estado
+func countHosts(hosts []domain.HostSummary) map[string]int {
+	counts := make(map[string]int, 3)
+	for _, host := range hosts {
+		counts[host.Status]++
+	}
+	return counts
+}
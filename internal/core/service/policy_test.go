@@ -0,0 +1,128 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+func cf(fqdn, ip string) domain.ResultEntry {
+	return domain.ResultEntry{FQDN: fqdn, IP: ip, Type: "A", Provider: domain.ProviderCloudflare, Proxied: true}
+}
+
+func exposed(fqdn, ip string) domain.ResultEntry {
+	return domain.ResultEntry{FQDN: fqdn, IP: ip, Type: "A"}
+}
+
+func TestSummarizeHost(t *testing.T) {
+	tunnel := cf("tunnel.example.com", "104.16.1.1")
+	tunnel.Product = domain.CFProductTunnel
+	fastly := domain.ResultEntry{FQDN: "mixed.example.com", IP: "151.101.1.1", Provider: "fastly", Proxied: true}
+	withTech := exposed("app.example.com", "192.0.2.20")
+	withTech.Web = []domain.WebProbe{
+		{Tech: []domain.Technology{{Name: "nginx", Version: "1.25"}, {Name: "PHP"}}},
+		{Tech: []domain.Technology{{Name: "nginx", Version: "1.25"}}},
+	}
+
+	tests := []struct {
+		name      string
+		entries   []domain.ResultEntry
+		status    string
+		proxied   int
+		providers []string
+		products  []string
+		tech      []string
+		note      bool
+	}{
+		{"proxied", []domain.ResultEntry{cf("www.example.com", "104.16.1.1"), cf("www.example.com", "104.16.1.2")}, domain.HostProxied, 2, []string{"cloudflare"}, nil, nil, false},
+		{"mixed", []domain.ResultEntry{cf("mixed.example.com", "104.16.1.1"), fastly, exposed("mixed.example.com", "192.0.2.10")}, domain.HostMixed, 2, []string{"cloudflare", "fastly"}, nil, nil, false},
+		{"exposed", []domain.ResultEntry{withTech}, domain.HostExposed, 0, nil, nil, []string{"PHP", "nginx/1.25"}, false},
+		{"tunnel", []domain.ResultEntry{tunnel}, domain.HostProxied, 1, []string{"cloudflare"}, []string{domain.CFProductTunnel}, nil, true},
+		{"tunnel con IPs expuestas", []domain.ResultEntry{tunnel, exposed("tunnel.example.com", "192.0.2.30")}, domain.HostMixed, 1, []string{"cloudflare"}, []string{domain.CFProductTunnel}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := summarizeHost(tt.entries[0].FQDN, tt.entries)
+			if summary.Status != tt.status || summary.Total != len(tt.entries) || summary.Proxied != tt.proxied {
+				t.Errorf("resumen = %s %d/%d, esperado %s %d/%d", summary.Status, summary.Proxied, summary.Total, tt.status, tt.proxied, len(tt.entries))
+			}
+			if !slices.Equal(summary.Providers, tt.providers) || !slices.Equal(summary.Products, tt.products) || !slices.Equal(summary.Tech, tt.tech) {
+				t.Errorf("providers/products/tech = %v %v %v", summary.Providers, summary.Products, summary.Tech)
+			}
+			if (summary.Note != "") != tt.note {
+				t.Errorf("note = %q", summary.Note)
+			}
+		})
+	}
+}
+
+func TestIncludedProviders(t *testing.T) {
+	tests := []struct {
+		name   string
+		config domain.ScannerConfig
+		want   []string
+	}{
+		{"ninguno", domain.ScannerConfig{}, nil},
+		{"include-cf", domain.ScannerConfig{IncludeCF: true}, []string{"cloudflare"}},
+		{"normaliza nombres", domain.ScannerConfig{IncludeProviders: []string{" Fastly ", "", "AKAMAI"}}, []string{"akamai", "fastly"}},
+		{"all", domain.ScannerConfig{IncludeProviders: []string{"all"}, IncludeCF: true}, []string{"all", "cloudflare"}},
+	}
+	for _, tt := range tests {
+		included := includedProviders(tt.config)
+		var got []string
+		for name := range included {
+			got = append(got, name)
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: includedProviders = %v, esperado %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestApplyPolicy(t *testing.T) {
+	fastly := domain.ResultEntry{FQDN: "mixed.example.com", IP: "151.101.1.1", Provider: "fastly", Proxied: true}
+	answers := map[string][]domain.ResultEntry{
+		"www.example.com":   {cf("www.example.com", "104.16.1.1")},
+		"mixed.example.com": {cf("mixed.example.com", "104.16.1.2"), fastly, exposed("mixed.example.com", "192.0.2.10")},
+		"api.example.com":   {exposed("api.example.com", "192.0.2.20")},
+	}
+
+	tests := []struct {
+		name   string
+		config domain.ScannerConfig
+		want   map[string]int // resultados conservados por host
+	}{
+		{"solo expuestas", domain.ScannerConfig{}, map[string]int{"mixed.example.com": 1, "api.example.com": 1}},
+		{"include-cf", domain.ScannerConfig{IncludeCF: true}, map[string]int{"www.example.com": 1, "mixed.example.com": 2, "api.example.com": 1}},
+		{"include-providers", domain.ScannerConfig{IncludeProviders: []string{"fastly"}}, map[string]int{"mixed.example.com": 2, "api.example.com": 1}},
+		{"all", domain.ScannerConfig{IncludeProviders: []string{"all"}}, map[string]int{"www.example.com": 1, "mixed.example.com": 3, "api.example.com": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, hosts := applyPolicy(answers, tt.config)
+			if len(results) != len(tt.want) {
+				t.Errorf("hosts con resultados = %d, esperado %d", len(results), len(tt.want))
+			}
+			for fqdn, n := range tt.want {
+				if len(results[fqdn]) != n {
+					t.Errorf("%s: %d resultados, esperado %d", fqdn, len(results[fqdn]), n)
+				}
+			}
+
+			// El resumen cubre todos los hosts, sin importar la política
+			var names, statuses []string
+			for _, host := range hosts {
+				names = append(names, host.FQDN)
+				statuses = append(statuses, host.Status)
+			}
+			if want := []string{"api.example.com", "mixed.example.com", "www.example.com"}; !slices.Equal(names, want) {
+				t.Errorf("hosts = %v, esperado %v", names, want)
+			}
+			if want := []string{domain.HostExposed, domain.HostMixed, domain.HostProxied}; !slices.Equal(statuses, want) {
+				t.Errorf("estados = %v, esperado %v", statuses, want)
+			}
+		})
+	}
+}
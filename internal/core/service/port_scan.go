@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// Puertos donde se espera HTTP sin TLS; el resto de los abiertos se prueba
+// con TLS
+var plainHTTPPorts = map[int]bool{80: true, 2052: true, 2082: true, 2086: true, 2095: true, 8000: true, 8008: true, 8080: true, 8880: true}
+
+// scanPorts busca puertos TCP abiertos en las IPs candidatas y los agrega a
+// sus respuestas. Los orígenes suelen quedar expuestos en puertos no
+// estándar aunque 443 solo acepte tráfico del CDN.
+func (s *Scanner) scanPorts(ctx context.Context, config domain.ScannerConfig, answers map[string][]domain.ResultEntry, candidates []string) []domain.PortScan {
+	s.logger.Info().Int("candidates", len(candidates)).Msg("Escaneando puertos de IPs candidatas")
+
+	var (
+		mu    sync.Mutex
+		group = newBoundedGroup(ctx, config.Threads)
+		scans []domain.PortScan
+	)
+
+	for _, ip := range candidates {
+		dispatched := group.Go(func() {
+			open, err := s.portScanner.OpenPorts(ctx, ip)
+			if err != nil {
+				s.logger.Debug().Err(err).Str("ip", ip).Msg("Error escaneando puertos")
+				return
+			}
+			mu.Lock()
+			scans = append(scans, domain.PortScan{IP: ip, Open: open})
+			mu.Unlock()
+		})
+		if !dispatched {
+			break
+		}
+	}
+	group.Wait()
+
+	sort.Slice(scans, func(i, j int) bool {
+		return scans[i].IP < scans[j].IP
+	})
+
+	withOpen := 0
+	for _, scan := range scans {
+		if len(scan.Open) == 0 {
+			continue
+		}
+		withOpen++
+		for _, entries := range answers {
+			for i := range entries {
+				if entries[i].IP == scan.IP {
+					entries[i].Ports = scan.Open
+				}
+			}
+		}
+		s.logger.Info().Str("ip", scan.IP).Ints("ports", scan.Open).Msg("Puertos abiertos")
+	}
+	s.logger.Info().
+		Int("scanned", len(scans)).
+		Int("with_open_ports", withOpen).
+		Msg("Escaneo de puertos completado")
+
+	return scans
+}
+
+// openPorts indexa los puertos abiertos por IP; nil si no hubo escaneo
+func openPorts(scans []domain.PortScan) map[string][]int {
+	if scans == nil {
+		return nil
+	}
+	open := make(map[string][]int, len(scans))
+	for _, scan := range scans {
+		open[scan.IP] = scan.Open
+	}
+	return open
+}
+
+// httpPorts retorna los puertos a consultar por HTTP en ip: 0 (los estándar)
+// sin escaneo de puertos o si 80/443 están abiertos, más los demás puertos
+// abiertos
+func httpPorts(open map[string][]int, ip string) []int {
+	if open == nil {
+		return []int{0}
+	}
+	var ports []int
+	for _, port := range open[ip] {
+		switch {
+		case port == 80 || port == 443:
+			if !slices.Contains(ports, 0) {
+				ports = append([]int{0}, ports...)
+			}
+		default:
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// tlsPorts retorna los puertos a consultar por TLS en ip: los configurados
+// sin escaneo de puertos, o los abiertos que no son HTTP plano
+func tlsPorts(open map[string][]int, ip string, configured []int) []int {
+	if open == nil {
+		return configured
+	}
+	var ports []int
+	for _, port := range open[ip] {
+		if !plainHTTPPorts[port] {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
@@ -13,26 +13,96 @@ import (
 type Scanner struct {
 	dnsResolver       ports.DNSResolver
 	cloudflareService ports.CloudflareService
+	providerRegistry  ports.ProviderRegistry
+	cloudClassifier   ports.CloudClassifier
 	fileRepo          ports.FileRepository
 	progressReporter  ports.ProgressReporter
 	metricsCollector  ports.MetricsCollector
 	healthChecker     ports.HealthChecker
+	httpProber        ports.HTTPProber
+	cloudflareAccount ports.CloudflareAccount
+	originVerifier    ports.OriginVerifier
+	tlsProber         ports.TLSProber
+	faviconFetcher    ports.FaviconFetcher
+	portScanner       ports.PortScanner
+	webProber         ports.WebProber
 	logger            zerolog.Logger
 	startTime         time.Time
 }
 
+// ScannerOption habilita etapas opcionales del escaneo
+type ScannerOption func(*Scanner)
+
+// WithHTTPProber habilita la confirmación por cabeceras HTTP del proveedor
+// de cada IP
+func WithHTTPProber(prober ports.HTTPProber) ScannerOption {
+	return func(s *Scanner) {
+		s.httpProber = prober
+	}
+}
+
+// WithCloudflareAccount habilita la auditoría del inventario DNS de la cuenta
+// Cloudflare contra lo encontrado por el escaneo
+func WithCloudflareAccount(account ports.CloudflareAccount) ScannerOption {
+	return func(s *Scanner) {
+		s.cloudflareAccount = account
+	}
+}
+
+// WithOriginVerifier habilita la verificación de orígenes candidatos por
+// similitud con el sitio servido por el CDN
+func WithOriginVerifier(verifier ports.OriginVerifier) ScannerOption {
+	return func(s *Scanner) {
+		s.originVerifier = verifier
+	}
+}
+
+// WithTLSProber habilita la obtención de certificados TLS de las IPs candidatas
+func WithTLSProber(prober ports.TLSProber) ScannerOption {
+	return func(s *Scanner) {
+		s.tlsProber = prober
+	}
+}
+
+// WithFaviconFetcher habilita la comparación de favicons entre el sitio y las
+// IPs candidatas
+func WithFaviconFetcher(fetcher ports.FaviconFetcher) ScannerOption {
+	return func(s *Scanner) {
+		s.faviconFetcher = fetcher
+	}
+}
+
+// WithWebProber habilita el enriquecimiento HTTP de cada host resuelto
+func WithWebProber(prober ports.WebProber) ScannerOption {
+	return func(s *Scanner) {
+		s.webProber = prober
+	}
+}
+
+// WithPortScanner habilita el escaneo de puertos TCP de las IPs candidatas
+func WithPortScanner(scanner ports.PortScanner) ScannerOption {
+	return func(s *Scanner) {
+		s.portScanner = scanner
+	}
+}
+
 func NewScanner(
 	dnsResolver ports.DNSResolver,
 	cloudflareService ports.CloudflareService,
+	providerRegistry ports.ProviderRegistry,
+	cloudClassifier ports.CloudClassifier,
 	fileRepo ports.FileRepository,
 	progressReporter ports.ProgressReporter,
 	metricsCollector ports.MetricsCollector,
 	healthChecker ports.HealthChecker,
 	logger zerolog.Logger,
+	opts ...ScannerOption,
 ) *Scanner {
-	return &Scanner{
+	s := &Scanner{
 		dnsResolver:       dnsResolver,
 		cloudflareService: cloudflareService,
+		providerRegistry:  providerRegistry,
+		cloudClassifier:   cloudClassifier,
 		fileRepo:          fileRepo,
 		progressReporter:  progressReporter,
 		metricsCollector:  metricsCollector,
@@ -40,6 +110,10 @@ func NewScanner(
 		logger:            logger,
 		startTime:         time.Now(),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *Scanner) Scan(ctx context.Context, config domain.ScannerConfig) (*domain.ScanResult, error) {
@@ -64,13 +138,42 @@ func (s *Scanner) Scan(ctx context.Context, config domain.ScannerConfig) (*domai
 
 	s.logger.Info().Int("subdomains", len(subdomains)).Msg("Wordlist cargada")
 
+	// Validar token y zona antes de escanear: la auditoría corre al final
+	if s.cloudflareAccount != nil && config.CFAudit {
+		zone, err := s.cloudflareAccount.Zone(ctx, config.Domain)
+		if err != nil {
+			s.logger.Error().Err(err).Msg("Error validando cuenta Cloudflare")
+			return nil, fmt.Errorf("error validando cuenta Cloudflare: %w", err)
+		}
+		s.logger.Info().Str("zone", zone).Msg("Cuenta Cloudflare validada")
+	}
+
 	// Obtener rangos de Cloudflare
 	ranges, err := s.cloudflareService.GetRanges(ctx, config.NoFetchCF)
 	if err != nil {
+		if config.RangesFile != "" {
+			return nil, fmt.Errorf("error cargando rangos Cloudflare: %w", err)
+		}
 		s.logger.Warn().Err(err).Msg("Error obteniendo rangos Cloudflare, usando defaults")
 		// El servicio Cloudflare ya maneja los defaults internamente
 	}
 
+	rangesLog := s.logger.Info().
+		Str("provider", ranges.Source.Provider).
+		Str("source", ranges.Source.Source).
+		Str("version", ranges.Source.Version).
+		Int("ipv4_ranges", len(ranges.IPv4)).
+		Int("ipv6_ranges", len(ranges.IPv6))
+	if !ranges.Source.FetchedAt.IsZero() {
+		rangesLog = rangesLog.Time("fetched_at", ranges.Source.FetchedAt)
+	}
+	rangesLog.Msg("Rangos Cloudflare")
+
+	// Los rangos obtenidos reemplazan a los incluidos en el registro
+	if len(ranges.IPv4) > 0 || len(ranges.IPv6) > 0 {
+		s.providerRegistry.SetRanges(domain.ProviderCloudflare, ranges)
+	}
+
 	// Configurar progreso
 	if s.progressReporter != nil {
 		s.progressReporter.Start(len(subdomains))
@@ -78,25 +181,128 @@ func (s *Scanner) Scan(ctx context.Context, config domain.ScannerConfig) (*domai
 	}
 
 	// Ejecutar workers
-	results := s.startWorkers(ctx, config, subdomains, ranges)
+	answers := s.startWorkers(ctx, config, subdomains)
+
+	// Confirmar el proveedor por cabeceras HTTP
+	if s.httpProber != nil && config.HTTPProbe {
+		s.probeHTTP(ctx, config, answers)
+	}
+
+	// Visitar cada host por HTTPS y HTTP; la detección de tecnologías usa las
+	// mismas visitas
+	if s.webProber != nil && (config.Enrich || config.Tech) {
+		s.enrichHosts(ctx, config, answers)
+	}
+
+	// Buscar IPs de origen en SPF y MX
+	var leads []domain.OriginLead
+	if config.SPF {
+		leads = s.mineLeads(ctx, config, answers)
+	}
+	candidates := originCandidates(answers, leads, config.MaxCandidates)
+
+	// Buscar puertos abiertos; guían las consultas HTTP y TLS a los candidatos
+	var portScans []domain.PortScan
+	if s.portScanner != nil && config.PortScan {
+		portScans = s.scanPorts(ctx, config, answers, candidates)
+	}
+	open := openPorts(portScans)
+
+	// Verificar orígenes candidatos contra el sitio servido por el CDN
+	var origins []domain.OriginCheck
+	if s.originVerifier != nil && config.VerifyOrigins {
+		origins = s.verifyOrigins(ctx, config, answers, candidates, open)
+	}
+
+	// Obtener certificados TLS de las IPs candidatas
+	var certs []domain.CertCheck
+	if s.tlsProber != nil && config.TLSProbe {
+		certs = s.harvestCertificates(ctx, config, answers, candidates, open)
+	}
+
+	// Barrer el bloque de cada origen confirmado buscando vecinos con el
+	// certificado del dominio
+	var sweep []domain.SweepHit
+	if s.tlsProber != nil && config.Sweep {
+		sweep = s.sweepNeighbours(ctx, config, answers, origins, certs)
+	}
+
+	// Buscar virtual hosts en los orígenes confirmados
+	var vhosts []domain.VHostCheck
+	if s.originVerifier != nil && config.VHosts {
+		vhosts = s.discoverVHosts(ctx, config, answers, subdomains, origins, certs, sweep)
+	}
+
+	// Buscar candidatos que sirven el favicon del sitio
+	var favicons []domain.FaviconCheck
+	if s.faviconFetcher != nil && config.Favicons {
+		favicons = s.matchFavicons(ctx, config, answers, candidates)
+	}
+
+	// Combinar las señales en un puntaje de origen por host
+	var scores []domain.OriginScore
+	if config.Score {
+		scores = s.scoreOrigins(ctx, config, scoreInputs{
+			answers:  answers,
+			leads:    leads,
+			origins:  origins,
+			certs:    certs,
+			favicons: favicons,
+			evaluated: map[string]bool{
+				domain.SignalHTTP:    s.originVerifier != nil && config.VerifyOrigins,
+				domain.SignalTLS:     s.tlsProber != nil && config.TLSProbe,
+				domain.SignalFavicon: s.faviconFetcher != nil && config.Favicons,
+				domain.SignalSPF:     config.SPF,
+			},
+		}, candidates)
+	}
+
+	// Comparar con el inventario de la cuenta Cloudflare
+	var audit *domain.AccountAudit
+	if s.cloudflareAccount != nil && config.CFAudit {
+		audit = s.runAudit(ctx, config, answers)
+	}
+
+	// Aplicar la política de salida sobre las respuestas clasificadas
+	results, hosts := applyPolicy(answers, config)
+	counts := countHosts(hosts)
+	s.logger.Info().
+		Int("hosts", len(hosts)).
+		Int(domain.HostProxied, counts[domain.HostProxied]).
+		Int(domain.HostMixed, counts[domain.HostMixed]).
+		Int(domain.HostExposed, counts[domain.HostExposed]).
+		Msg("Exposición de hosts")
 
 	duration := time.Since(startTime)
 
+	scanResult := &domain.ScanResult{
+		Domain:     config.Domain,
+		TotalFound: len(results),
+		Duration:   duration,
+		Ranges:     ranges.Source,
+		Hosts:      hosts,
+		Results:    results,
+		Answers:    answers,
+		Audit:      audit,
+		Origins:    origins,
+		Certs:      certs,
+		Favicons:   favicons,
+		Leads:      leads,
+		Candidates: scores,
+		Sweep:      sweep,
+		Ports:      portScans,
+		VHosts:     vhosts,
+	}
+
 	// Guardar resultados si es necesario
 	if config.Output != "" {
-		if err := s.fileRepo.SaveResults(results, config); err != nil {
+		if err := s.fileRepo.SaveResults(scanResult, config); err != nil {
 			s.logger.Error().Err(err).Str("output", config.Output).Msg("Error guardando resultados")
 			return nil, fmt.Errorf("error guardando resultados: %w", err)
 		}
 		s.logger.Info().Str("output", config.Output).Msg("Resultados guardados")
 	}
 
-	scanResult := &domain.ScanResult{
-		TotalFound: len(results),
-		Duration:   duration,
-		Results:    results,
-	}
-
 	s.logger.Info().
 		Int("found", scanResult.TotalFound).
 		Dur("duration", scanResult.Duration).
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// scoreInputs reúne lo obtenido por las etapas del escaneo. evaluated indica
+// las señales cuya etapa se ejecutó: las demás no cuentan en el promedio, así
+// que no haber corrido -tls-probe no castiga a ningún candidato.
+type scoreInputs struct {
+	answers   map[string][]domain.ResultEntry
+	leads     []domain.OriginLead
+	origins   []domain.OriginCheck
+	certs     []domain.CertCheck
+	favicons  []domain.FaviconCheck
+	evaluated map[string]bool
+}
+
+// scoreOrigins combina las señales de cada IP candidata en un puntaje por
+// host y retorna los mejores config.TopCandidates de cada host
+func (s *Scanner) scoreOrigins(ctx context.Context, config domain.ScannerConfig, in scoreInputs, candidates []string) []domain.OriginScore {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	target := strings.ToLower(config.Domain)
+	var hosts []string
+	for fqdn := range frontedHosts(in.answers) {
+		hosts = append(hosts, fqdn)
+	}
+	if len(hosts) == 0 {
+		hosts = append(hosts, target)
+	}
+	sort.Strings(hosts)
+
+	in.evaluated[domain.SignalDNS] = true
+	in.evaluated[domain.SignalPTR] = true
+	in.evaluated[domain.SignalHosting] = s.cloudClassifier != nil
+	ptrs := s.lookupPTRs(ctx, config, candidates)
+
+	weights := scoreWeights(config.ScoreWeights)
+	total := 0.0
+	for name, weight := range weights {
+		if in.evaluated[name] {
+			total += weight
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+
+	names := sortedSignals(weights)
+	var scores []domain.OriginScore
+	for _, host := range hosts {
+		var ranked []domain.OriginScore
+		for _, ip := range candidates {
+			score := domain.OriginScore{FQDN: host, IP: ip}
+			sum := 0.0
+			for _, name := range names {
+				if !in.evaluated[name] || weights[name] == 0 {
+					continue
+				}
+				value, detail := s.signal(in, ptrs[ip], name, host, ip, target)
+				if value <= 0 {
+					continue
+				}
+				sum += value * weights[name]
+				score.Signals = append(score.Signals, domain.ScoreSignal{
+					Name:   name,
+					Value:  math.Round(value*1000) / 1000,
+					Weight: weights[name],
+					Detail: detail,
+				})
+			}
+			if sum == 0 {
+				continue
+			}
+			score.Score = math.Round(sum/total*1000) / 1000
+			ranked = append(ranked, score)
+		}
+
+		sort.Slice(ranked, func(i, j int) bool {
+			if ranked[i].Score != ranked[j].Score {
+				return ranked[i].Score > ranked[j].Score
+			}
+			return ranked[i].IP < ranked[j].IP
+		})
+		if config.TopCandidates > 0 && len(ranked) > config.TopCandidates {
+			ranked = ranked[:config.TopCandidates]
+		}
+		if len(ranked) > 0 {
+			s.logger.Info().
+				Str("fqdn", host).
+				Str("ip", ranked[0].IP).
+				Float64("score", ranked[0].Score).
+				Int("candidates", len(ranked)).
+				Msg("Mejor candidato a origen")
+		}
+		scores = append(scores, ranked...)
+	}
+	return scores
+}
+
+// signal evalúa una señal para el par host/IP
+func (s *Scanner) signal(in scoreInputs, ptrs []string, name, host, ip, target string) (float64, string) {
+	switch name {
+	case domain.SignalHTTP:
+		return httpSignal(in.origins, host, ip)
+	case domain.SignalTLS:
+		return tlsSignal(in.certs, host, ip)
+	case domain.SignalFavicon:
+		return faviconSignal(in.favicons, host, ip)
+	case domain.SignalSPF:
+		return spfSignal(in.leads, ip)
+	case domain.SignalPTR:
+		return ptrSignal(ptrs, host, target)
+	case domain.SignalDNS:
+		return dnsSignal(in.answers, host, ip)
+	case domain.SignalHosting:
+		return s.hostingSignal(in.answers, ip)
+	}
+	return 0, ""
+}
+
+// scoreWeights aplica los pesos configurados sobre los por defecto
+func scoreWeights(custom map[string]float64) map[string]float64 {
+	weights := domain.DefaultScoreWeights()
+	for name, weight := range custom {
+		weights[strings.ToLower(name)] = weight
+	}
+	return weights
+}
+
+// sortedSignals ordena las señales por peso descendente para que la
+// explicación empiece por las más fuertes
+func sortedSignals(weights map[string]float64) []string {
+	names := make([]string, 0, len(weights))
+	for name := range weights {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if weights[names[i]] != weights[names[j]] {
+			return weights[names[i]] > weights[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// lookupPTRs resuelve el PTR de cada candidato
+func (s *Scanner) lookupPTRs(ctx context.Context, config domain.ScannerConfig, candidates []string) map[string][]string {
+	var (
+		mu    sync.Mutex
+		group = newBoundedGroup(ctx, config.Threads)
+		ptrs  = make(map[string][]string, len(candidates))
+	)
+
+	for _, ip := range candidates {
+		dispatched := group.Go(func() {
+			lookupCtx, cancel := timeoutContext(ctx, config.Timeout)
+			defer cancel()
+
+			names, err := s.dnsResolver.LookupAddr(lookupCtx, ip)
+			if err != nil {
+				s.logger.Debug().Err(err).Str("ip", ip).Msg("Candidato sin PTR")
+				return
+			}
+			mu.Lock()
+			ptrs[ip] = names
+			mu.Unlock()
+		})
+		if !dispatched {
+			break
+		}
+	}
+	group.Wait()
+	return ptrs
+}
+
+// httpSignal es la similitud de la IP con el sitio del host según el
+// veredicto: likely (incluye las comparaciones no concluyentes) se limita a
+// 0.7 y unrelated no aporta
+func httpSignal(origins []domain.OriginCheck, host, ip string) (float64, string) {
+	best, detail := 0.0, ""
+	for _, check := range origins {
+		if check.FQDN != host || check.IP != ip {
+			continue
+		}
+		value := check.Score
+		switch check.Verdict {
+		case domain.OriginLikely:
+			value = min(value, 0.7)
+		case domain.OriginUnrelated:
+			value = 0
+		}
+		if value > best {
+			best, detail = value, fmt.Sprintf("%s %.3f", check.Verdict, check.Score)
+		}
+	}
+	return best, detail
+}
+
+// tlsSignal vale más si el certificado nombra al host que si solo cubre el
+// dominio, y más si es el certificado por defecto que si requiere SNI
+func tlsSignal(certs []domain.CertCheck, host, ip string) (float64, string) {
+	best, detail := 0.0, ""
+	for _, check := range certs {
+		if check.IP != ip || check.Match == "" {
+			continue
+		}
+		value := 0.5
+		name := check.Names[0]
+		for _, candidate := range check.Names {
+			if certCovers(candidate, host) {
+				value, name = 0.8, candidate
+				break
+			}
+		}
+		if check.Match == domain.CertMatchDefault {
+			value += 0.2
+		}
+		if value > best {
+			best, detail = value, fmt.Sprintf("%s:%d %s", check.Match, check.Port, name)
+		}
+	}
+	return best, detail
+}
+
+// certCovers indica si el nombre de un certificado cubre host, incluyendo
+// wildcards de un nivel
+func certCovers(name, host string) bool {
+	if name == host {
+		return true
+	}
+	_, parent, ok := strings.Cut(host, ".")
+	return ok && strings.HasPrefix(name, "*.") && name[2:] == parent
+}
+
+// faviconSignal vale 1 si la IP sirve el favicon del host y 0.5 si sirve el
+// de otro host del dominio
+func faviconSignal(favicons []domain.FaviconCheck, host, ip string) (float64, string) {
+	best, detail := 0.0, ""
+	for _, check := range favicons {
+		for _, match := range check.Matches {
+			if match.IP != ip {
+				continue
+			}
+			if check.FQDN == host {
+				return 1, match.Favicon.Path
+			}
+			if best == 0 {
+				best, detail = 0.5, check.FQDN+match.Favicon.Path
+			}
+		}
+	}
+	return best, detail
+}
+
+// spfSignal vale 1 si la IP aparece sola en el SPF/MX y 0.7 si es parte de
+// un rango
+func spfSignal(leads []domain.OriginLead, ip string) (float64, string) {
+	for _, lead := range leads {
+		if lead.IP == ip {
+			return 1, strings.Join(lead.Chain, " > ")
+		}
+	}
+	for _, lead := range leads {
+		if slices.Contains(lead.IPs, ip) {
+			return 0.7, strings.Join(lead.Chain, " > ")
+		}
+	}
+	return 0, ""
+}
+
+// ptrSignal vale 1 si el PTR es el propio host y 0.7 si es otro nombre del
+// dominio; un PTR genérico del hosting no aporta
+func ptrSignal(names []string, host, target string) (float64, string) {
+	best, detail := 0.0, ""
+	for _, name := range names {
+		name = strings.ToLower(name)
+		switch {
+		case name == host:
+			return 1, name
+		case best == 0 && (name == target || strings.HasSuffix(name, "."+target)):
+			best, detail = 0.7, name
+		}
+	}
+	return best, detail
+}
+
+// dnsSignal vale 1 si el propio host también resuelve directo a la IP
+// (host mixed) y 0.6 si lo hace otro host del dominio
+func dnsSignal(answers map[string][]domain.ResultEntry, host, ip string) (float64, string) {
+	for _, entry := range answers[host] {
+		if entry.IP == ip {
+			return 1, host
+		}
+	}
+
+	var others []string
+	for fqdn, entries := range answers {
+		for _, entry := range entries {
+			if entry.IP == ip {
+				others = append(others, fqdn)
+				break
+			}
+		}
+	}
+	if len(others) == 0 {
+		return 0, ""
+	}
+	sort.Strings(others)
+	return 0.6, strings.Join(others, ",")
+}
+
+// hostingSignal indica que la IP pertenece a un proveedor cloud, donde suelen
+// vivir los orígenes
+func (s *Scanner) hostingSignal(answers map[string][]domain.ResultEntry, ip string) (float64, string) {
+	var info *domain.CloudInfo
+	for _, entries := range answers {
+		for _, entry := range entries {
+			if entry.IP == ip && entry.Cloud != nil {
+				info = entry.Cloud
+			}
+		}
+	}
+	if info == nil && s.cloudClassifier != nil {
+		if cloud, ok := s.cloudClassifier.ClassifyIP(ip); ok {
+			info = &cloud
+		}
+	}
+	if info == nil {
+		return 0, ""
+	}
+	if info.Region != "" {
+		return 1, info.Provider + "/" + info.Region
+	}
+	return 1, info.Provider
+}
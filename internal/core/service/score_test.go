@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+func TestCertCovers(t *testing.T) {
+	tests := []struct {
+		name, host string
+		want       bool
+	}{
+		{"www.example.com", "www.example.com", true},
+		{"*.example.com", "www.example.com", true},
+		// El wildcard cubre un solo nivel y no al apex
+		{"*.example.com", "a.www.example.com", false},
+		{"*.example.com", "example.com", false},
+		{"example.com", "www.example.com", false},
+		{"*.www.example.com", "www.example.com", false},
+		{"*.other.com", "www.example.com", false},
+	}
+	for _, tt := range tests {
+		if got := certCovers(tt.name, tt.host); got != tt.want {
+			t.Errorf("certCovers(%s, %s) = %v, esperado %v", tt.name, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestPTRSignal(t *testing.T) {
+	tests := []struct {
+		names  []string
+		value  float64
+		detail string
+	}{
+		{[]string{"WWW.example.com"}, 1, "www.example.com"},
+		// El propio host gana aunque venga después de otro nombre del dominio
+		{[]string{"mail.example.com", "www.example.com"}, 1, "www.example.com"},
+		{[]string{"mail.example.com", "origin.example.com"}, 0.7, "mail.example.com"},
+		{[]string{"example.com"}, 0.7, "example.com"},
+		// Un PTR genérico o de un dominio parecido no aporta
+		{[]string{"ec2-192-0-2-10.compute-1.amazonaws.com"}, 0, ""},
+		{[]string{"www.notexample.com"}, 0, ""},
+		{nil, 0, ""},
+	}
+	for _, tt := range tests {
+		value, detail := ptrSignal(tt.names, "www.example.com", "example.com")
+		if value != tt.value || detail != tt.detail {
+			t.Errorf("ptrSignal(%v) = %v %q, esperado %v %q", tt.names, value, detail, tt.value, tt.detail)
+		}
+	}
+}
+
+func TestDNSSignal(t *testing.T) {
+	answers := map[string][]domain.ResultEntry{
+		"www.example.com":   {cf("www.example.com", "104.16.1.1"), exposed("www.example.com", "192.0.2.10")},
+		"mail.example.com":  {exposed("mail.example.com", "192.0.2.20")},
+		"ftp.example.com":   {exposed("ftp.example.com", "192.0.2.20")},
+		"admin.example.com": {exposed("admin.example.com", "192.0.2.10")},
+	}
+	tests := []struct {
+		ip     string
+		value  float64
+		detail string
+	}{
+		// El propio host es mixed: resuelve directo a la IP
+		{"192.0.2.10", 1, "www.example.com"},
+		{"192.0.2.20", 0.6, "ftp.example.com,mail.example.com"},
+		{"192.0.2.30", 0, ""},
+	}
+	for _, tt := range tests {
+		value, detail := dnsSignal(answers, "www.example.com", tt.ip)
+		if value != tt.value || detail != tt.detail {
+			t.Errorf("dnsSignal(%s) = %v %q, esperado %v %q", tt.ip, value, detail, tt.value, tt.detail)
+		}
+	}
+}
+
+func TestEvidenceSignals(t *testing.T) {
+	host, ip := "www.example.com", "192.0.2.10"
+
+	origins := []domain.OriginCheck{
+		{FQDN: host, IP: ip, Score: 0.9, Verdict: domain.OriginLikely},
+		{FQDN: host, IP: "192.0.2.20", Score: 0.95, Verdict: domain.OriginConfirmed},
+		{FQDN: host, IP: "192.0.2.30", Score: 0.4, Verdict: domain.OriginUnrelated},
+	}
+	certs := []domain.CertCheck{
+		{IP: ip, Port: 8443, Match: domain.CertMatchSNI, Names: []string{"example.com"}},
+		{IP: ip, Port: 443, Match: domain.CertMatchDefault, Names: []string{"example.com", "*.example.com"}},
+		{IP: "192.0.2.20", Port: 443, Match: domain.CertMatchSNI, Names: []string{"api.example.com"}},
+		{IP: "192.0.2.30", Port: 443, Related: []string{"other.net"}},
+	}
+	favicons := []domain.FaviconCheck{
+		{FQDN: "shop.example.com", Matches: []domain.FaviconMatch{{IP: "192.0.2.20", Favicon: domain.Favicon{Path: "/favicon.ico"}}}},
+		{FQDN: host, Matches: []domain.FaviconMatch{{IP: ip, Favicon: domain.Favicon{Path: "/favicon.ico"}}}},
+	}
+	leads := []domain.OriginLead{
+		{Prefix: "192.0.2.16/28", IPs: []string{"192.0.2.20"}, Source: domain.LeadSPF, Chain: []string{"example.com", "ip4:192.0.2.16/28"}},
+		{IP: ip, Source: domain.LeadMX, Chain: []string{"example.com", "mx:mail.example.com"}},
+	}
+
+	tests := []struct {
+		name   string
+		got    func(ip string) (float64, string)
+		values map[string]float64
+	}{
+		// likely se limita a 0.7; unrelated no aporta
+		{"http", func(ip string) (float64, string) { return httpSignal(origins, host, ip) }, map[string]float64{ip: 0.7, "192.0.2.20": 0.95, "192.0.2.30": 0}},
+		// Por defecto y nombrando al host: 1; con SNI y solo el dominio: 0.5
+		{"tls", func(ip string) (float64, string) { return tlsSignal(certs, host, ip) }, map[string]float64{ip: 1, "192.0.2.20": 0.5, "192.0.2.30": 0}},
+		{"favicon", func(ip string) (float64, string) { return faviconSignal(favicons, host, ip) }, map[string]float64{ip: 1, "192.0.2.20": 0.5, "192.0.2.30": 0}},
+		{"spf", func(ip string) (float64, string) { return spfSignal(leads, ip) }, map[string]float64{ip: 1, "192.0.2.20": 0.7, "192.0.2.30": 0}},
+	}
+	for _, tt := range tests {
+		for ip, want := range tt.values {
+			if value, detail := tt.got(ip); value != want || (value > 0) != (detail != "") {
+				t.Errorf("%s(%s) = %v %q, esperado %v", tt.name, ip, value, detail, want)
+			}
+		}
+	}
+}
+
+func TestScoreNormalisation(t *testing.T) {
+	answers := map[string][]domain.ResultEntry{
+		"www.example.com": {cf("www.example.com", "104.16.1.1")},
+	}
+	origins := []domain.OriginCheck{{FQDN: "www.example.com", IP: "192.0.2.10", Score: 0.9, Verdict: domain.OriginConfirmed}}
+
+	tests := []struct {
+		name      string
+		weights   map[string]float64
+		evaluated map[string]bool
+		want      float64
+	}{
+		// http (4) + dns (1) + ptr (1): tls, favicon y spf no corrieron y
+		// hosting no tiene clasificador
+		{"solo evaluadas", nil, map[string]bool{domain.SignalHTTP: true}, 0.6},
+		// Una etapa evaluada sin aporte sí baja el puntaje
+		{"tls sin coincidencias", nil, map[string]bool{domain.SignalHTTP: true, domain.SignalTLS: true}, 0.4},
+		{"pesos propios", map[string]float64{"HTTP": 2, domain.SignalPTR: 0}, map[string]bool{domain.SignalHTTP: true}, 0.6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := newTestScanner(&fakeResolver{}, nil)
+			config := domain.ScannerConfig{Domain: "example.com", Threads: 1, Timeout: time.Second, ScoreWeights: tt.weights}
+			in := scoreInputs{answers: answers, origins: origins, evaluated: tt.evaluated}
+
+			scores := scanner.scoreOrigins(context.Background(), config, in, []string{"192.0.2.10"})
+			if len(scores) != 1 || scores[0].Score != tt.want {
+				t.Fatalf("scores = %+v, esperado %v", scores, tt.want)
+			}
+			if signals := scores[0].Signals; len(signals) != 1 || signals[0].Name != domain.SignalHTTP {
+				t.Errorf("señales = %+v", signals)
+			}
+		})
+	}
+}
+
+func TestScoreTopCandidates(t *testing.T) {
+	answers := map[string][]domain.ResultEntry{
+		"www.example.com": {cf("www.example.com", "104.16.1.1")},
+		"api.example.com": {cf("api.example.com", "104.16.1.2")},
+	}
+	var origins []domain.OriginCheck
+	for ip, score := range map[string]float64{"192.0.2.1": 0.5, "192.0.2.2": 0.9, "192.0.2.3": 0.9, "192.0.2.4": 0.8} {
+		origins = append(origins,
+			domain.OriginCheck{FQDN: "www.example.com", IP: ip, Score: score, Verdict: domain.OriginConfirmed},
+			domain.OriginCheck{FQDN: "api.example.com", IP: ip, Score: 1 - score, Verdict: domain.OriginConfirmed},
+		)
+	}
+	// 192.0.2.5 no tiene ninguna señal y no se reporta
+	candidates := []string{"192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.4", "192.0.2.5"}
+
+	scanner := newTestScanner(&fakeResolver{}, nil)
+	config := domain.ScannerConfig{Domain: "example.com", Threads: 2, Timeout: time.Second, TopCandidates: 2}
+	in := scoreInputs{answers: answers, origins: origins, evaluated: map[string]bool{domain.SignalHTTP: true}}
+	scores := scanner.scoreOrigins(context.Background(), config, in, candidates)
+
+	var got []string
+	for _, score := range scores {
+		got = append(got, score.FQDN+" "+score.IP)
+	}
+	// Por host, de mayor a menor puntaje y por IP en los empates
+	want := []string{
+		"api.example.com 192.0.2.1", "api.example.com 192.0.2.4",
+		"www.example.com 192.0.2.2", "www.example.com 192.0.2.3",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("candidatos = %v, esperado %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("candidatos = %v, esperado %v", got, want)
+			break
+		}
+	}
+
+	config.TopCandidates = 0
+	if all := scanner.scoreOrigins(context.Background(), config, in, candidates); len(all) != 8 {
+		t.Errorf("sin límite = %d candidatos, esperado 8", len(all))
+	}
+}
+
+func TestLookupPTRsNoTimeout(t *testing.T) {
+	// -timeout 0 no limita la resolución de PTR
+	resolver := &fakeResolver{ptr: map[string][]string{"192.0.2.10": {"origin.example.com."}}}
+	scanner := newTestScanner(resolver, nil)
+	config := domain.ScannerConfig{Domain: "example.com", Threads: 2, Timeout: 0}
+
+	ptrs := scanner.lookupPTRs(context.Background(), config, []string{"192.0.2.10", "192.0.2.11"})
+	if len(ptrs) != 1 || !slices.Equal(ptrs["192.0.2.10"], []string{"origin.example.com."}) {
+		t.Errorf("ptrs = %v", ptrs)
+	}
+}
@@ -0,0 +1,319 @@
+package service
+
+import (
+	"context"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// Límites de la expansión SPF (RFC 7208 §4.6.4)
+const (
+	defaultSPFLookups = 10
+	maxMXHosts        = 10
+	// Rangos de hasta 16 direcciones se expanden como candidatos
+	maxExpandBits = 4
+)
+
+// spfMiner expande el registro SPF del dominio siguiendo include:, redirect=,
+// a y mx, con detección de ciclos y límite de consultas
+type spfMiner struct {
+	scanner    *Scanner
+	config     domain.ScannerConfig
+	maxLookups int
+	lookups    int
+	visited    map[string]bool
+	leads      []domain.OriginLead
+}
+
+// mineLeads busca IPs de origen en el SPF y los MX del dominio. Las IPs de
+// CDN/WAF se descartan; las que coinciden con respuestas del escaneo se
+// agregan como evidencia.
+func (s *Scanner) mineLeads(ctx context.Context, config domain.ScannerConfig, answers map[string][]domain.ResultEntry) []domain.OriginLead {
+	miner := &spfMiner{
+		scanner:    s,
+		config:     config,
+		maxLookups: config.SPFMaxLookups,
+		visited:    make(map[string]bool),
+	}
+	if miner.maxLookups <= 0 {
+		miner.maxLookups = defaultSPFLookups
+	}
+
+	target := strings.ToLower(config.Domain)
+	miner.walk(ctx, target, []string{target})
+	miner.mx(ctx, target, "", []string{target}, domain.LeadMX)
+
+	// Sin duplicados: se conserva la primera cadena que llegó a cada IP/rango
+	seen := make(map[string]bool)
+	var leads []domain.OriginLead
+	for _, lead := range miner.leads {
+		key := lead.IP + lead.Prefix
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		leads = append(leads, lead)
+		// spf:ip4:203.0.113.0/28, o mx:mail.example.com para los MX directos
+		evidence := lead.Chain[len(lead.Chain)-1]
+		if lead.Source == domain.LeadSPF {
+			evidence = domain.LeadSPF + ":" + evidence
+		}
+		for _, ip := range append([]string{lead.IP}, lead.IPs...) {
+			if ip != "" {
+				addEvidence(answers, ip, evidence)
+			}
+		}
+	}
+
+	s.logger.Info().
+		Int("leads", len(leads)).
+		Int("spf_lookups", miner.lookups).
+		Msg("Minería SPF/MX completada")
+	return leads
+}
+
+// walk procesa el SPF de name; chain es la procedencia hasta name
+func (m *spfMiner) walk(ctx context.Context, name string, chain []string) {
+	if m.visited[name] {
+		m.scanner.logger.Warn().Str("domain", name).Strs("chain", chain).Msg("Ciclo en SPF")
+		return
+	}
+	m.visited[name] = true
+
+	record, ok := m.record(ctx, name)
+	if !ok {
+		return
+	}
+
+	var redirect string
+	hasAll := false
+	for _, term := range strings.Fields(record)[1:] {
+		if value, ok := cutPrefixFold(term, "redirect="); ok {
+			redirect = value
+			continue
+		}
+		if strings.Contains(term, "=") {
+			// Otros modificadores (exp=) no aportan direcciones
+			continue
+		}
+
+		qualifier := term[0]
+		if strings.ContainsRune("+-~?", rune(qualifier)) {
+			term = term[1:]
+		} else {
+			qualifier = '+'
+		}
+		// Las direcciones con -all/-ip4 no envían correo en nombre del dominio
+		if qualifier == '-' {
+			if strings.EqualFold(term, "all") {
+				hasAll = true
+			}
+			continue
+		}
+
+		mechanism, value, _ := strings.Cut(term, ":")
+		mechanism = strings.ToLower(mechanism)
+		step := append(slices.Clone(chain), term)
+
+		// a/24 y mx/24 llevan la máscara sin dominio
+		if base, spec, ok := strings.Cut(mechanism, "/"); ok && value == "" {
+			mechanism, value = base, "/"+spec
+		}
+
+		switch mechanism {
+		case "all":
+			hasAll = true
+		case "ip4", "ip6":
+			m.addLead(value, step, domain.LeadSPF)
+		case "a", "mx":
+			if !m.count(name) {
+				return
+			}
+			m.hostMechanism(ctx, name, mechanism, value, step)
+		case "include":
+			if !m.count(name) {
+				return
+			}
+			m.walk(ctx, strings.ToLower(value), step)
+		case "ptr", "exists":
+			// Cuentan para el límite pero no llevan a direcciones concretas
+			if !m.count(name) {
+				return
+			}
+		}
+	}
+
+	// redirect= solo aplica si el registro no termina en all
+	if redirect != "" && !hasAll {
+		if !m.count(name) {
+			return
+		}
+		m.walk(ctx, strings.ToLower(redirect), append(slices.Clone(chain), "redirect="+redirect))
+	}
+}
+
+// hostMechanism resuelve a[:domain][/cidr] y mx[:domain][/cidr]
+func (m *spfMiner) hostMechanism(ctx context.Context, name, mechanism, value string, chain []string) {
+	host, spec, _ := strings.Cut(value, "/")
+	host = orDefault(host, name)
+	if mechanism == "mx" {
+		m.mx(ctx, host, spec, chain, domain.LeadSPF)
+		return
+	}
+	m.resolve(ctx, host, spec, chain, domain.LeadSPF)
+}
+
+// record retorna el registro v=spf1 de name
+func (m *spfMiner) record(ctx context.Context, name string) (string, bool) {
+	lookupCtx, cancel := timeoutContext(ctx, m.config.Timeout)
+	defer cancel()
+
+	records, err := m.scanner.dnsResolver.LookupTXT(lookupCtx, name)
+	if err != nil {
+		m.scanner.logger.Debug().Err(err).Str("domain", name).Msg("Error resolviendo TXT")
+		return "", false
+	}
+
+	var spf []string
+	for _, record := range records {
+		if fields := strings.Fields(record); len(fields) > 0 && strings.EqualFold(fields[0], "v=spf1") {
+			spf = append(spf, record)
+		}
+	}
+	switch len(spf) {
+	case 0:
+		m.scanner.logger.Debug().Str("domain", name).Msg("Dominio sin registro SPF")
+		return "", false
+	case 1:
+	default:
+		m.scanner.logger.Warn().Str("domain", name).Int("records", len(spf)).Msg("Varios registros SPF; se usa el primero")
+	}
+	return spf[0], true
+}
+
+// count registra una consulta DNS; false si se alcanzó el límite
+func (m *spfMiner) count(name string) bool {
+	if m.lookups >= m.maxLookups {
+		m.scanner.logger.Warn().
+			Str("domain", name).
+			Int("limit", m.maxLookups).
+			Msg("Límite de consultas SPF alcanzado")
+		return false
+	}
+	m.lookups++
+	return true
+}
+
+// mx resuelve los hosts de correo de name
+func (m *spfMiner) mx(ctx context.Context, name, spec string, chain []string, source string) {
+	lookupCtx, cancel := timeoutContext(ctx, m.config.Timeout)
+	defer cancel()
+
+	hosts, err := m.scanner.dnsResolver.LookupMX(lookupCtx, name)
+	if err != nil {
+		m.scanner.logger.Debug().Err(err).Str("domain", name).Msg("Error resolviendo MX")
+		return
+	}
+	if len(hosts) > maxMXHosts {
+		hosts = hosts[:maxMXHosts]
+	}
+	for _, host := range hosts {
+		m.resolve(ctx, host, spec, append(slices.Clone(chain), "mx:"+host), source)
+	}
+}
+
+// resolve agrega las IPs de host; con spec (a:host/24) agrega el rango
+func (m *spfMiner) resolve(ctx context.Context, host, spec string, chain []string, source string) {
+	lookupCtx, cancel := timeoutContext(ctx, m.config.Timeout)
+	defer cancel()
+
+	ips, err := m.scanner.dnsResolver.LookupIP(lookupCtx, host)
+	if err != nil {
+		m.scanner.logger.Debug().Err(err).Str("host", host).Msg("Error resolviendo host de SPF/MX")
+		return
+	}
+	for _, ip := range ips {
+		value := ip
+		if spec != "" {
+			addr, err := netip.ParseAddr(ip)
+			if err != nil {
+				continue
+			}
+			// a:host/24//64 lleva la máscara IPv4 y luego la IPv6; spec
+			// llega sin la primera barra
+			mask, mask6, _ := strings.Cut(spec, "//")
+			if strings.HasPrefix(spec, "/") {
+				mask, mask6 = "", spec[1:]
+			}
+			if addr.Is6() {
+				mask = mask6
+			}
+			if mask != "" {
+				value = ip + "/" + mask
+			}
+		}
+		m.addLead(value, chain, source)
+	}
+}
+
+// addLead agrega una IP o un rango; los rangos chicos se expanden en IPs
+// candidatas y las IPs de CDN/WAF se descartan
+func (m *spfMiner) addLead(value string, chain []string, source string) {
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		addr, aErr := netip.ParseAddr(value)
+		if aErr != nil {
+			m.scanner.logger.Debug().Str("value", value).Strs("chain", chain).Msg("Dirección SPF inválida")
+			return
+		}
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	}
+	prefix = prefix.Masked()
+
+	if prefix.IsSingleIP() {
+		if !m.isCDN(prefix.Addr().String()) {
+			m.leads = append(m.leads, domain.OriginLead{IP: prefix.Addr().String(), Source: source, Chain: chain})
+		}
+		return
+	}
+
+	lead := domain.OriginLead{Prefix: prefix.String(), Source: source, Chain: chain}
+	if prefix.Addr().BitLen()-prefix.Bits() <= maxExpandBits {
+		for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
+			if !m.isCDN(addr.String()) {
+				lead.IPs = append(lead.IPs, addr.String())
+			}
+		}
+		if len(lead.IPs) == 0 {
+			return
+		}
+	} else if m.isCDN(prefix.Addr().String()) {
+		return
+	}
+	m.leads = append(m.leads, lead)
+}
+
+func (m *spfMiner) isCDN(ip string) bool {
+	provider, _, ok := m.scanner.providerRegistry.ClassifyIP(ip)
+	if ok {
+		m.scanner.logger.Debug().Str("ip", ip).Str("provider", provider).Msg("IP de SPF/MX pertenece a un CDN")
+	}
+	return ok
+}
+
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return "", false
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return strings.ToLower(value)
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// leadKeys resume los leads como "IP", "prefijo" o "prefijo=ip1,ip2"
+func leadKeys(leads []domain.OriginLead) []string {
+	keys := make([]string, 0, len(leads))
+	for _, lead := range leads {
+		switch {
+		case lead.IP != "":
+			keys = append(keys, lead.IP)
+		case len(lead.IPs) > 0:
+			keys = append(keys, lead.Prefix+"="+strings.Join(lead.IPs, ","))
+		default:
+			keys = append(keys, lead.Prefix)
+		}
+	}
+	return keys
+}
+
+func mineTest(t *testing.T, resolver *fakeResolver, registry *fakeRegistry, maxLookups int) []domain.OriginLead {
+	t.Helper()
+	scanner := newTestScanner(resolver, registry)
+	config := domain.ScannerConfig{Domain: "example.com", Timeout: time.Second, SPFMaxLookups: maxLookups}
+	return scanner.mineLeads(context.Background(), config, nil)
+}
+
+func TestSPFMechanisms(t *testing.T) {
+	tests := []struct {
+		name     string
+		txt      map[string][]string
+		ips      map[string][]string
+		mx       map[string][]string
+		registry *fakeRegistry
+		want     []string
+	}{
+		{
+			name: "ip4, ip6 y calificadores",
+			txt:  map[string][]string{"example.com": {"google-site-verification=abc", "v=spf1 ip4:192.0.2.1 +ip4:192.0.2.2 ~ip6:2001:db8::1 -ip4:192.0.2.3 ?ip4:198.51.100.0/24 exp=explain.example.com -all"}},
+			want: []string{"192.0.2.1", "192.0.2.2", "2001:db8::1", "198.51.100.0/24"},
+		},
+		{
+			name: "máscaras a/24//64",
+			txt:  map[string][]string{"example.com": {"v=spf1 a/24//64 a:mail.example.com/30 a//120 ~all"}},
+			ips: map[string][]string{
+				"example.com":      {"192.0.2.10", "2001:db8::10"},
+				"mail.example.com": {"198.51.100.5", "2001:db8:1::5"},
+			},
+			want: []string{
+				"192.0.2.0/24", "2001:db8::/64",
+				"198.51.100.4/30=198.51.100.4,198.51.100.5,198.51.100.6,198.51.100.7",
+				"2001:db8:1::5", // /30 solo aplica a IPv4
+				"192.0.2.10", "2001:db8::/120",
+			},
+		},
+		{
+			name: "mx con máscara",
+			txt:  map[string][]string{"example.com": {"v=spf1 mx/31 mx:other.example ~all"}},
+			mx: map[string][]string{
+				"example.com":   {"mx1.example.com"},
+				"other.example": {"mx.other.example"},
+			},
+			ips: map[string][]string{
+				"mx1.example.com":  {"192.0.2.20"},
+				"mx.other.example": {"203.0.113.9"},
+			},
+			// mx/31 cubre .20 y .21; el MX directo del dominio repite .20
+			want: []string{"192.0.2.20/31=192.0.2.20,192.0.2.21", "203.0.113.9", "192.0.2.20"},
+		},
+		{
+			name: "expansión de /28 sin IPs de CDN",
+			txt:  map[string][]string{"example.com": {"v=spf1 ip4:203.0.113.0/28 ip4:203.0.113.0/27 ip6:2001:db8::/124 ip4:104.16.0.0/28 ip4:104.16.0.0/20 ~all"}},
+			registry: &fakeRegistry{prefixes: map[string]string{
+				"203.0.113.1/32": "fastly",
+				"104.16.0.0/13":  domain.ProviderCloudflare,
+			}},
+			want: []string{
+				"203.0.113.0/28=203.0.113.0,203.0.113.2,203.0.113.3,203.0.113.4,203.0.113.5,203.0.113.6,203.0.113.7,203.0.113.8,203.0.113.9,203.0.113.10,203.0.113.11,203.0.113.12,203.0.113.13,203.0.113.14,203.0.113.15",
+				// Más de 16 direcciones: se reporta el rango sin expandir
+				"203.0.113.0/27",
+				"2001:db8::/124=2001:db8::,2001:db8::1,2001:db8::2,2001:db8::3,2001:db8::4,2001:db8::5,2001:db8::6,2001:db8::7,2001:db8::8,2001:db8::9,2001:db8::a,2001:db8::b,2001:db8::c,2001:db8::d,2001:db8::e,2001:db8::f",
+			},
+		},
+		{
+			name: "redirect sin all",
+			txt: map[string][]string{
+				"example.com":      {"v=spf1 ip4:192.0.2.1 redirect=_spf.example.com"},
+				"_spf.example.com": {"v=spf1 ip4:192.0.2.2 -all"},
+			},
+			want: []string{"192.0.2.1", "192.0.2.2"},
+		},
+		{
+			name: "all tiene precedencia sobre redirect",
+			txt: map[string][]string{
+				"example.com":      {"v=spf1 ip4:192.0.2.1 REDIRECT=_spf.example.com ~all"},
+				"_spf.example.com": {"v=spf1 ip4:192.0.2.2 -all"},
+			},
+			want: []string{"192.0.2.1"},
+		},
+		{
+			name: "-all también anula redirect",
+			txt: map[string][]string{
+				"example.com":      {"v=spf1 ip4:192.0.2.1 -all redirect=_spf.example.com"},
+				"_spf.example.com": {"v=spf1 ip4:192.0.2.2 -all"},
+			},
+			want: []string{"192.0.2.1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolver := &fakeResolver{txt: tt.txt, ips: tt.ips, mx: tt.mx}
+			if got := leadKeys(mineTest(t, resolver, tt.registry, 0)); !slices.Equal(got, tt.want) {
+				t.Errorf("leads = %v\nesperado %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSPFCycle(t *testing.T) {
+	resolver := &fakeResolver{txt: map[string][]string{
+		"example.com":       {"v=spf1 include:_spf.example.net ~all"},
+		"_spf.example.net":  {"v=spf1 include:_spf2.example.net ip4:192.0.2.1 ~all"},
+		"_spf2.example.net": {"v=spf1 include:EXAMPLE.com include:_spf.example.net ip4:192.0.2.2 ~all"},
+	}}
+	leads := mineTest(t, resolver, nil, 0)
+
+	if got := leadKeys(leads); !slices.Equal(got, []string{"192.0.2.2", "192.0.2.1"}) {
+		t.Errorf("leads = %v", got)
+	}
+	// Cada dominio se consulta una sola vez
+	for _, name := range []string{"example.com", "_spf.example.net", "_spf2.example.net"} {
+		if n := resolver.queries["TXT "+name]; n != 1 {
+			t.Errorf("TXT %s consultado %d veces", name, n)
+		}
+	}
+	// La cadena registra la procedencia
+	if want := []string{"example.com", "include:_spf.example.net", "include:_spf2.example.net", "ip4:192.0.2.2"}; !slices.Equal(leads[0].Chain, want) {
+		t.Errorf("chain = %v, esperado %v", leads[0].Chain, want)
+	}
+}
+
+func TestSPFNoTimeout(t *testing.T) {
+	// -timeout 0 no limita las consultas de include y mx
+	resolver := &fakeResolver{
+		txt: map[string][]string{
+			"example.com":      {"v=spf1 include:_spf.example.net mx ~all"},
+			"_spf.example.net": {"v=spf1 ip4:192.0.2.1 ~all"},
+		},
+		mx:  map[string][]string{"example.com": {"mail.example.com"}},
+		ips: map[string][]string{"mail.example.com": {"198.51.100.5"}},
+	}
+	scanner := newTestScanner(resolver, nil)
+	config := domain.ScannerConfig{Domain: "example.com", Timeout: 0}
+	leads := scanner.mineLeads(context.Background(), config, nil)
+	if got := leadKeys(leads); !slices.Equal(got, []string{"192.0.2.1", "198.51.100.5"}) {
+		t.Errorf("leads = %v", got)
+	}
+}
+
+func TestSPFLookupLimit(t *testing.T) {
+	// Una cadena de 12 includes, cada uno con una IP propia
+	txt := make(map[string][]string)
+	for i := 0; i < 12; i++ {
+		txt[fmt.Sprintf("s%d.example.com", i)] = []string{fmt.Sprintf("v=spf1 ip4:192.0.2.%d include:s%d.example.com ~all", i+1, i+1)}
+	}
+	txt["example.com"] = []string{"v=spf1 include:s0.example.com a mx ptr exists:%{i}.example.com ~all"}
+
+	tests := []struct {
+		maxLookups int
+		want       int
+	}{
+		// 0 usa el límite de la RFC (10): el include inicial y 9 más
+		{0, 10},
+		{3, 3},
+		{1, 1},
+	}
+	for _, tt := range tests {
+		resolver := &fakeResolver{txt: txt}
+		leads := mineTest(t, resolver, nil, tt.maxLookups)
+		if len(leads) != tt.want {
+			t.Errorf("max %d: %d leads, esperado %d (%v)", tt.maxLookups, len(leads), tt.want, leadKeys(leads))
+		}
+		// Al alcanzar el límite no se siguen evaluando mecanismos con consultas
+		if n := resolver.queries["A example.com"]; n != 0 {
+			t.Errorf("max %d: a del dominio consultado %d veces tras el límite", tt.maxLookups, n)
+		}
+	}
+}
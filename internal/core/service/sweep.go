@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// Límites del barrido de vecinos; la configuración no puede superarlos
+const (
+	sweepMinPrefix4 = 20
+	sweepPrefix6    = 120
+	sweepMaxTotal   = 4096
+	sweepMaxRate    = 100
+)
+
+// sweepNeighbours busca, en el bloque que rodea a cada origen confirmado,
+// otras IPs cuyo certificado nombra al dominio (staging, admin, orígenes de
+// la API). El total de direcciones y las conexiones por segundo están
+// acotados por sweep_max y sweep_rate.
+func (s *Scanner) sweepNeighbours(ctx context.Context, config domain.ScannerConfig, answers map[string][]domain.ResultEntry, origins []domain.OriginCheck, certs []domain.CertCheck) []domain.SweepHit {
+	seeds := confirmedOrigins(origins, certs)
+	if len(seeds) == 0 {
+		s.logger.Info().Msg("Sin orígenes confirmados para barrer vecinos")
+		return nil
+	}
+
+	ports := config.TLSPorts
+	if len(ports) == 0 {
+		ports = []int{443}
+	}
+	budget := min(config.SweepMax, sweepMaxTotal)
+	if budget <= 0 {
+		budget = sweepMaxTotal
+	}
+	rate := min(max(config.SweepRate, 1), sweepMaxRate)
+
+	// Las semillas y las IPs ya sondeadas no se repiten
+	seen := make(map[string]bool)
+	for _, ip := range seeds {
+		seen[ip] = true
+	}
+	for _, check := range certs {
+		seen[check.IP] = true
+	}
+
+	// El presupuesto se reparte entre las semillas
+	perSeed := max(budget/len(seeds), 1)
+
+	type target struct {
+		ip, seed, netblock string
+	}
+	var targets []target
+	for _, seed := range seeds {
+		block, ok := s.sweepBlock(seed, config.SweepPrefix)
+		if !ok {
+			continue
+		}
+		count := 0
+		for _, addr := range neighbours(block, seed) {
+			if count >= perSeed || len(targets) >= budget {
+				break
+			}
+			ip := addr.String()
+			if seen[ip] {
+				continue
+			}
+			seen[ip] = true
+			if _, _, cdn := s.providerRegistry.ClassifyIP(ip); cdn {
+				continue
+			}
+			targets = append(targets, target{ip: ip, seed: seed, netblock: block.String()})
+			count++
+		}
+	}
+
+	s.logger.Info().
+		Strs("seeds", seeds).
+		Int("addresses", len(targets)).
+		Ints("ports", ports).
+		Int("rate", rate).
+		Msg("Barriendo vecinos de orígenes confirmados")
+
+	// sweep_rate cuenta handshakes: cada dirección y puerto lleva dos (sin
+	// SNI y con SNI), así que se espera un tick antes de cada uno
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
+	wait := func() bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+			return true
+		}
+	}
+
+	var (
+		mu    sync.Mutex
+		group = newBoundedGroup(ctx, config.Threads)
+		hits  []domain.SweepHit
+	)
+
+dispatch:
+	for _, t := range targets {
+		for _, port := range ports {
+			dispatched := group.Go(func() {
+				for _, check := range s.certificatesAt(ctx, config.Domain, t.ip, port, wait) {
+					if check.Match == "" {
+						continue
+					}
+					mu.Lock()
+					hits = append(hits, domain.SweepHit{Seed: t.seed, Netblock: t.netblock, Cert: check})
+					mu.Unlock()
+				}
+			})
+			if !dispatched {
+				break dispatch
+			}
+		}
+	}
+	group.Wait()
+
+	sort.Slice(hits, func(i, j int) bool {
+		a, b := hits[i].Cert, hits[j].Cert
+		if a.IP != b.IP {
+			return a.IP < b.IP
+		}
+		if a.Port != b.Port {
+			return a.Port < b.Port
+		}
+		return a.SNI < b.SNI
+	})
+
+	for _, hit := range hits {
+		addEvidence(answers, hit.Cert.IP, "sweep:"+hit.Seed)
+		s.logger.Warn().
+			Str("ip", hit.Cert.IP).
+			Int("port", hit.Cert.Port).
+			Str("seed", hit.Seed).
+			Strs("names", hit.Cert.Names).
+			Msg("Vecino con certificado del dominio")
+	}
+	s.logger.Info().
+		Int("addresses", len(targets)).
+		Int("hits", len(hits)).
+		Msg("Barrido de vecinos completado")
+
+	return hits
+}
+
+// confirmedOrigins retorna las IPs con veredicto confirmed o cuyo
+// certificado nombra al dominio
+func confirmedOrigins(origins []domain.OriginCheck, certs []domain.CertCheck) []string {
+	seen := make(map[string]bool)
+	var seeds []string
+	add := func(ip string) {
+		if !seen[ip] {
+			seen[ip] = true
+			seeds = append(seeds, ip)
+		}
+	}
+	for _, check := range origins {
+		if check.Verdict == domain.OriginConfirmed {
+			add(check.IP)
+		}
+	}
+	for _, check := range certs {
+		if check.Match != "" {
+			add(check.IP)
+		}
+	}
+	sort.Strings(seeds)
+	return seeds
+}
+
+// sweepBlock retorna el bloque a barrer alrededor de seed: el /prefix de la
+// semilla (/120 en IPv6), recortado al prefijo del proveedor cloud si es más
+// chico
+func (s *Scanner) sweepBlock(seed string, prefix int) (netip.Prefix, bool) {
+	addr, err := netip.ParseAddr(seed)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr = addr.Unmap()
+
+	bits := sweepPrefix6
+	if addr.Is4() {
+		bits = max(prefix, sweepMinPrefix4)
+	}
+	block, err := addr.Prefix(bits)
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+
+	if s.cloudClassifier != nil {
+		if info, ok := s.cloudClassifier.ClassifyIP(seed); ok {
+			if cloud, err := netip.ParsePrefix(info.Prefix); err == nil && cloud.Bits() > block.Bits() {
+				block = cloud.Masked()
+			}
+		}
+	}
+	return block, true
+}
+
+// neighbours retorna las direcciones del bloque ordenadas por distancia a
+// seed, para que el límite de direcciones corte las más lejanas
+func neighbours(block netip.Prefix, seed string) []netip.Addr {
+	center, err := netip.ParseAddr(seed)
+	if err != nil {
+		return nil
+	}
+	center = center.Unmap()
+
+	var addrs []netip.Addr
+	up, down := center.Next(), center.Prev()
+	for {
+		inUp := up.IsValid() && block.Contains(up)
+		inDown := down.IsValid() && block.Contains(down)
+		if !inUp && !inDown {
+			break
+		}
+		if inUp {
+			addrs = append(addrs, up)
+			up = up.Next()
+		}
+		if inDown {
+			addrs = append(addrs, down)
+			down = down.Prev()
+		}
+	}
+	return addrs
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+func TestNeighbours(t *testing.T) {
+	tests := []struct {
+		block, seed string
+		want        []string
+	}{
+		// Alternando hacia arriba y hacia abajo desde la semilla
+		{"192.0.2.0/29", "192.0.2.3", []string{"192.0.2.4", "192.0.2.2", "192.0.2.5", "192.0.2.1", "192.0.2.6", "192.0.2.0", "192.0.2.7"}},
+		{"192.0.2.0/30", "192.0.2.0", []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"}},
+		{"192.0.2.0/30", "192.0.2.3", []string{"192.0.2.2", "192.0.2.1", "192.0.2.0"}},
+		{"192.0.2.0/30", "::ffff:192.0.2.1", []string{"192.0.2.2", "192.0.2.0", "192.0.2.3"}},
+		{"2001:db8::/126", "2001:db8::2", []string{"2001:db8::3", "2001:db8::1", "2001:db8::"}},
+		// Bordes del espacio de direcciones
+		{"0.0.0.0/31", "0.0.0.0", []string{"0.0.0.1"}},
+		{"255.255.255.254/31", "255.255.255.255", []string{"255.255.255.254"}},
+		{"192.0.2.7/32", "192.0.2.7", nil},
+		{"192.0.2.0/30", "no-es-una-ip", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, addr := range neighbours(netip.MustParsePrefix(tt.block), tt.seed) {
+			got = append(got, addr.String())
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("neighbours(%s, %s) = %v, esperado %v", tt.block, tt.seed, got, tt.want)
+		}
+	}
+}
+
+// fakeCloud atribuye prefijos fijos a un proveedor cloud
+type fakeCloud map[string]string // IP → prefijo
+
+func (f fakeCloud) ClassifyIP(ip string) (domain.CloudInfo, bool) {
+	prefix, ok := f[ip]
+	return domain.CloudInfo{Provider: "aws", Prefix: prefix}, ok
+}
+
+func TestSweepBlock(t *testing.T) {
+	scanner := newTestScanner(&fakeResolver{}, nil)
+	scanner.cloudClassifier = fakeCloud{
+		"198.51.100.70": "198.51.100.64/26",
+		"203.0.113.5":   "203.0.0.0/16",
+	}
+
+	tests := []struct {
+		seed   string
+		prefix int
+		want   string
+		ok     bool
+	}{
+		{"192.0.2.77", 24, "192.0.2.0/24", true},
+		{"192.0.2.77", 28, "192.0.2.64/28", true},
+		// Nunca más grande que /20
+		{"192.0.2.77", 16, "192.0.0.0/20", true},
+		{"::ffff:192.0.2.77", 24, "192.0.2.0/24", true},
+		// IPv6 siempre usa /120
+		{"2001:db8::1:2", 24, "2001:db8::1:0/120", true},
+		// El prefijo del proveedor cloud recorta el bloque si es más chico
+		{"198.51.100.70", 24, "198.51.100.64/26", true},
+		{"203.0.113.5", 24, "203.0.113.0/24", true},
+		{"no-es-una-ip", 24, "", false},
+	}
+	for _, tt := range tests {
+		block, ok := scanner.sweepBlock(tt.seed, tt.prefix)
+		if ok != tt.ok || (ok && block.String() != tt.want) {
+			t.Errorf("sweepBlock(%s, %d) = %s %v, esperado %s %v", tt.seed, tt.prefix, block, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// recordingProber registra cada handshake y responde con certificados fijos
+type recordingProber struct {
+	mu    sync.Mutex
+	calls []string // "ip sni"
+	times []time.Time
+	certs map[string][]string // IP → SANs
+}
+
+func (p *recordingProber) Certificates(_ context.Context, ip string, _ int, sni string) ([]domain.CertInfo, error) {
+	p.mu.Lock()
+	p.calls = append(p.calls, ip+" "+sni)
+	p.times = append(p.times, time.Now())
+	p.mu.Unlock()
+
+	sans, ok := p.certs[ip]
+	if !ok {
+		return nil, errors.New("handshake rechazado")
+	}
+	return []domain.CertInfo{{SANs: sans, Serial: ip}}, nil
+}
+
+func (p *recordingProber) dialed() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	seen := make(map[string]bool)
+	var ips []string
+	for _, call := range p.calls {
+		ip, _, _ := strings.Cut(call, " ")
+		if !seen[ip] {
+			seen[ip] = true
+			ips = append(ips, ip)
+		}
+	}
+	slices.Sort(ips)
+	return ips
+}
+
+func TestSweepBudgetSplit(t *testing.T) {
+	registry := &fakeRegistry{prefixes: map[string]string{"198.51.100.9/32": "fastly"}}
+	scanner := newTestScanner(&fakeResolver{}, registry)
+	prober := &recordingProber{certs: map[string][]string{"192.0.2.9": {"staging.example.com"}}}
+	WithTLSProber(prober)(scanner)
+
+	origins := []domain.OriginCheck{
+		{IP: "192.0.2.10", Verdict: domain.OriginConfirmed},
+		{IP: "198.51.100.10", Verdict: domain.OriginConfirmed},
+		{IP: "203.0.113.10", Verdict: domain.OriginLikely},
+	}
+	// 192.0.2.11 ya se sondeó con -tls-probe
+	certs := []domain.CertCheck{{IP: "192.0.2.11", Port: 443}}
+	config := domain.ScannerConfig{Domain: "example.com", Threads: 4, SweepPrefix: 28, SweepMax: 4, SweepRate: 100, TLSPorts: []int{443}}
+
+	answers := map[string][]domain.ResultEntry{"staging.example.com": {exposed("staging.example.com", "192.0.2.9")}}
+	hits := scanner.sweepNeighbours(context.Background(), config, answers, origins, certs)
+
+	// Dos direcciones por semilla confirmada, las más cercanas que no sean
+	// ya conocidas ni de un CDN
+	want := []string{"192.0.2.12", "192.0.2.9", "198.51.100.11", "198.51.100.12"}
+	slices.Sort(want)
+	if got := prober.dialed(); !slices.Equal(got, want) {
+		t.Errorf("direcciones = %v, esperado %v", got, want)
+	}
+
+	if len(hits) != 1 || hits[0].Cert.IP != "192.0.2.9" || hits[0].Seed != "192.0.2.10" || hits[0].Netblock != "192.0.2.0/28" {
+		t.Fatalf("hits = %+v", hits)
+	}
+	if evidence := answers["staging.example.com"][0].Evidence; !slices.Contains(evidence, "sweep:192.0.2.10") {
+		t.Errorf("evidencia = %v", evidence)
+	}
+}
+
+func TestSweepRateCountsHandshakes(t *testing.T) {
+	scanner := newTestScanner(&fakeResolver{}, nil)
+	prober := &recordingProber{}
+	WithTLSProber(prober)(scanner)
+
+	origins := []domain.OriginCheck{{IP: "192.0.2.10", Verdict: domain.OriginConfirmed}}
+	config := domain.ScannerConfig{Domain: "example.com", Threads: 4, SweepPrefix: 24, SweepMax: 2, SweepRate: 20, TLSPorts: []int{443}}
+
+	start := time.Now()
+	scanner.sweepNeighbours(context.Background(), config, nil, origins, nil)
+	elapsed := time.Since(start)
+
+	// Dos direcciones con dos handshakes cada una (sin SNI y con SNI): cuatro
+	// ticks de 50ms
+	if len(prober.calls) != 4 {
+		t.Fatalf("handshakes = %v", prober.calls)
+	}
+	if interval := time.Second / 20; elapsed < 3*interval {
+		t.Errorf("4 handshakes en %v, esperado al menos %v a %d por segundo", elapsed, 3*interval, config.SweepRate)
+	}
+}
+
+func TestSweepCancelled(t *testing.T) {
+	scanner := newTestScanner(&fakeResolver{}, nil)
+	prober := &recordingProber{}
+	WithTLSProber(prober)(scanner)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	origins := []domain.OriginCheck{{IP: "192.0.2.10", Verdict: domain.OriginConfirmed}}
+	config := domain.ScannerConfig{Domain: "example.com", Threads: 1, SweepPrefix: 24, SweepMax: 16, SweepRate: 1}
+	if hits := scanner.sweepNeighbours(ctx, config, nil, origins, nil); len(hits) != 0 || len(prober.calls) != 0 {
+		t.Errorf("hits = %v, handshakes = %v", hits, prober.calls)
+	}
+}
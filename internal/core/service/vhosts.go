@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+const (
+	// Hosts aleatorios usados como referencia de la respuesta por defecto
+	vhostBaselines = 3
+	// Similitud a partir de la cual una respuesta es la misma por defecto
+	vhostSameScore = 0.9
+)
+
+// vhostTarget es una IP (y puerto, 0 para los estándar) a la que pedir hosts
+type vhostTarget struct {
+	ip   string
+	port int
+}
+
+// discoverVHosts pide cada origen confirmado con Host tomados de los
+// resultados y del wordlist (<palabra>.<dominio>) y reporta los nombres cuya
+// respuesta se distingue de la de hosts aleatorios, agrupados por similitud
+func (s *Scanner) discoverVHosts(ctx context.Context, config domain.ScannerConfig, answers map[string][]domain.ResultEntry, subdomains []string, origins []domain.OriginCheck, certs []domain.CertCheck, sweep []domain.SweepHit) []domain.VHostCheck {
+	targets := vhostTargets(origins, certs, sweep)
+	if len(targets) == 0 {
+		s.logger.Info().Msg("Sin orígenes confirmados para buscar virtual hosts")
+		return nil
+	}
+
+	names := vhostNames(config, answers, subdomains)
+	s.logger.Info().
+		Int("origins", len(targets)).
+		Int("names", len(names)).
+		Msg("Buscando virtual hosts en orígenes confirmados")
+
+	var checks []domain.VHostCheck
+	for _, target := range targets {
+		if ctx.Err() != nil {
+			break
+		}
+		check, ok := s.vhostsAt(ctx, config, target, names, answers)
+		if !ok {
+			continue
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// vhostsAt compara los nombres contra la referencia de hosts aleatorios en
+// una IP
+func (s *Scanner) vhostsAt(ctx context.Context, config domain.ScannerConfig, target vhostTarget, names []string, answers map[string][]domain.ResultEntry) (domain.VHostCheck, bool) {
+	address := target.ip
+	if target.port != 0 {
+		address = net.JoinHostPort(target.ip, strconv.Itoa(target.port))
+	}
+
+	var baselines []domain.PageSnapshot
+	for range vhostBaselines {
+		host := randomLabel() + "." + config.Domain
+		snapshot, err := s.originVerifier.Snapshot(ctx, target.ip, target.port, host)
+		if err != nil {
+			s.logger.Debug().Err(err).Str("address", address).Str("host", host).Msg("Error obteniendo referencia de host aleatorio")
+			continue
+		}
+		baselines = append(baselines, snapshot)
+	}
+	if len(baselines) == 0 {
+		s.logger.Warn().Str("address", address).Msg("El origen no responde a hosts aleatorios; sin virtual hosts")
+		return domain.VHostCheck{}, false
+	}
+
+	var (
+		mu     sync.Mutex
+		group  = newBoundedGroup(ctx, config.Threads)
+		found  []domain.VHost
+		shapes []domain.PageSnapshot
+	)
+	for _, name := range names {
+		dispatched := group.Go(func() {
+			snapshot, err := s.originVerifier.Snapshot(ctx, target.ip, target.port, name)
+			if err != nil {
+				s.logger.Debug().Err(err).Str("address", address).Str("host", name).Msg("Virtual host sin respuesta")
+				return
+			}
+
+			// Parecido a la referencia: el servidor responde igual a cualquier host
+			score := 0.0
+			for _, baseline := range baselines {
+				score = max(score, s.originVerifier.Compare(baseline, snapshot))
+			}
+			if score >= vhostSameScore {
+				return
+			}
+
+			mu.Lock()
+			found = append(found, domain.VHost{
+				Name:     name,
+				Status:   snapshot.Status,
+				Title:    snapshot.Title,
+				Baseline: math.Round(score*1000) / 1000,
+				Hidden:   !resolvesTo(answers, name, target.ip),
+			})
+			shapes = append(shapes, snapshot)
+			mu.Unlock()
+		})
+		if !dispatched {
+			break
+		}
+	}
+	group.Wait()
+
+	// Ordenar por nombre antes de agrupar para que los números de grupo no
+	// dependan del orden de las respuestas
+	order := make([]int, len(found))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return found[order[i]].Name < found[order[j]].Name
+	})
+	sortedFound := make([]domain.VHost, len(found))
+	sortedShapes := make([]domain.PageSnapshot, len(found))
+	for i, idx := range order {
+		sortedFound[i], sortedShapes[i] = found[idx], shapes[idx]
+	}
+	found = sortedFound
+
+	s.clusterVHosts(found, sortedShapes)
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Cluster != found[j].Cluster {
+			return found[i].Cluster < found[j].Cluster
+		}
+		return found[i].Name < found[j].Name
+	})
+
+	hidden := 0
+	for _, vhost := range found {
+		if vhost.Hidden {
+			hidden++
+			s.logger.Warn().
+				Str("address", address).
+				Str("host", vhost.Name).
+				Int("status", vhost.Status).
+				Int("cluster", vhost.Cluster).
+				Msg("Virtual host oculto")
+		}
+	}
+	s.logger.Info().
+		Str("address", address).
+		Int("names", len(names)).
+		Int("distinct", len(found)).
+		Int("hidden", hidden).
+		Msg("Virtual hosts del origen")
+
+	return domain.VHostCheck{
+		IP:     target.ip,
+		Port:   target.port,
+		Status: baselines[0].Status,
+		Title:  baselines[0].Title,
+		VHosts: found,
+	}, true
+}
+
+// clusterVHosts agrupa las respuestas parecidas entre sí: muchos nombres en un
+// mismo grupo suelen ser una redirección o un error común, no sitios distintos
+func (s *Scanner) clusterVHosts(found []domain.VHost, shapes []domain.PageSnapshot) {
+	var leaders []int
+	for i := range found {
+		cluster := -1
+		for c, leader := range leaders {
+			if s.originVerifier.Compare(shapes[leader], shapes[i]) >= vhostSameScore {
+				cluster = c
+				break
+			}
+		}
+		if cluster < 0 {
+			cluster = len(leaders)
+			leaders = append(leaders, i)
+		}
+		found[i].Cluster = cluster + 1
+	}
+}
+
+// vhostTargets retorna los orígenes confirmados: veredicto confirmed (en su
+// puerto), certificado que nombra al dominio o vecino del barrido
+func vhostTargets(origins []domain.OriginCheck, certs []domain.CertCheck, sweep []domain.SweepHit) []vhostTarget {
+	seen := make(map[vhostTarget]bool)
+	var targets []vhostTarget
+	add := func(target vhostTarget) {
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	for _, check := range origins {
+		if check.Verdict == domain.OriginConfirmed {
+			add(vhostTarget{ip: check.IP, port: check.Port})
+		}
+	}
+	for _, check := range certs {
+		if check.Match != "" {
+			add(vhostTarget{ip: check.IP})
+		}
+	}
+	for _, hit := range sweep {
+		add(vhostTarget{ip: hit.Cert.IP})
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].ip != targets[j].ip {
+			return targets[i].ip < targets[j].ip
+		}
+		return targets[i].port < targets[j].port
+	})
+	return targets
+}
+
+// vhostNames arma los nombres a probar: el dominio, los FQDNs del escaneo y
+// <palabra>.<dominio> del wordlist, limitados a config.VHostMax
+func vhostNames(config domain.ScannerConfig, answers map[string][]domain.ResultEntry, subdomains []string) []string {
+	seen := map[string]bool{config.Domain: true}
+	names := []string{config.Domain}
+
+	fqdns := make([]string, 0, len(answers))
+	for fqdn := range answers {
+		fqdns = append(fqdns, fqdn)
+	}
+	sort.Strings(fqdns)
+	for _, word := range subdomains {
+		if word = strings.TrimSpace(word); word != "" {
+			fqdns = append(fqdns, word+"."+config.Domain)
+		}
+	}
+
+	for _, name := range fqdns {
+		if config.VHostMax > 0 && len(names) >= config.VHostMax {
+			break
+		}
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// resolvesTo indica si el escaneo encontró que name resuelve a ip
+func resolvesTo(answers map[string][]domain.ResultEntry, name, ip string) bool {
+	for _, entry := range answers[name] {
+		if entry.IP == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// randomLabel genera una etiqueta DNS que no debería existir
+func randomLabel() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return "cloudrip-" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/httpprobe"
+	"github.com/rs/zerolog"
+)
+
+const vhostPage = `<!doctype html><html><head><title>%s</title></head><body>%s</body></html>`
+
+// vhostOrigin sirve app y admin (mirror es un alias de app) y la página por
+// defecto a cualquier otro Host
+func vhostOrigin(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	switch host {
+	case "app.example.com", "mirror.example.com":
+		fmt.Fprintf(w, vhostPage, "Example App", "<h1>Dashboard</h1><p>Orders shipped today, pending invoices and customer tickets</p>")
+	case "admin.example.com":
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprintf(w, vhostPage, "Admin login", "<form>Username password remember me sign in</form>")
+	default:
+		fmt.Fprintf(w, vhostPage, "Welcome to nginx!", "<h1>Welcome to nginx!</h1><p>If you see this page, the nginx web server is successfully installed</p>")
+	}
+}
+
+// vhostServer levanta un origen TLS y retorna su IP y puerto
+func vhostServer(t *testing.T, handler http.HandlerFunc) (string, int) {
+	t.Helper()
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	host, portStr, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host, port
+}
+
+func newVHostScanner() *Scanner {
+	scanner := newTestScanner(&fakeResolver{}, nil)
+	WithOriginVerifier(httpprobe.NewProber(nil, 5*time.Second, zerolog.Nop()))(scanner)
+	return scanner
+}
+
+func TestDiscoverVHosts(t *testing.T) {
+	ip, port := vhostServer(t, vhostOrigin)
+	scanner := newVHostScanner()
+
+	config := domain.ScannerConfig{Domain: "example.com", Threads: 4}
+	answers := map[string][]domain.ResultEntry{
+		"app.example.com": {{IP: ip}},
+		"www.example.com": {{IP: ip}},
+	}
+	origins := []domain.OriginCheck{{IP: ip, Port: port, Verdict: domain.OriginConfirmed}}
+	checks := scanner.discoverVHosts(context.Background(), config, answers, []string{"admin", "mirror", "www", "blog"}, origins, nil, nil)
+	if len(checks) != 1 {
+		t.Fatalf("%d orígenes, esperado 1", len(checks))
+	}
+	check := checks[0]
+	if check.IP != ip || check.Port != port || check.Status != http.StatusOK || check.Title != "Welcome to nginx!" {
+		t.Errorf("referencia = %s:%d %d %q", check.IP, check.Port, check.Status, check.Title)
+	}
+
+	// example.com, www y blog responden como un host aleatorio y se
+	// descartan; mirror cae en el grupo de app
+	want := []domain.VHost{
+		{Name: "admin.example.com", Status: http.StatusUnauthorized, Title: "Admin login", Cluster: 1, Hidden: true},
+		{Name: "app.example.com", Status: http.StatusOK, Title: "Example App", Cluster: 2},
+		{Name: "mirror.example.com", Status: http.StatusOK, Title: "Example App", Cluster: 2, Hidden: true},
+	}
+	if len(check.VHosts) != len(want) {
+		t.Fatalf("VHosts = %+v, esperado %+v", check.VHosts, want)
+	}
+	for i, got := range check.VHosts {
+		if got.Baseline >= vhostSameScore {
+			t.Errorf("%s: baseline = %.3f, esperado < %.1f", got.Name, got.Baseline, vhostSameScore)
+		}
+		got.Baseline = 0
+		if got != want[i] {
+			t.Errorf("VHosts[%d] = %+v, esperado %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestDiscoverVHostsCatchAll(t *testing.T) {
+	// Un origen que responde lo mismo a cualquier Host no tiene virtual hosts
+	catchAll := func(w http.ResponseWriter, r *http.Request) {
+		r.Host = "default"
+		vhostOrigin(w, r)
+	}
+	ip, port := vhostServer(t, catchAll)
+	scanner := newVHostScanner()
+
+	config := domain.ScannerConfig{Domain: "example.com", Threads: 4}
+	origins := []domain.OriginCheck{{IP: ip, Port: port, Verdict: domain.OriginConfirmed}}
+	checks := scanner.discoverVHosts(context.Background(), config, nil, []string{"admin", "app"}, origins, nil, nil)
+	if len(checks) != 1 || len(checks[0].VHosts) != 0 {
+		t.Errorf("checks = %+v, esperado un origen sin virtual hosts", checks)
+	}
+
+	// Sin respuesta a los hosts aleatorios el origen se omite
+	closed := httptest.NewServer(http.HandlerFunc(vhostOrigin))
+	addr := closed.Listener.Addr().(*net.TCPAddr)
+	closed.Close()
+	origins = []domain.OriginCheck{{IP: addr.IP.String(), Port: addr.Port, Verdict: domain.OriginConfirmed}}
+	if checks := scanner.discoverVHosts(context.Background(), config, nil, []string{"app"}, origins, nil, nil); len(checks) != 0 {
+		t.Errorf("origen cerrado: checks = %+v", checks)
+	}
+}
+
+func TestVHostNames(t *testing.T) {
+	answers := map[string][]domain.ResultEntry{
+		"www.example.com": nil,
+		"api.example.com": nil,
+	}
+	subdomains := []string{"admin", " ", "www", "dev"}
+
+	tests := []struct {
+		max  int
+		want []string
+	}{
+		// El dominio primero, luego los FQDNs del escaneo y el wordlist sin repetir
+		{0, []string{"example.com", "api.example.com", "www.example.com", "admin.example.com", "dev.example.com"}},
+		{3, []string{"example.com", "api.example.com", "www.example.com"}},
+		{1, []string{"example.com"}},
+	}
+	for _, tt := range tests {
+		config := domain.ScannerConfig{Domain: "example.com", VHostMax: tt.max}
+		if got := vhostNames(config, answers, subdomains); !slices.Equal(got, tt.want) {
+			t.Errorf("vhostNames(max %d) = %v, esperado %v", tt.max, got, tt.want)
+		}
+	}
+}
@@ -2,7 +2,11 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/netip"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,15 +17,13 @@ import (
 type workerPool struct {
 	scanner *Scanner
 	config  domain.ScannerConfig
-	ranges  domain.CFRanges
 	logger  zerolog.Logger
 }
 
-func (s *Scanner) startWorkers(ctx context.Context, config domain.ScannerConfig, subs []string, ranges domain.CFRanges) map[string][]domain.ResultEntry {
+func (s *Scanner) startWorkers(ctx context.Context, config domain.ScannerConfig, subs []string) map[string][]domain.ResultEntry {
 	pool := &workerPool{
 		scanner: s,
 		config:  config,
-		ranges:  ranges,
 		logger:  s.logger.With().Str("component", "worker_pool").Logger(),
 	}
 
@@ -49,9 +51,6 @@ func (wp *workerPool) execute(ctx context.Context, subs []string) map[string][]d
 		defer collectWg.Done()
 		for result := range results {
 			collector.Collect(result)
-			if wp.scanner.progressReporter != nil {
-				wp.scanner.progressReporter.Increment()
-			}
 		}
 	}()
 
@@ -98,6 +97,11 @@ func (wp *workerPool) worker(ctx context.Context, wg *sync.WaitGroup, id int, jo
 				wp.scanner.metricsCollector.IncrementSuccess()
 			}
 			wp.scanner.metricsCollector.RecordWorkerActivity(id)
+			// El progreso cuenta subdominios, no respuestas: un host con
+			// varias IPs avanza una sola vez
+			if wp.scanner.progressReporter != nil {
+				wp.scanner.progressReporter.Increment()
+			}
 		}
 	}
 
@@ -116,11 +120,11 @@ func (wp *workerPool) processJob(ctx context.Context, job domain.Job, results ch
 	fqdn := wp.buildFQDN(job.Subdomain)
 
 	// Resolver IPs
-	ips, err := wp.scanner.dnsResolver.LookupIP(ctx, fqdn)
+	ips, err := wp.lookupIP(ctx, fqdn)
 	if err != nil {
 		wp.logger.Debug().Err(err).Str("fqdn", fqdn).Msg("Error en lookup IP")
 	} else if len(ips) > 0 {
-		wp.processIPs(fqdn, ips, results)
+		wp.processIPs(fqdn, ips, cnameHint{}, results)
 	}
 
 	// Seguir CNAME si está habilitado
@@ -138,40 +142,247 @@ func (wp *workerPool) buildFQDN(subdomain string) string {
 	return fmt.Sprintf("%s.%s", subdomain, wp.config.Domain)
 }
 
-func (wp *workerPool) processIPs(fqdn string, ips []string, results chan<- domain.ResultEntry) {
+// cnameHint es lo que se sabe del host por el destino de su CNAME
+type cnameHint struct {
+	target   string
+	provider string // proveedor por sufijo del destino
+	product  string // producto de Cloudflare por sufijo del destino
+}
+
+// processIPs clasifica cada IP por proveedor; todas las respuestas se
+// conservan y el filtrado ocurre después (ver applyPolicy)
+func (wp *workerPool) processIPs(fqdn string, ips []string, hint cnameHint, results chan<- domain.ResultEntry) {
 	for _, ip := range ips {
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			wp.logger.Debug().Err(err).Str("ip", ip).Msg("IP inválida en respuesta")
+			continue
+		}
+		addr = addr.Unmap()
+		if !domain.AllowsFamily(wp.config.IPFamily, addr) {
+			continue
+		}
+
 		ipType := "A"
-		if len(ip) > 16 { // IPv6 básico check
+		if addr.Is6() {
 			ipType = "AAAA"
 		}
+		ip = addr.String()
 
-		isCF := wp.scanner.cloudflareService.IsCloudflareIP(ip, wp.ranges)
-		if !isCF || wp.config.IncludeCF {
-			results <- domain.ResultEntry{
-				FQDN: fqdn,
-				IP:   ip,
-				Type: ipType,
+		// Solo los rangos marcan una IP como protegida; un CNAME hacia un CDN
+		// cuyo destino resuelve fuera de sus rangos queda como pista
+		provider, prefix, _ := wp.scanner.providerRegistry.ClassifyIP(ip)
+
+		entry := domain.ResultEntry{
+			FQDN:          fqdn,
+			IP:            ip,
+			Type:          ipType,
+			Provider:      provider,
+			Prefix:        prefix,
+			Proxied:       provider != "",
+			CNAME:         hint.target,
+			CNAMEProvider: hint.provider,
+			Product:       hint.product,
+		}
+		// Solo se atribuye a un cloud lo que no es un borde CDN
+		if provider == "" && wp.scanner.cloudClassifier != nil {
+			if info, ok := wp.scanner.cloudClassifier.ClassifyIP(ip); ok {
+				entry.Cloud = &info
 			}
-			wp.logger.Debug().
-				Str("fqdn", fqdn).
-				Str("ip", ip).
-				Str("type", ipType).
-				Bool("cloudflare", isCF).
-				Msg("Resultado encontrado")
 		}
+
+		results <- entry
+		wp.logger.Debug().
+			Str("fqdn", fqdn).
+			Str("ip", ip).
+			Str("type", ipType).
+			Str("provider", provider).
+			Str("prefix", prefix).
+			Str("cname_provider", hint.provider).
+			Msg("Resultado encontrado")
 	}
 }
 
+// processCNAME sigue un nivel de CNAME. Algunos productos de Cloudflare solo
+// se ven en el destino (tunnel, pages, workers, partial CNAME, SaaS); un
+// tunnel no tiene IP de origen, así que se reporta aunque el destino no
+// resuelva.
 func (wp *workerPool) processCNAME(ctx context.Context, fqdn string, results chan<- domain.ResultEntry) {
-	target, err := wp.scanner.dnsResolver.LookupCNAME(ctx, fqdn)
+	target, err := wp.lookupCNAME(ctx, fqdn)
 	if err != nil || target == "" {
 		return
 	}
+	target = strings.TrimSuffix(strings.ToLower(target), ".")
+	if target == strings.ToLower(fqdn) {
+		return
+	}
+
+	hint := cnameHint{target: target}
+	hint.provider, _ = wp.scanner.providerRegistry.ClassifyCNAME(target)
+	if product, ok := wp.scanner.cloudflareService.ProductFromCNAME(target); ok {
+		hint.product = product
+		hint.provider = domain.ProviderCloudflare
+	}
 
-	ips, err := wp.scanner.dnsResolver.LookupIP(ctx, target)
+	ips, err := wp.lookupIP(ctx, target)
+	if hint.product == domain.CFProductTunnel || (hint.product != "" && (err != nil || len(ips) == 0)) {
+		wp.emitCNAME(fqdn, hint, results)
+		return
+	}
 	if err != nil || len(ips) == 0 {
 		return
 	}
 
-	wp.processIPs(fqdn, ips, results)
+	if hint.product == "" && wp.looksLikeSaaS(target, ips) {
+		hint.product = domain.CFProductSaaS
+		hint.provider = domain.ProviderCloudflare
+	}
+
+	wp.processIPs(fqdn, ips, hint, results)
+}
+
+// looksLikeSaaS detecta un custom hostname de Cloudflare for SaaS: un CNAME
+// hacia la zona de un tercero (ni el dominio escaneado ni *.cloudflare.*)
+// cuyo destino resuelve solo a rangos de Cloudflare. Cloudflare rechaza los
+// CNAME entre cuentas (error 1014) salvo para custom hostnames.
+func (wp *workerPool) looksLikeSaaS(target string, ips []string) bool {
+	domainName := strings.ToLower(wp.config.Domain)
+	if target == domainName || strings.HasSuffix(target, "."+domainName) || isCloudflareZone(target) {
+		return false
+	}
+	for _, ip := range ips {
+		if provider, _, _ := wp.scanner.providerRegistry.ClassifyIP(ip); provider != domain.ProviderCloudflare {
+			return false
+		}
+	}
+	return true
+}
+
+// isCloudflareZone indica si name pertenece a una zona propia de Cloudflare
+// (cloudflare.com, cloudflare.net...), cuyos hosts no son de un cliente
+func isCloudflareZone(name string) bool {
+	labels := strings.Split(name, ".")
+	return len(labels) >= 2 && labels[len(labels)-2] == "cloudflare"
+}
+
+// emitCNAME reporta un host de Cloudflare sin IPs que clasificar
+func (wp *workerPool) emitCNAME(fqdn string, hint cnameHint, results chan<- domain.ResultEntry) {
+	results <- domain.ResultEntry{
+		FQDN:          fqdn,
+		Type:          "CNAME",
+		Provider:      hint.provider,
+		Proxied:       true,
+		CNAME:         hint.target,
+		CNAMEProvider: hint.provider,
+		Product:       hint.product,
+	}
+	wp.logger.Debug().
+		Str("fqdn", fqdn).
+		Str("cname", hint.target).
+		Str("product", hint.product).
+		Msg("Producto Cloudflare detectado por CNAME")
+}
+
+func (wp *workerPool) lookupIP(ctx context.Context, fqdn string) ([]string, error) {
+	var ips []string
+	err := wp.withRetry(ctx, fqdn, func(actx context.Context) error {
+		var err error
+		ips, err = wp.scanner.dnsResolver.LookupIP(actx, fqdn)
+		return err
+	})
+	return ips, err
+}
+
+func (wp *workerPool) lookupCNAME(ctx context.Context, fqdn string) (string, error) {
+	var target string
+	err := wp.withRetry(ctx, fqdn, func(actx context.Context) error {
+		var err error
+		target, err = wp.scanner.dnsResolver.LookupCNAME(actx, fqdn)
+		return err
+	})
+	return target, err
+}
+
+// withRetry aplica timeout por consulta y reintenta los errores transitorios
+// con backoff exponencial (-retries, -backoff, -timeout)
+func (wp *workerPool) withRetry(ctx context.Context, fqdn string, fn func(context.Context) error) error {
+	var err error
+	delay := wp.config.Backoff
+
+	for attempt := 0; attempt <= wp.config.Retries; attempt++ {
+		if attempt > 0 {
+			wp.scanner.metricsCollector.IncrementRetry()
+			wp.logger.Debug().Err(err).Str("fqdn", fqdn).Int("attempt", attempt).Msg("Reintentando resolución DNS")
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+				delay *= 2
+			}
+		}
+
+		actx, cancel := timeoutContext(ctx, wp.config.Timeout)
+		err = fn(actx)
+		cancel()
+
+		if err == nil || ctx.Err() != nil || !isRetryable(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// timeoutContext aplica un timeout por consulta; 0 o negativo es sin límite
+// (como -timeout 0)
+func timeoutContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// boundedGroup ejecuta tareas con a lo sumo workers goroutines a la vez y
+// deja de despacharlas cuando el contexto se cancela
+type boundedGroup struct {
+	ctx context.Context
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+// newBoundedGroup crea el grupo; workers menor a 1 se toma como 1
+func newBoundedGroup(ctx context.Context, workers int) *boundedGroup {
+	return &boundedGroup{ctx: ctx, sem: make(chan struct{}, max(workers, 1))}
+}
+
+// Go espera un lugar libre y ejecuta fn en una goroutine. Retorna false sin
+// ejecutarla si el contexto se canceló mientras esperaba.
+func (g *boundedGroup) Go(fn func()) bool {
+	g.sem <- struct{}{}
+	if g.ctx.Err() != nil {
+		<-g.sem
+		return false
+	}
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer func() { <-g.sem }()
+		fn()
+	}()
+	return true
+}
+
+// Wait espera a que terminen las tareas despachadas
+func (g *boundedGroup) Wait() {
+	g.wg.Wait()
+}
+
+// isRetryable indica si un error DNS es transitorio (timeout, SERVFAIL)
+func isRetryable(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	return errors.Is(err, context.DeadlineExceeded)
 }
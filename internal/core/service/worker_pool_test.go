@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+func TestCNAMEHintDoesNotMarkProxied(t *testing.T) {
+	resolver := &fakeResolver{
+		ips: map[string][]string{
+			"www.example.com":         {"192.0.2.10"},
+			"edge.fastly.example.net": {"151.101.1.1", "192.0.2.10"},
+		},
+		cnames: map[string]string{"www.example.com": "edge.fastly.example.net"},
+	}
+	registry := &fakeRegistry{
+		prefixes: map[string]string{"151.101.0.0/16": "fastly"},
+		suffixes: map[string]string{"fastly.example.net": "fastly"},
+	}
+	scanner := newTestScanner(resolver, registry)
+
+	config := domain.ScannerConfig{Domain: "example.com", Threads: 1, FollowCNAME: true}
+	answers := scanner.startWorkers(context.Background(), config, []string{"www"})
+
+	byIP := make(map[string]domain.ResultEntry)
+	for _, entry := range answers["www.example.com"] {
+		byIP[entry.IP] = entry
+	}
+	if len(byIP) != 2 {
+		t.Fatalf("respuestas = %+v", answers["www.example.com"])
+	}
+
+	// Dentro de los rangos: protegida por el proveedor
+	edge := byIP["151.101.1.1"]
+	if edge.Provider != "fastly" || !edge.Proxied || edge.Prefix != "151.101.0.0/16" {
+		t.Errorf("151.101.1.1 = %+v, esperado fastly proxied", edge)
+	}
+	// Fuera de todo rango: expuesta aunque el CNAME apunte al CDN; la
+	// resolución directa y la del CNAME se combinan en una entrada
+	origin := byIP["192.0.2.10"]
+	if origin.Provider != "" || origin.Proxied {
+		t.Errorf("192.0.2.10 = %+v, no debería marcarse como protegida", origin)
+	}
+	if origin.CNAME != "edge.fastly.example.net" || origin.CNAMEProvider != "fastly" {
+		t.Errorf("192.0.2.10 pista CNAME = %q/%q", origin.CNAME, origin.CNAMEProvider)
+	}
+
+	if summary := summarizeHost("www.example.com", answers["www.example.com"]); summary.Status != domain.HostMixed {
+		t.Errorf("estado = %q, esperado %q", summary.Status, domain.HostMixed)
+	}
+}
+
+// countingProgress cuenta los avances reportados
+type countingProgress struct {
+	mu    sync.Mutex
+	total int
+	done  int
+}
+
+func (p *countingProgress) Start(total int) { p.total = total }
+func (p *countingProgress) Stop()           {}
+
+func (p *countingProgress) Increment() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+}
+
+func (p *countingProgress) GetProgress() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return float64(p.done) / float64(p.total) * 100
+}
+
+func TestProgressCountsJobs(t *testing.T) {
+	resolver := &fakeResolver{
+		ips: map[string][]string{
+			"www.example.com":  {"192.0.2.1", "192.0.2.2", "2001:db8::1"},
+			"edge.example.net": {"192.0.2.3", "192.0.2.4"},
+		},
+		cnames: map[string]string{"www.example.com": "edge.example.net"},
+	}
+	scanner := newTestScanner(resolver, nil)
+	progress := &countingProgress{}
+	scanner.progressReporter = progress
+
+	subs := []string{"www", "missing", "other"}
+	progress.Start(len(subs))
+	config := domain.ScannerConfig{Domain: "example.com", Threads: 2, FollowCNAME: true}
+	answers := scanner.startWorkers(context.Background(), config, subs)
+
+	if len(answers["www.example.com"]) < 3 {
+		t.Fatalf("respuestas = %+v", answers)
+	}
+	// Cinco respuestas para www, pero un avance por subdominio
+	if progress.done != len(subs) || progress.GetProgress() != 100 {
+		t.Errorf("progreso = %d (%.0f%%), esperado %d", progress.done, progress.GetProgress(), len(subs))
+	}
+}
+
+func TestCloudflareProductsByCNAME(t *testing.T) {
+	resolver := &fakeResolver{
+		ips: map[string][]string{
+			"app.example.com":  {"104.16.1.1"},
+			"docs.example.com": {"104.16.2.2"},
+			"site.example.net": {"104.16.3.3"},
+		},
+		cnames: map[string]string{
+			// El destino de un tunnel no resuelve
+			"tunnel.example.com": "6ff42ae2-765d-4adf-8112-31c55c1551ef.cfargotunnel.com.",
+			"docs.example.com":   "docs.pages.dev",
+			// CNAME entre zonas hacia Cloudflare: custom hostname SaaS
+			"shop.example.com": "site.example.net",
+		},
+	}
+	registry := &fakeRegistry{prefixes: map[string]string{"104.16.0.0/13": domain.ProviderCloudflare}}
+	scanner := newTestScanner(resolver, registry)
+
+	config := domain.ScannerConfig{Domain: "example.com", Threads: 2, FollowCNAME: true}
+	answers := scanner.startWorkers(context.Background(), config, []string{"tunnel", "docs", "shop", "app"})
+
+	tunnel := answers["tunnel.example.com"]
+	if len(tunnel) != 1 {
+		t.Fatalf("tunnel = %+v, esperado una entrada CNAME", tunnel)
+	}
+	if entry := tunnel[0]; entry.Type != "CNAME" || entry.Product != domain.CFProductTunnel || !entry.Proxied || entry.IP != "" ||
+		entry.CNAME != "6ff42ae2-765d-4adf-8112-31c55c1551ef.cfargotunnel.com" {
+		t.Errorf("tunnel = %+v", entry)
+	}
+	summary := summarizeHost("tunnel.example.com", tunnel)
+	if summary.Status != domain.HostProxied || summary.Note == "" {
+		t.Errorf("resumen tunnel = %+v", summary)
+	}
+	// Un host solo con tunnel se reporta como protegido aun con la política
+	// por defecto (solo expuestas): el resumen lo incluye
+	if _, hosts := applyPolicy(answers, config); len(hosts) != 4 {
+		t.Errorf("hosts = %+v", hosts)
+	}
+
+	// Pages sin IPs en el destino se reporta por el CNAME, junto a la IP directa
+	if summary := summarizeHost("docs.example.com", answers["docs.example.com"]); summary.Total != 2 ||
+		len(summary.Products) != 1 || summary.Products[0] != domain.CFProductPages {
+		t.Errorf("resumen docs = %+v", summary)
+	}
+	for _, entry := range answers["shop.example.com"] {
+		if entry.Product != domain.CFProductSaaS || entry.Provider != domain.ProviderCloudflare {
+			t.Errorf("shop = %+v, esperado producto saas", entry)
+		}
+	}
+	for _, entry := range answers["app.example.com"] {
+		if entry.Product != "" || entry.CNAME != "" {
+			t.Errorf("app = %+v", entry)
+		}
+	}
+}
+
+func TestSaaSHeuristic(t *testing.T) {
+	resolver := &fakeResolver{
+		ips: map[string][]string{
+			"example.com":                         {"104.16.1.1"},
+			"site.saas-vendor.net":                {"104.16.3.3", "2606:4700::3"},
+			"mixed.saas-vendor.net":               {"104.16.3.4", "192.0.2.10"},
+			"origin.saas-vendor.net":              {"192.0.2.11"},
+			"www.example.com.cloudflare.net":      {"104.16.5.5"},
+			"status.cloudflare.com":               {"104.16.6.6"},
+			"api.example.com":                     {"104.16.7.7"},
+			"shop.example.com.cdn.cloudflare.net": {"104.16.8.8"},
+		},
+		cnames: map[string]string{
+			"custom.example.com": "site.saas-vendor.net.",
+			// Alguna IP fuera de Cloudflare: no es un custom hostname
+			"mixed.example.com":  "mixed.saas-vendor.net",
+			"origin.example.com": "origin.saas-vendor.net",
+			// Dentro de la zona escaneada
+			"www.example.com": "example.com",
+			"app.example.com": "api.example.com",
+			// Zonas propias de Cloudflare
+			"generic.example.com": "www.example.com.cloudflare.net",
+			"status.example.com":  "status.cloudflare.com",
+			"shop.example.com":    "shop.example.com.cdn.cloudflare.net",
+		},
+	}
+	registry := &fakeRegistry{prefixes: map[string]string{
+		"104.16.0.0/13":  domain.ProviderCloudflare,
+		"2606:4700::/32": domain.ProviderCloudflare,
+	}}
+	scanner := newTestScanner(resolver, registry)
+
+	config := domain.ScannerConfig{Domain: "example.com", Threads: 2, FollowCNAME: true}
+	want := map[string]string{
+		"custom":  domain.CFProductSaaS,
+		"mixed":   "",
+		"origin":  "",
+		"www":     "",
+		"app":     "",
+		"generic": "",
+		"status":  "",
+		"shop":    "",
+	}
+	subs := make([]string, 0, len(want))
+	for sub := range want {
+		subs = append(subs, sub)
+	}
+	answers := scanner.startWorkers(context.Background(), config, subs)
+
+	for sub, product := range want {
+		entries := answers[sub+".example.com"]
+		if len(entries) == 0 {
+			t.Errorf("%s: sin respuestas", sub)
+		}
+		for _, entry := range entries {
+			if entry.Product != product {
+				t.Errorf("%s: %s producto %q, esperado %q", sub, entry.IP, entry.Product, product)
+			}
+		}
+	}
+	for _, entry := range answers["custom.example.com"] {
+		if entry.CNAMEProvider != domain.ProviderCloudflare || !entry.Proxied {
+			t.Errorf("custom = %+v", entry)
+		}
+	}
+}
+
+func TestBoundedGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	group := newBoundedGroup(ctx, 2)
+
+	var (
+		mu              sync.Mutex
+		running, peak   int
+		release         = make(chan struct{})
+		ran, dispatched int
+	)
+	for range 5 {
+		if !group.Go(func() {
+			mu.Lock()
+			running++
+			peak = max(peak, running)
+			mu.Unlock()
+			<-release
+			mu.Lock()
+			running--
+			ran++
+			mu.Unlock()
+		}) {
+			break
+		}
+		dispatched++
+		if dispatched == 2 {
+			// Con los dos lugares ocupados, la siguiente tarea espera
+			close(release)
+		}
+	}
+	group.Wait()
+	if dispatched != 5 || ran != 5 || peak > 2 {
+		t.Errorf("despachadas %d, ejecutadas %d, máximo simultáneo %d", dispatched, ran, peak)
+	}
+
+	// Tras cancelar no se despachan más tareas
+	cancel()
+	if group.Go(func() { t.Error("tarea ejecutada tras cancelar") }) {
+		t.Error("Go retornó true tras cancelar")
+	}
+	group.Wait()
+
+	// workers menor a 1 se toma como 1
+	if single := newBoundedGroup(context.Background(), 0); cap(single.sem) != 1 {
+		t.Errorf("workers 0: capacidad %d", cap(single.sem))
+	}
+}
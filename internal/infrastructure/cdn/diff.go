@@ -0,0 +1,68 @@
+package cdn
+
+import (
+	"net/netip"
+	"sort"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// RangesDiff es la diferencia entre dos conjuntos de rangos
+type RangesDiff struct {
+	From      domain.RangeSource `json:"from"`
+	To        domain.RangeSource `json:"to"`
+	Added     []string           `json:"added"`
+	Removed   []string           `json:"removed"`
+	Unchanged int                `json:"unchanged"`
+}
+
+// Changed indica si hay prefijos agregados o eliminados
+func (d RangesDiff) Changed() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0
+}
+
+// Diff compara dos conjuntos de rangos. Los prefijos se normalizan, así que
+// 2606:4700:0::/32 y 2606:4700::/32 se consideran iguales.
+func Diff(from, to domain.CFRanges) RangesDiff {
+	before := prefixSet(from)
+	after := prefixSet(to)
+
+	diff := RangesDiff{From: from.Source, To: to.Source, Added: []string{}, Removed: []string{}}
+	for prefix := range after {
+		if before[prefix] {
+			diff.Unchanged++
+		} else {
+			diff.Added = append(diff.Added, prefix.String())
+		}
+	}
+	for prefix := range before {
+		if !after[prefix] {
+			diff.Removed = append(diff.Removed, prefix.String())
+		}
+	}
+
+	sortPrefixes(diff.Added)
+	sortPrefixes(diff.Removed)
+	return diff
+}
+
+func prefixSet(ranges domain.CFRanges) map[netip.Prefix]bool {
+	set := make(map[netip.Prefix]bool, len(ranges.IPv4)+len(ranges.IPv6))
+	for _, cidr := range append(append([]string(nil), ranges.IPv4...), ranges.IPv6...) {
+		if prefix, err := netip.ParsePrefix(cidr); err == nil {
+			set[prefix.Masked()] = true
+		}
+	}
+	return set
+}
+
+// sortPrefixes ordena IPv4 antes que IPv6 y luego por dirección y largo
+func sortPrefixes(cidrs []string) {
+	sort.Slice(cidrs, func(i, j int) bool {
+		a, b := netip.MustParsePrefix(cidrs[i]), netip.MustParsePrefix(cidrs[j])
+		if c := a.Addr().Compare(b.Addr()); c != 0 {
+			return c < 0
+		}
+		return a.Bits() < b.Bits()
+	})
+}
@@ -0,0 +1,57 @@
+package cdn
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+func TestDiff(t *testing.T) {
+	from := domain.CFRanges{
+		IPv4:   []string{"104.16.0.0/13", "103.21.244.0/22", "198.51.100.0/24", "no-es-un-cidr"},
+		IPv6:   []string{"2606:4700:0::/32", "2a06:98c0::/29"},
+		Source: domain.RangeSource{Provider: domain.ProviderCloudflare, Source: domain.RangesSourceCache},
+	}
+	to := domain.CFRanges{
+		// 104.16.0.1/13 se normaliza a 104.16.0.0/13
+		IPv4:   []string{"104.16.0.1/13", "103.21.244.0/22", "172.64.0.0/13", "104.24.0.0/14"},
+		IPv6:   []string{"2606:4700::/32", "2400:cb00::/32"},
+		Source: domain.RangeSource{Provider: domain.ProviderCloudflare, Source: domain.RangesSourceAPI},
+	}
+
+	diff := Diff(from, to)
+	if !diff.Changed() {
+		t.Error("Changed() = false")
+	}
+	// IPv4 antes que IPv6, luego por dirección
+	if want := []string{"104.24.0.0/14", "172.64.0.0/13", "2400:cb00::/32"}; !slices.Equal(diff.Added, want) {
+		t.Errorf("Added = %v, esperado %v", diff.Added, want)
+	}
+	if want := []string{"198.51.100.0/24", "2a06:98c0::/29"}; !slices.Equal(diff.Removed, want) {
+		t.Errorf("Removed = %v, esperado %v", diff.Removed, want)
+	}
+	if diff.Unchanged != 3 {
+		t.Errorf("Unchanged = %d, esperado 3", diff.Unchanged)
+	}
+	if diff.From.Source != domain.RangesSourceCache || diff.To.Source != domain.RangesSourceAPI {
+		t.Errorf("orígenes = %+v %+v", diff.From, diff.To)
+	}
+}
+
+func TestDiffUnchanged(t *testing.T) {
+	ranges := domain.CFRanges{IPv4: []string{"104.16.0.0/13"}, IPv6: []string{"2606:4700::/32"}}
+	diff := Diff(ranges, ranges)
+	if diff.Changed() || diff.Unchanged != 2 {
+		t.Errorf("Diff = %+v", diff)
+	}
+	// Las listas vacías se serializan como [] y no como null
+	if diff.Added == nil || diff.Removed == nil {
+		t.Error("Added/Removed no deberían ser nil")
+	}
+
+	empty := Diff(domain.CFRanges{}, domain.CFRanges{})
+	if empty.Changed() || empty.Unchanged != 0 {
+		t.Errorf("Diff vacío = %+v", empty)
+	}
+}
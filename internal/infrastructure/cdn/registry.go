@@ -0,0 +1,228 @@
+package cdn
+
+import (
+	_ "embed"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/iptrie"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed data/providers.yaml
+var bundledProviders []byte
+
+type providersFile struct {
+	Providers []domain.CDNProvider `yaml:"providers"`
+}
+
+// Registry mantiene los proveedores CDN/WAF conocidos. Las escrituras
+// recompilan un trie inmutable; las lecturas no toman locks.
+type Registry struct {
+	mu        sync.Mutex
+	providers map[string]domain.CDNProvider
+	compiled  atomic.Pointer[compiled]
+	logger    zerolog.Logger
+}
+
+type compiled struct {
+	trie     *iptrie.Trie[string]
+	suffixes []suffixRule
+}
+
+type suffixRule struct {
+	suffix   string
+	provider string
+}
+
+// NewRegistry crea un registro con los proveedores incluidos en el binario
+func NewRegistry(logger zerolog.Logger) (*Registry, error) {
+	r := &Registry{
+		providers: make(map[string]domain.CDNProvider),
+		logger:    logger,
+	}
+
+	if err := r.load(bundledProviders, "bundled"); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// BundledRanges retorna los rangos de un proveedor incluidos en el binario,
+// sin depender de un registro (ej: respaldo de los rangos de Cloudflare)
+func BundledRanges(name string) (domain.CFRanges, bool) {
+	var file providersFile
+	if err := yaml.Unmarshal(bundledProviders, &file); err != nil {
+		return domain.CFRanges{}, false
+	}
+	for _, p := range file.Providers {
+		if strings.EqualFold(p.Name, name) {
+			return domain.CFRanges{
+				IPv4: p.IPv4,
+				IPv6: p.IPv6,
+				Source: domain.RangeSource{
+					Provider: strings.ToLower(p.Name),
+					Source:   domain.RangesSourceRegistry,
+				},
+			}, true
+		}
+	}
+	return domain.CFRanges{}, false
+}
+
+// LoadFile agrega los proveedores de un archivo YAML/JSON. Un proveedor con
+// el mismo nombre que uno existente lo reemplaza.
+func (r *Registry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("leyendo archivo de proveedores: %w", err)
+	}
+	return r.load(data, path)
+}
+
+func (r *Registry) load(data []byte, source string) error {
+	var file providersFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parseando proveedores (%s): %w", source, err)
+	}
+
+	for _, p := range file.Providers {
+		if p.Name == "" {
+			return fmt.Errorf("proveedor sin nombre en %s", source)
+		}
+		r.Register(p)
+	}
+
+	r.logger.Debug().Str("source", source).Int("providers", len(file.Providers)).Msg("Proveedores CDN cargados")
+	return nil
+}
+
+// Register agrega o reemplaza un proveedor
+func (r *Registry) Register(provider domain.CDNProvider) {
+	provider.Name = strings.ToLower(provider.Name)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.providers[provider.Name] = provider
+	r.rebuild()
+}
+
+// SetRanges reemplaza solo los rangos IP de un proveedor, conservando sus
+// sufijos CNAME (ej: rangos de Cloudflare obtenidos desde su API)
+func (r *Registry) SetRanges(name string, ranges domain.CFRanges) {
+	name = strings.ToLower(name)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	provider := r.providers[name]
+	provider.Name = name
+	provider.IPv4 = ranges.IPv4
+	provider.IPv6 = ranges.IPv6
+	r.providers[name] = provider
+	r.rebuild()
+}
+
+// rebuild debe llamarse con r.mu tomado
+func (r *Registry) rebuild() {
+	builder := iptrie.NewBuilder[string]()
+	var suffixes []suffixRule
+
+	for _, name := range r.sortedNames() {
+		p := r.providers[name]
+		for _, cidr := range append(append([]string(nil), p.IPv4...), p.IPv6...) {
+			prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+			if err != nil {
+				r.logger.Debug().Err(err).Str("provider", name).Str("cidr", cidr).Msg("Error parseando CIDR")
+				continue
+			}
+			builder.Insert(prefix, name)
+		}
+		for _, suffix := range p.CNAMESuffixes {
+			suffix = strings.Trim(strings.ToLower(suffix), ".")
+			if suffix != "" {
+				suffixes = append(suffixes, suffixRule{suffix: suffix, provider: name})
+			}
+		}
+	}
+
+	// Sufijos más largos primero para que gane el más específico
+	sort.SliceStable(suffixes, func(i, j int) bool {
+		return len(suffixes[i].suffix) > len(suffixes[j].suffix)
+	})
+
+	r.compiled.Store(&compiled{trie: builder.Build(), suffixes: suffixes})
+}
+
+func (r *Registry) sortedNames() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ClassifyIP retorna el proveedor y el prefijo que contienen la IP
+func (r *Registry) ClassifyIP(ip string) (string, string, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", "", false
+	}
+
+	prefix, provider, ok := r.compiled.Load().trie.Lookup(addr)
+	if !ok {
+		return "", "", false
+	}
+	return provider, prefix.String(), true
+}
+
+// ClassifyCNAME retorna el proveedor cuyo sufijo coincide con el destino CNAME
+func (r *Registry) ClassifyCNAME(target string) (string, bool) {
+	target = strings.TrimSuffix(strings.ToLower(target), ".")
+	for _, rule := range r.compiled.Load().suffixes {
+		if target == rule.suffix || strings.HasSuffix(target, "."+rule.suffix) {
+			return rule.provider, true
+		}
+	}
+	return "", false
+}
+
+// Providers retorna los nombres de los proveedores registrados
+func (r *Registry) Providers() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sortedNames()
+}
+
+// Provider retorna la definición de un proveedor
+func (r *Registry) Provider(name string) (domain.CDNProvider, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	p, ok := r.providers[strings.ToLower(name)]
+	return p, ok
+}
+
+// Ranges retorna los rangos de un proveedor con el formato de CFRanges
+func (r *Registry) Ranges(name string) (domain.CFRanges, bool) {
+	p, ok := r.Provider(name)
+	if !ok {
+		return domain.CFRanges{}, false
+	}
+	return domain.CFRanges{
+		IPv4: p.IPv4,
+		IPv6: p.IPv6,
+		Source: domain.RangeSource{
+			Provider: p.Name,
+			Source:   domain.RangesSourceRegistry,
+		},
+	}, true
+}
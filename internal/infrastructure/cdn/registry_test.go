@@ -0,0 +1,174 @@
+package cdn
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/rs/zerolog"
+)
+
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	registry, err := NewRegistry(zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+func TestClassifyIPPrecedence(t *testing.T) {
+	registry := newTestRegistry(t)
+	registry.Register(domain.CDNProvider{Name: "Wide", IPv4: []string{"198.51.100.0/24"}, IPv6: []string{"2001:db8::/32"}})
+	registry.Register(domain.CDNProvider{Name: "narrow", IPv4: []string{"198.51.100.128/25", "no-es-un-cidr"}, IPv6: []string{"2001:db8:1::/48"}})
+
+	tests := []struct {
+		ip, provider, prefix string
+		ok                   bool
+	}{
+		// El prefijo más específico gana aunque sea de otro proveedor
+		{"198.51.100.200", "narrow", "198.51.100.128/25", true},
+		{"198.51.100.10", "wide", "198.51.100.0/24", true},
+		{"2001:db8:1::1", "narrow", "2001:db8:1::/48", true},
+		{"2001:db8:2::1", "wide", "2001:db8::/32", true},
+		{"::ffff:198.51.100.10", "wide", "198.51.100.0/24", true},
+		{"104.16.132.229", domain.ProviderCloudflare, "104.16.0.0/13", true},
+		{"192.0.2.1", "", "", false},
+		{"no-es-una-ip", "", "", false},
+	}
+	for _, tt := range tests {
+		provider, prefix, ok := registry.ClassifyIP(tt.ip)
+		if provider != tt.provider || prefix != tt.prefix || ok != tt.ok {
+			t.Errorf("ClassifyIP(%s) = %q %q %v, esperado %q %q %v", tt.ip, provider, prefix, ok, tt.provider, tt.prefix, tt.ok)
+		}
+	}
+}
+
+func TestClassifyCNAMEPrecedence(t *testing.T) {
+	registry := newTestRegistry(t)
+	registry.Register(domain.CDNProvider{Name: "generic", CNAMESuffixes: []string{"cdn.example.net"}})
+	registry.Register(domain.CDNProvider{Name: "edge", CNAMESuffixes: []string{".Edge.CDN.example.net."}})
+
+	tests := []struct {
+		target, provider string
+	}{
+		// El sufijo más largo gana
+		{"site.edge.cdn.example.net", "edge"},
+		{"edge.cdn.example.net.", "edge"},
+		{"site.cdn.example.net", "generic"},
+		// Solo coinciden etiquetas completas
+		{"site.mycdn.example.net", ""},
+		{"example-docs.pages.dev", domain.ProviderCloudflare},
+		{"d111111abcdef8.cloudfront.net", "cloudfront"},
+		// Dominios demasiado genéricos para atribuir un CDN
+		{"e1234.a.akamaitechnologies.com", ""},
+		{"app.trafficmanager.net", ""},
+		{"dash.cloudflare.com", ""},
+	}
+	for _, tt := range tests {
+		provider, ok := registry.ClassifyCNAME(tt.target)
+		if provider != tt.provider || ok != (tt.provider != "") {
+			t.Errorf("ClassifyCNAME(%s) = %q %v, esperado %q", tt.target, provider, ok, tt.provider)
+		}
+	}
+}
+
+func TestSetRanges(t *testing.T) {
+	registry := newTestRegistry(t)
+	registry.SetRanges("Cloudflare", domain.CFRanges{IPv4: []string{"192.0.2.0/24"}})
+
+	if provider, _, _ := registry.ClassifyIP("192.0.2.1"); provider != domain.ProviderCloudflare {
+		t.Errorf("el rango nuevo no se aplicó: %q", provider)
+	}
+	if provider, _, ok := registry.ClassifyIP("104.16.132.229"); ok {
+		t.Errorf("el rango anterior debería reemplazarse: %q", provider)
+	}
+	// Los sufijos CNAME se conservan
+	if provider, _ := registry.ClassifyCNAME("example-docs.pages.dev"); provider != domain.ProviderCloudflare {
+		t.Errorf("se perdieron los sufijos CNAME: %q", provider)
+	}
+
+	ranges, ok := registry.Ranges(domain.ProviderCloudflare)
+	if !ok || !slices.Equal(ranges.IPv4, []string{"192.0.2.0/24"}) || len(ranges.IPv6) != 0 {
+		t.Errorf("Ranges = %+v %v", ranges, ok)
+	}
+	if ranges.Source.Source != domain.RangesSourceRegistry {
+		t.Errorf("source = %q", ranges.Source.Source)
+	}
+
+	// Un proveedor desconocido se crea solo con sus rangos
+	registry.SetRanges("nuevo", domain.CFRanges{IPv6: []string{"2001:db8::/32"}})
+	if provider, _, _ := registry.ClassifyIP("2001:db8::1"); provider != "nuevo" {
+		t.Errorf("ClassifyIP con proveedor nuevo = %q", provider)
+	}
+}
+
+func TestLoadFileOverrides(t *testing.T) {
+	registry := newTestRegistry(t)
+	bundled := registry.Providers()
+
+	path := filepath.Join(t.TempDir(), "providers.yaml")
+	data := `providers:
+  - name: Fastly
+    ipv4: [192.0.2.0/24]
+    cname_suffixes: [fastly-custom.example]
+  - name: acme-waf
+    ipv6: ["2001:db8:a::/48"]
+    cname_suffixes: [waf.acme.example]
+`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := registry.Providers(); len(got) != len(bundled)+1 || !slices.Contains(got, "acme-waf") {
+		t.Errorf("Providers = %v", got)
+	}
+	// El proveedor con el mismo nombre se reemplaza completo
+	if provider, _, _ := registry.ClassifyIP("192.0.2.1"); provider != "fastly" {
+		t.Errorf("ClassifyIP(192.0.2.1) = %q, esperado fastly", provider)
+	}
+	if provider, _, ok := registry.ClassifyIP("151.101.1.1"); ok {
+		t.Errorf("los rangos incluidos de fastly deberían reemplazarse: %q", provider)
+	}
+	if _, ok := registry.ClassifyCNAME("site.global.ssl.fastly.net"); ok {
+		t.Error("los sufijos incluidos de fastly deberían reemplazarse")
+	}
+	if provider, _ := registry.ClassifyCNAME("www.fastly-custom.example"); provider != "fastly" {
+		t.Errorf("ClassifyCNAME sufijo nuevo = %q", provider)
+	}
+	if provider, _, _ := registry.ClassifyIP("2001:db8:a::1"); provider != "acme-waf" {
+		t.Errorf("ClassifyIP acme-waf = %q", provider)
+	}
+
+	for _, bad := range []string{"providers: [{ipv4: [192.0.2.0/24]}]", "providers: {"} {
+		if err := os.WriteFile(path, []byte(bad), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := registry.LoadFile(path); err == nil {
+			t.Errorf("se esperaba error con %q", bad)
+		}
+	}
+	if err := registry.LoadFile(filepath.Join(t.TempDir(), "no-existe.yaml")); err == nil {
+		t.Error("se esperaba error con un archivo inexistente")
+	}
+}
+
+func TestBundledRanges(t *testing.T) {
+	ranges, ok := BundledRanges("Cloudflare")
+	if !ok || len(ranges.IPv4) == 0 || len(ranges.IPv6) == 0 {
+		t.Fatalf("BundledRanges(cloudflare) = %+v %v", ranges, ok)
+	}
+	// Es la misma copia que carga el registro
+	registered, _ := newTestRegistry(t).Ranges(domain.ProviderCloudflare)
+	if !slices.Equal(ranges.IPv4, registered.IPv4) || !slices.Equal(ranges.IPv6, registered.IPv6) {
+		t.Errorf("BundledRanges difiere del registro: %v, %v", ranges, registered)
+	}
+	if _, ok := BundledRanges("no-existe"); ok {
+		t.Error("BundledRanges(no-existe) debería fallar")
+	}
+}
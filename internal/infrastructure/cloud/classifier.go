@@ -0,0 +1,111 @@
+package cloud
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/iptrie"
+	"github.com/rs/zerolog"
+)
+
+// Source describe un archivo de rangos cargado
+type Source struct {
+	Provider string
+	Version  string
+	Path     string
+	Prefixes int
+}
+
+// Classifier atribuye IPs a proveedores cloud a partir de archivos de rangos
+// locales. Se carga una vez y luego es de solo lectura.
+type Classifier struct {
+	trie    *iptrie.Trie[domain.CloudInfo]
+	sources []Source
+	logger  zerolog.Logger
+}
+
+// LoadDir carga todos los archivos .json de dir, detectando el formato
+// (AWS, GCP, Azure u Oracle) por su contenido
+func LoadDir(dir string, logger zerolog.Logger) (*Classifier, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("listando rangos cloud: %w", err)
+	}
+	sort.Strings(paths)
+	return LoadFiles(paths, logger)
+}
+
+// LoadFiles carga los archivos de rangos indicados
+func LoadFiles(paths []string, logger zerolog.Logger) (*Classifier, error) {
+	c := &Classifier{logger: logger.With().Str("component", "cloud").Logger()}
+
+	var all []rangeEntry
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("leyendo rangos cloud: %w", err)
+		}
+		provider, version, entries, err := parseRanges(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+
+		c.sources = append(c.sources, Source{Provider: provider, Version: version, Path: path, Prefixes: len(entries)})
+		c.logger.Debug().
+			Str("provider", provider).
+			Str("version", version).
+			Str("path", path).
+			Int("prefixes", len(entries)).
+			Msg("Rangos cloud cargados")
+		all = append(all, entries...)
+	}
+
+	if len(all) == 0 {
+		return nil, fmt.Errorf("no se encontraron rangos cloud")
+	}
+
+	resolveGeneric(all)
+	builder := iptrie.NewBuilder[domain.CloudInfo]()
+	for _, e := range all {
+		builder.Insert(e.prefix, e.info)
+	}
+	c.trie = builder.Build()
+	return c, nil
+}
+
+// ClassifyIP retorna el proveedor, región y servicio cloud de la IP
+func (c *Classifier) ClassifyIP(ip string) (domain.CloudInfo, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return domain.CloudInfo{}, false
+	}
+
+	prefix, info, ok := c.trie.Lookup(addr)
+	if !ok {
+		return domain.CloudInfo{}, false
+	}
+	info.Prefix = prefix.String()
+	return info, true
+}
+
+// Sources retorna los archivos cargados
+func (c *Classifier) Sources() []Source {
+	return c.sources
+}
+
+// Providers retorna los proveedores cargados, sin repetir
+func (c *Classifier) Providers() []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, s := range c.sources {
+		if !seen[s.Provider] {
+			seen[s.Provider] = true
+			out = append(out, s.Provider)
+		}
+	}
+	return out
+}
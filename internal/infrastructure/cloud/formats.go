@@ -0,0 +1,271 @@
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/iptrie"
+)
+
+// Nombres de proveedor cloud
+const (
+	ProviderAWS    = "aws"
+	ProviderGCP    = "gcp"
+	ProviderAzure  = "azure"
+	ProviderOracle = "oracle"
+)
+
+// rangeEntry es un prefijo con su atribución. generic marca los prefijos
+// agregados (AMAZON, AzureCloud, OCI) que se solapan con los específicos.
+type rangeEntry struct {
+	prefix  netip.Prefix
+	info    domain.CloudInfo
+	generic bool
+}
+
+// rangesDocument une las claves de primer nivel de los cuatro formatos para
+// detectar cuál es sin depender del nombre del archivo
+type rangesDocument struct {
+	// AWS y GCP
+	SyncToken    string          `json:"syncToken"`
+	CreateDate   string          `json:"createDate"`
+	CreationTime string          `json:"creationTime"`
+	Prefixes     json.RawMessage `json:"prefixes"`
+	IPv6Prefixes json.RawMessage `json:"ipv6_prefixes"`
+	// Azure
+	ChangeNumber int             `json:"changeNumber"`
+	Values       json.RawMessage `json:"values"`
+	// Oracle
+	LastUpdated string          `json:"last_updated_timestamp"`
+	Regions     json.RawMessage `json:"regions"`
+}
+
+// parseRanges detecta el formato y retorna el proveedor, la versión del
+// archivo y sus prefijos
+func parseRanges(data []byte) (string, string, []rangeEntry, error) {
+	var doc rangesDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return "", "", nil, fmt.Errorf("parseando JSON: %w", err)
+	}
+
+	switch {
+	case doc.Values != nil:
+		entries, err := parseAzure(doc.Values)
+		return ProviderAzure, fmt.Sprint(doc.ChangeNumber), entries, err
+	case doc.Regions != nil:
+		entries, err := parseOracle(doc.Regions)
+		return ProviderOracle, doc.LastUpdated, entries, err
+	case doc.IPv6Prefixes != nil || doc.CreateDate != "":
+		entries, err := parseAWS(doc.Prefixes, doc.IPv6Prefixes)
+		return ProviderAWS, doc.SyncToken, entries, err
+	case doc.Prefixes != nil:
+		entries, err := parseGCP(doc.Prefixes)
+		return ProviderGCP, doc.SyncToken, entries, err
+	default:
+		return "", "", nil, fmt.Errorf("formato de rangos desconocido")
+	}
+}
+
+// parseAWS lee ip-ranges.json. Cada prefijo aparece como AMAZON y además
+// bajo el servicio concreto (EC2, S3, CLOUDFRONT...)
+func parseAWS(v4, v6 json.RawMessage) ([]rangeEntry, error) {
+	var entries []rangeEntry
+	for _, raw := range []json.RawMessage{v4, v6} {
+		if raw == nil {
+			continue
+		}
+		var prefixes []struct {
+			IPPrefix   string `json:"ip_prefix"`
+			IPv6Prefix string `json:"ipv6_prefix"`
+			Region     string `json:"region"`
+			Service    string `json:"service"`
+		}
+		if err := json.Unmarshal(raw, &prefixes); err != nil {
+			return nil, fmt.Errorf("parseando prefijos AWS: %w", err)
+		}
+		for _, p := range prefixes {
+			cidr := p.IPPrefix
+			if cidr == "" {
+				cidr = p.IPv6Prefix
+			}
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("prefijo AWS inválido %q: %w", cidr, err)
+			}
+			entries = append(entries, rangeEntry{
+				prefix:  prefix,
+				info:    domain.CloudInfo{Provider: ProviderAWS, Region: p.Region, Service: p.Service},
+				generic: p.Service == "AMAZON",
+			})
+		}
+	}
+	return entries, nil
+}
+
+// parseGCP lee cloud.json; el scope es la región y el servicio siempre es
+// "Google Cloud"
+func parseGCP(raw json.RawMessage) ([]rangeEntry, error) {
+	var prefixes []struct {
+		IPv4Prefix string `json:"ipv4Prefix"`
+		IPv6Prefix string `json:"ipv6Prefix"`
+		Service    string `json:"service"`
+		Scope      string `json:"scope"`
+	}
+	if err := json.Unmarshal(raw, &prefixes); err != nil {
+		return nil, fmt.Errorf("parseando prefijos GCP: %w", err)
+	}
+
+	entries := make([]rangeEntry, 0, len(prefixes))
+	for _, p := range prefixes {
+		cidr := p.IPv4Prefix
+		if cidr == "" {
+			cidr = p.IPv6Prefix
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("prefijo GCP inválido %q: %w", cidr, err)
+		}
+		entries = append(entries, rangeEntry{
+			prefix: prefix,
+			info:   domain.CloudInfo{Provider: ProviderGCP, Region: p.Scope, Service: p.Service},
+		})
+	}
+	return entries, nil
+}
+
+// parseAzure lee ServiceTags_Public_*.json. Los tags tienen la forma
+// Servicio[.Región]; AzureCloud agrupa todos los rangos de una región.
+func parseAzure(raw json.RawMessage) ([]rangeEntry, error) {
+	var values []struct {
+		Name       string `json:"name"`
+		Properties struct {
+			Region          string   `json:"region"`
+			SystemService   string   `json:"systemService"`
+			AddressPrefixes []string `json:"addressPrefixes"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, fmt.Errorf("parseando service tags Azure: %w", err)
+	}
+
+	var entries []rangeEntry
+	for _, v := range values {
+		service, _, _ := strings.Cut(v.Name, ".")
+		if v.Properties.SystemService != "" {
+			service = v.Properties.SystemService
+		}
+		// Los tags sin región repiten los rangos regionales
+		if v.Properties.Region == "" && service != "AzureCloud" {
+			continue
+		}
+
+		for _, cidr := range v.Properties.AddressPrefixes {
+			prefix, err := netip.ParsePrefix(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("prefijo Azure inválido %q: %w", cidr, err)
+			}
+			entries = append(entries, rangeEntry{
+				prefix:  prefix,
+				info:    domain.CloudInfo{Provider: ProviderAzure, Region: v.Properties.Region, Service: service},
+				generic: service == "AzureCloud",
+			})
+		}
+	}
+	return entries, nil
+}
+
+// parseOracle lee public_ip_ranges.json; el servicio es el tag más
+// específico del CIDR (OSN, OBJECT_STORAGE) o OCI
+func parseOracle(raw json.RawMessage) ([]rangeEntry, error) {
+	var regions []struct {
+		Region string `json:"region"`
+		CIDRs  []struct {
+			CIDR string   `json:"cidr"`
+			Tags []string `json:"tags"`
+		} `json:"cidrs"`
+	}
+	if err := json.Unmarshal(raw, &regions); err != nil {
+		return nil, fmt.Errorf("parseando regiones Oracle: %w", err)
+	}
+
+	var entries []rangeEntry
+	for _, region := range regions {
+		for _, c := range region.CIDRs {
+			prefix, err := netip.ParsePrefix(c.CIDR)
+			if err != nil {
+				return nil, fmt.Errorf("prefijo Oracle inválido %q: %w", c.CIDR, err)
+			}
+			service := "OCI"
+			for _, tag := range c.Tags {
+				if tag != "OCI" {
+					service = tag
+				}
+			}
+			entries = append(entries, rangeEntry{
+				prefix:  prefix,
+				info:    domain.CloudInfo{Provider: ProviderOracle, Region: region.Region, Service: service},
+				generic: service == "OCI",
+			})
+		}
+	}
+	return entries, nil
+}
+
+// Niveles de especificidad de una atribución
+const (
+	levelGlobal    = iota // agregado sin región (AzureCloud)
+	levelAggregate        // agregado regional (AMAZON, AzureCloud.<región>, OCI)
+	levelService          // servicio concreto
+)
+
+func specificity(e rangeEntry) int {
+	switch {
+	case e.generic && e.info.Region == "":
+		return levelGlobal
+	case e.generic:
+		return levelAggregate
+	default:
+		return levelService
+	}
+}
+
+// resolveGeneric reemplaza la atribución de los prefijos genéricos por la
+// del prefijo más específico del mismo proveedor que los contiene: un
+// agregado regional toma el servicio y el AzureCloud global toma la región
+// (y el servicio, si lo hay). Luego los ordena del menos al más específico:
+// en un trie el último valor insertado para un mismo prefijo gana, así
+// ninguna atribución genérica pisa a una más específica.
+func resolveGeneric(entries []rangeEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		return specificity(entries[i]) < specificity(entries[j])
+	})
+	levels := make([]int, len(entries))
+	for i, e := range entries {
+		levels[i] = specificity(e)
+	}
+
+	for level := levelAggregate; level >= levelGlobal; level-- {
+		builder := iptrie.NewBuilder[domain.CloudInfo]()
+		for i, e := range entries {
+			if levels[i] > level {
+				builder.Insert(e.prefix, e.info)
+			}
+		}
+		specific := builder.Build()
+
+		for i := range entries {
+			e := &entries[i]
+			if levels[i] != level {
+				continue
+			}
+			prefix, info, ok := specific.Lookup(e.prefix.Addr())
+			if ok && prefix.Bits() <= e.prefix.Bits() && info.Provider == e.info.Provider {
+				e.info = info
+			}
+		}
+	}
+}
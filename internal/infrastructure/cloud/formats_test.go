@@ -0,0 +1,116 @@
+package cloud
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/rs/zerolog"
+)
+
+func TestParseRangesFormats(t *testing.T) {
+	tests := []struct {
+		file     string
+		provider string
+		version  string
+		entries  int
+	}{
+		{"aws.json", ProviderAWS, "1704067200", 7},
+		{"gcp.json", ProviderGCP, "1704067201", 3},
+		// AzureFrontDoor.Frontend no tiene región y se descarta
+		{"azure.json", ProviderAzure, "250", 9},
+		{"oracle.json", ProviderOracle, "2024-01-01T00:00:02.000000", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			provider, version, entries, err := parseRanges(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if provider != tt.provider || version != tt.version || len(entries) != tt.entries {
+				t.Errorf("parseRanges = %s %s %d prefijos, esperado %s %s %d", provider, version, len(entries), tt.provider, tt.version, tt.entries)
+			}
+			for _, e := range entries {
+				if e.info.Provider != tt.provider {
+					t.Errorf("%s atribuido a %q", e.prefix, e.info.Provider)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRangesErrors(t *testing.T) {
+	tests := map[string]string{
+		"json inválido":       `{`,
+		"formato desconocido": `{"foo": []}`,
+		"prefijo AWS":         `{"createDate": "x", "prefixes": [{"ip_prefix": "3.5.140.0/33", "service": "EC2"}]}`,
+		"prefijo GCP":         `{"prefixes": [{"ipv4Prefix": "no-es-un-cidr"}]}`,
+		"prefijo Azure":       `{"values": [{"name": "AzureCloud.westus", "properties": {"region": "westus", "addressPrefixes": ["13.64.0.0"]}}]}`,
+		"prefijo Oracle":      `{"regions": [{"region": "x", "cidrs": [{"cidr": "", "tags": ["OCI"]}]}]}`,
+	}
+	for name, data := range tests {
+		if _, _, _, err := parseRanges([]byte(data)); err == nil {
+			t.Errorf("%s: se esperaba error", name)
+		}
+	}
+}
+
+func TestClassifyIP(t *testing.T) {
+	classifier, err := LoadDir("testdata", zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := classifier.Providers(); len(got) != 4 {
+		t.Errorf("Providers = %v", got)
+	}
+
+	tests := []struct {
+		ip   string
+		want domain.CloudInfo
+	}{
+		// AWS: el servicio concreto reemplaza a AMAZON en el mismo prefijo
+		{"3.5.141.10", domain.CloudInfo{Provider: ProviderAWS, Region: "ap-northeast-2", Service: "S3", Prefix: "3.5.140.0/22"}},
+		{"52.94.76.9", domain.CloudInfo{Provider: ProviderAWS, Region: "us-west-2", Service: "EC2", Prefix: "52.94.76.0/24"}},
+		// Fuera del /24 de EC2 solo queda el agregado
+		{"52.94.77.9", domain.CloudInfo{Provider: ProviderAWS, Region: "us-west-2", Service: "AMAZON", Prefix: "52.94.76.0/22"}},
+		{"13.33.0.1", domain.CloudInfo{Provider: ProviderAWS, Region: "GLOBAL", Service: "CLOUDFRONT", Prefix: "13.32.0.0/15"}},
+		{"2600:1f14::1", domain.CloudInfo{Provider: ProviderAWS, Region: "us-west-2", Service: "EC2", Prefix: "2600:1f14::/35"}},
+		// GCP: el scope es la región
+		{"34.81.1.1", domain.CloudInfo{Provider: ProviderGCP, Region: "asia-east1", Service: "Google Cloud", Prefix: "34.80.0.0/15"}},
+		{"2600:1900:4010::1", domain.CloudInfo{Provider: ProviderGCP, Region: "europe-west1", Service: "Google Cloud", Prefix: "2600:1900:4010::/44"}},
+		// Azure: el AzureCloud global (sin región) no pisa al regional
+		{"13.64.1.1", domain.CloudInfo{Provider: ProviderAzure, Region: "westus", Service: "AzureCloud", Prefix: "13.64.0.0/16"}},
+		{"13.64.200.1", domain.CloudInfo{Provider: ProviderAzure, Region: "westus", Service: "AzureStorage", Prefix: "13.64.128.0/17"}},
+		{"2603:1030::1", domain.CloudInfo{Provider: ProviderAzure, Region: "westus", Service: "AzureCloud", Prefix: "2603:1030::/40"}},
+		{"20.42.1.1", domain.CloudInfo{Provider: ProviderAzure, Region: "eastus", Service: "AzureSQL", Prefix: "20.42.0.0/16"}},
+		// Solo el tag global cubre el prefijo: sin región
+		{"40.65.0.1", domain.CloudInfo{Provider: ProviderAzure, Service: "AzureCloud", Prefix: "40.64.0.0/13"}},
+		// Oracle: el tag más específico gana sobre OCI
+		{"134.70.25.1", domain.CloudInfo{Provider: ProviderOracle, Region: "us-ashburn-1", Service: "OBJECT_STORAGE", Prefix: "134.70.24.0/21"}},
+		{"147.154.1.1", domain.CloudInfo{Provider: ProviderOracle, Region: "us-ashburn-1", Service: "OSN", Prefix: "147.154.0.0/18"}},
+		{"130.61.1.1", domain.CloudInfo{Provider: ProviderOracle, Region: "eu-frankfurt-1", Service: "OCI", Prefix: "130.61.0.0/16"}},
+	}
+	for _, tt := range tests {
+		got, ok := classifier.ClassifyIP(tt.ip)
+		if !ok || got != tt.want {
+			t.Errorf("ClassifyIP(%s) = %+v %v, esperado %+v", tt.ip, got, ok, tt.want)
+		}
+	}
+
+	for _, ip := range []string{"13.107.246.1", "192.0.2.1", "no-es-una-ip"} {
+		if info, ok := classifier.ClassifyIP(ip); ok {
+			t.Errorf("ClassifyIP(%s) = %+v, no debería clasificarse", ip, info)
+		}
+	}
+}
+
+func TestLoadDirEmpty(t *testing.T) {
+	if _, err := LoadDir(t.TempDir(), zerolog.Nop()); err == nil {
+		t.Error("se esperaba error sin archivos de rangos")
+	}
+}
@@ -0,0 +1,127 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog"
+)
+
+// Feeds publicados por cada proveedor. Azure no tiene una URL estable: el
+// archivo ServiceTags_Public_AAAAMMDD.json cambia de nombre cada semana, por
+// lo que su URL debe indicarse explícitamente.
+var DefaultFeeds = map[string]string{
+	ProviderAWS:    "https://ip-ranges.amazonaws.com/ip-ranges.json",
+	ProviderGCP:    "https://www.gstatic.com/ipranges/cloud.json",
+	ProviderOracle: "https://docs.oracle.com/en-us/iaas/tools/public_ip_ranges.json",
+}
+
+// maxFeedSize limita el tamaño de cada descarga (ServiceTags ronda 5MB)
+const maxFeedSize = 64 << 20
+
+// Refresher descarga los archivos de rangos a un directorio local
+type Refresher struct {
+	client *http.Client
+	logger zerolog.Logger
+}
+
+func NewRefresher(client *http.Client, logger zerolog.Logger) *Refresher {
+	return &Refresher{
+		client: client,
+		logger: logger.With().Str("component", "cloud").Logger(),
+	}
+}
+
+// Refresh descarga cada feed a dir/<proveedor>.json. Un archivo solo se
+// reemplaza si la descarga es válida; los errores no detienen al resto.
+func (r *Refresher) Refresh(ctx context.Context, dir string, feeds map[string]string) ([]Source, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creando directorio de rangos: %w", err)
+	}
+
+	var sources []Source
+	var failed int
+	for _, provider := range []string{ProviderAWS, ProviderGCP, ProviderAzure, ProviderOracle} {
+		url, ok := feeds[provider]
+		if !ok || url == "" {
+			continue
+		}
+		source, err := r.refreshOne(ctx, dir, provider, url)
+		if err != nil {
+			failed++
+			r.logger.Error().Err(err).Str("provider", provider).Str("url", url).Msg("Error actualizando rangos cloud")
+			continue
+		}
+		sources = append(sources, source)
+		r.logger.Info().
+			Str("provider", provider).
+			Str("version", source.Version).
+			Int("prefixes", source.Prefixes).
+			Str("path", source.Path).
+			Msg("Rangos cloud actualizados")
+	}
+
+	if failed > 0 {
+		return sources, fmt.Errorf("%d feeds no pudieron actualizarse", failed)
+	}
+	return sources, nil
+}
+
+func (r *Refresher) refreshOne(ctx context.Context, dir, provider, url string) (Source, error) {
+	data, err := r.download(ctx, url)
+	if err != nil {
+		return Source{}, err
+	}
+
+	detected, version, entries, err := parseRanges(data)
+	if err != nil {
+		return Source{}, err
+	}
+	if detected != provider {
+		return Source{}, fmt.Errorf("se esperaba formato %s y se obtuvo %s", provider, detected)
+	}
+
+	path := filepath.Join(dir, provider+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return Source{}, fmt.Errorf("guardando rangos: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return Source{}, fmt.Errorf("guardando rangos: %w", err)
+	}
+
+	return Source{Provider: provider, Version: version, Path: path, Prefixes: len(entries)}, nil
+}
+
+func (r *Refresher) download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creando request: %w", err)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("descargando rangos: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			r.logger.Warn().Err(err).Msg("Error cerrando body de respuesta")
+		}
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("descargando rangos: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return nil, fmt.Errorf("leyendo rangos: %w", err)
+	}
+	return data, nil
+}
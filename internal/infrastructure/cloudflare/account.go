@@ -0,0 +1,213 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/rs/zerolog"
+)
+
+// DefaultAPIBaseURL es la base de la API v4 de Cloudflare
+const DefaultAPIBaseURL = "https://api.cloudflare.com/client/v4"
+
+// TokenEnv es la variable de entorno con el token de API de solo lectura
+const TokenEnv = "CLOUDFLARE_API_TOKEN"
+
+// ErrZoneNotFound indica que el token no tiene acceso a ninguna zona del dominio
+var ErrZoneNotFound = errors.New("zona no encontrada en la cuenta")
+
+// AccountClient lee zonas y registros DNS de una cuenta Cloudflare con un
+// token de solo lectura (Zone:Read y DNS:Read)
+type AccountClient struct {
+	baseURL string
+	token   string
+	client  *http.Client
+	logger  zerolog.Logger
+}
+
+func NewAccountClient(baseURL, token string, client *http.Client, logger zerolog.Logger) *AccountClient {
+	if baseURL == "" {
+		baseURL = DefaultAPIBaseURL
+	}
+	return &AccountClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		client:  client,
+		logger:  logger.With().Str("component", "cloudflare_account").Logger(),
+	}
+}
+
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type apiResultInfo struct {
+	Page       int `json:"page"`
+	TotalPages int `json:"total_pages"`
+}
+
+type apiListResponse[T any] struct {
+	Success    bool          `json:"success"`
+	Errors     []apiError    `json:"errors"`
+	Result     []T           `json:"result"`
+	ResultInfo apiResultInfo `json:"result_info"`
+}
+
+type apiZone struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type apiDNSRecord struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+	Proxied bool   `json:"proxied"`
+	TTL     int    `json:"ttl"`
+}
+
+// Zone retorna el nombre de la zona que contiene name. Sirve para validar el
+// token y la zona antes de escanear.
+func (c *AccountClient) Zone(ctx context.Context, name string) (string, error) {
+	zone, err := c.findZone(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	return zone.Name, nil
+}
+
+// Inventory retorna la zona que contiene name y todos sus registros DNS
+func (c *AccountClient) Inventory(ctx context.Context, name string) (domain.ZoneInventory, error) {
+	zone, err := c.findZone(ctx, name)
+	if err != nil {
+		return domain.ZoneInventory{}, err
+	}
+
+	records, err := list[apiDNSRecord](ctx, c, "/zones/"+url.PathEscape(zone.ID)+"/dns_records", nil)
+	if err != nil {
+		return domain.ZoneInventory{}, fmt.Errorf("listando registros de %s: %w", zone.Name, err)
+	}
+
+	inventory := domain.ZoneInventory{Zone: zone.Name, Records: make([]domain.DNSRecord, 0, len(records))}
+	for _, r := range records {
+		inventory.Records = append(inventory.Records, domain.DNSRecord{
+			Name:    strings.TrimSuffix(strings.ToLower(r.Name), "."),
+			Type:    strings.ToUpper(r.Type),
+			Content: r.Content,
+			Proxied: r.Proxied,
+			TTL:     r.TTL,
+		})
+	}
+
+	c.logger.Debug().Str("zone", zone.Name).Int("records", len(records)).Msg("Inventario de zona obtenido")
+	return inventory, nil
+}
+
+// findZone busca la zona de name probando name y luego sus dominios padre
+// (ej: dev.example.com, example.com)
+func (c *AccountClient) findZone(ctx context.Context, name string) (apiZone, error) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+
+	labels := strings.Split(name, ".")
+	for i := 0; i < len(labels)-1; i++ {
+		candidate := strings.Join(labels[i:], ".")
+
+		zones, err := list[apiZone](ctx, c, "/zones", url.Values{"name": {candidate}})
+		if err != nil {
+			return apiZone{}, fmt.Errorf("listando zonas: %w", err)
+		}
+		if len(zones) > 0 {
+			return zones[0], nil
+		}
+	}
+
+	return apiZone{}, fmt.Errorf("%w: %s", ErrZoneNotFound, name)
+}
+
+// list recorre todas las páginas de un listado de la API
+func list[T any](ctx context.Context, c *AccountClient, path string, query url.Values) ([]T, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("per_page", "100")
+
+	var all []T
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+
+		var response apiListResponse[T]
+		if err := c.get(ctx, path+"?"+query.Encode(), &response); err != nil {
+			return nil, err
+		}
+		all = append(all, response.Result...)
+
+		if page >= response.ResultInfo.TotalPages || len(response.Result) == 0 {
+			return all, nil
+		}
+	}
+}
+
+func (c *AccountClient) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("creando request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("consultando API: %w", err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			c.logger.Warn().Err(err).Msg("Error cerrando body de respuesta")
+		}
+	}(resp.Body)
+
+	var envelope struct {
+		Success bool       `json:"success"`
+		Errors  []apiError `json:"errors"`
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("leyendo respuesta: %w", err)
+	}
+	// Los errores de la API vienen en el mismo sobre JSON, también con 4xx
+	_ = json.Unmarshal(body, &envelope)
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("token rechazado por la API (status %d)%s", resp.StatusCode, describeErrors(envelope.Errors))
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("API retornó status %d%s", resp.StatusCode, describeErrors(envelope.Errors))
+	case !envelope.Success:
+		return fmt.Errorf("API retornó success=false%s", describeErrors(envelope.Errors))
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decodificando respuesta: %w", err)
+	}
+	return nil
+}
+
+func describeErrors(errs []apiError) string {
+	if len(errs) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(errs))
+	for _, e := range errs {
+		parts = append(parts, fmt.Sprintf("%d %s", e.Code, e.Message))
+	}
+	return ": " + strings.Join(parts, "; ")
+}
@@ -0,0 +1,124 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+const testToken = "test-token"
+
+// fakeAPI simula la API v4 con una zona example.com y registros paginados
+func fakeAPI(t *testing.T, perPage int) *httptest.Server {
+	t.Helper()
+
+	records := []apiDNSRecord{
+		{Name: "www.example.com", Type: "A", Content: "104.16.132.229", Proxied: true, TTL: 1},
+		{Name: "admin.example.com", Type: "A", Content: "203.0.113.10", TTL: 300},
+		{Name: "docs.example.com", Type: "CNAME", Content: "example-docs.pages.dev", Proxied: true, TTL: 1},
+		{Name: "example.com", Type: "MX", Content: "mx.example.com", TTL: 300},
+		{Name: "*.dev.example.com", Type: "A", Content: "198.51.100.42", TTL: 300},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /zones", func(w http.ResponseWriter, r *http.Request) {
+		var zones []apiZone
+		if r.URL.Query().Get("name") == "example.com" {
+			zones = append(zones, apiZone{ID: "zone-1", Name: "example.com"})
+		}
+		writeList(w, zones, 1, 1)
+	})
+	mux.HandleFunc("GET /zones/zone-1/dns_records", func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		pages := (len(records) + perPage - 1) / perPage
+		start := (page - 1) * perPage
+		end := min(start+perPage, len(records))
+		writeList(w, records[start:end], page, pages)
+	})
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			w.WriteHeader(http.StatusForbidden)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"success": false,
+				"errors":  []apiError{{Code: 9109, Message: "Invalid access token"}},
+			})
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+func writeList[T any](w http.ResponseWriter, result []T, page, pages int) {
+	_ = json.NewEncoder(w).Encode(apiListResponse[T]{
+		Success:    true,
+		Result:     result,
+		ResultInfo: apiResultInfo{Page: page, TotalPages: pages},
+	})
+}
+
+func TestInventory(t *testing.T) {
+	server := fakeAPI(t, 2)
+	defer server.Close()
+
+	client := NewAccountClient(server.URL, testToken, server.Client(), zerolog.Nop())
+
+	// Un subdominio se resuelve a la zona padre
+	inventory, err := client.Inventory(context.Background(), "dev.example.com")
+	if err != nil {
+		t.Fatalf("Inventory: %v", err)
+	}
+	if inventory.Zone != "example.com" {
+		t.Errorf("zone = %q, esperado example.com", inventory.Zone)
+	}
+	if len(inventory.Records) != 5 {
+		t.Fatalf("records = %d, esperado 5 (3 páginas)", len(inventory.Records))
+	}
+
+	proxied := map[string]bool{}
+	for _, r := range inventory.Records {
+		proxied[r.Name] = r.Proxied
+	}
+	if !proxied["www.example.com"] || proxied["admin.example.com"] {
+		t.Errorf("flag proxied incorrecto: %v", proxied)
+	}
+}
+
+func TestInventoryErrors(t *testing.T) {
+	server := fakeAPI(t, 100)
+	defer server.Close()
+
+	client := NewAccountClient(server.URL, testToken, server.Client(), zerolog.Nop())
+	if _, err := client.Inventory(context.Background(), "other.org"); !errors.Is(err, ErrZoneNotFound) {
+		t.Errorf("err = %v, esperado ErrZoneNotFound", err)
+	}
+
+	client = NewAccountClient(server.URL, "invalid", server.Client(), zerolog.Nop())
+	if _, err := client.Inventory(context.Background(), "example.com"); err == nil {
+		t.Error("se esperaba error con token inválido")
+	}
+}
+
+func TestZone(t *testing.T) {
+	server := fakeAPI(t, 100)
+	defer server.Close()
+
+	client := NewAccountClient(server.URL, testToken, server.Client(), zerolog.Nop())
+	if zone, err := client.Zone(context.Background(), "Dev.Example.com."); err != nil || zone != "example.com" {
+		t.Errorf("Zone = %q %v, esperado example.com", zone, err)
+	}
+	if _, err := client.Zone(context.Background(), "other.org"); !errors.Is(err, ErrZoneNotFound) {
+		t.Errorf("err = %v, esperado ErrZoneNotFound", err)
+	}
+
+	client = NewAccountClient(server.URL, "invalid", server.Client(), zerolog.Nop())
+	if _, err := client.Zone(context.Background(), "example.com"); err == nil {
+		t.Error("se esperaba error con token inválido")
+	}
+}
@@ -0,0 +1,196 @@
+package cloudflare
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// DefaultMaxAge es la edad máxima de la caché antes de revalidar con la API
+const DefaultMaxAge = 24 * time.Hour
+
+const cacheFileName = "cloudflare-ranges.json"
+
+// cacheEntry es la copia en disco de la última respuesta de la API
+type cacheEntry struct {
+	FetchedAt    time.Time `json:"fetched_at"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Version      string    `json:"version,omitempty"`
+	IPv4         []string  `json:"ipv4_cidrs"`
+	IPv6         []string  `json:"ipv6_cidrs"`
+}
+
+// apiRangesResponse es la respuesta de /client/v4/ips
+type apiRangesResponse struct {
+	Success bool `json:"success"`
+	Result  struct {
+		IPv4 []string `json:"ipv4_cidrs"`
+		IPv6 []string `json:"ipv6_cidrs"`
+		ETag string   `json:"etag"`
+	} `json:"result"`
+}
+
+func (e *cacheEntry) ranges(source string) domain.CFRanges {
+	return domain.CFRanges{
+		IPv4: e.IPv4,
+		IPv6: e.IPv6,
+		Source: domain.RangeSource{
+			Provider:  Owner,
+			Source:    source,
+			Version:   e.Version,
+			FetchedAt: e.FetchedAt,
+		},
+	}
+}
+
+func (s *Service) cachePath() string {
+	return filepath.Join(s.cacheDir, cacheFileName)
+}
+
+// readCache retorna la copia en caché o nil si no hay una válida
+func (s *Service) readCache() *cacheEntry {
+	if s.cacheDir == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.cachePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			s.logger.Warn().Err(err).Str("path", s.cachePath()).Msg("Error leyendo caché de rangos")
+		}
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || len(entry.IPv4)+len(entry.IPv6) == 0 {
+		s.logger.Warn().Err(err).Str("path", s.cachePath()).Msg("Caché de rangos inválida, se ignora")
+		return nil
+	}
+	return &entry
+}
+
+// writeCache guarda la copia de forma atómica; un error solo se registra
+func (s *Service) writeCache(entry *cacheEntry) {
+	if s.cacheDir == "" {
+		return
+	}
+
+	if err := s.saveCache(entry); err != nil {
+		s.logger.Warn().Err(err).Str("path", s.cachePath()).Msg("Error guardando caché de rangos")
+	}
+}
+
+func (s *Service) saveCache(entry *cacheEntry) error {
+	if err := os.MkdirAll(s.cacheDir, 0o755); err != nil {
+		return fmt.Errorf("creando directorio de caché: %w", err)
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("serializando caché: %w", err)
+	}
+
+	tmp := s.cachePath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("escribiendo caché: %w", err)
+	}
+	if err := os.Rename(tmp, s.cachePath()); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("escribiendo caché: %w", err)
+	}
+	return nil
+}
+
+// loadRangesFile carga rangos desde un archivo local. Acepta la respuesta de
+// la API, el archivo de caché o una lista de CIDRs (uno por línea, como
+// https://www.cloudflare.com/ips-v4).
+func (s *Service) loadRangesFile(path string) (domain.CFRanges, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return domain.CFRanges{}, fmt.Errorf("leyendo archivo de rangos: %w", err)
+	}
+
+	ranges, err := ParseRanges(data)
+	if err != nil {
+		return domain.CFRanges{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if ranges.Source.FetchedAt.IsZero() {
+		if info, err := os.Stat(path); err == nil {
+			ranges.Source.FetchedAt = info.ModTime().UTC()
+		}
+	}
+
+	s.logger.Debug().
+		Str("path", path).
+		Int("ipv4_ranges", len(ranges.IPv4)).
+		Int("ipv6_ranges", len(ranges.IPv6)).
+		Str("version", ranges.Source.Version).
+		Msg("Rangos Cloudflare cargados desde archivo")
+	return ranges, nil
+}
+
+// ParseRanges interpreta el contenido de un archivo de rangos. Si el archivo
+// no declara versión, se usa un hash de su contenido.
+func ParseRanges(data []byte) (domain.CFRanges, error) {
+	ranges := domain.CFRanges{
+		Source: domain.RangeSource{Provider: Owner, Source: domain.RangesSourceFile},
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var doc struct {
+			cacheEntry
+			Result *struct {
+				IPv4 []string `json:"ipv4_cidrs"`
+				IPv6 []string `json:"ipv6_cidrs"`
+				ETag string   `json:"etag"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return domain.CFRanges{}, fmt.Errorf("parseando JSON: %w", err)
+		}
+		if doc.Result != nil {
+			ranges.IPv4, ranges.IPv6, ranges.Source.Version = doc.Result.IPv4, doc.Result.IPv6, doc.Result.ETag
+		} else {
+			ranges.IPv4, ranges.IPv6, ranges.Source.Version = doc.IPv4, doc.IPv6, doc.Version
+			ranges.Source.FetchedAt = doc.FetchedAt
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			prefix, err := netip.ParsePrefix(line)
+			if err != nil {
+				return domain.CFRanges{}, fmt.Errorf("CIDR inválido %q: %w", line, err)
+			}
+			if prefix.Addr().Is4() {
+				ranges.IPv4 = append(ranges.IPv4, line)
+			} else {
+				ranges.IPv6 = append(ranges.IPv6, line)
+			}
+		}
+	}
+
+	if len(ranges.IPv4)+len(ranges.IPv6) == 0 {
+		return domain.CFRanges{}, fmt.Errorf("el archivo no contiene rangos")
+	}
+	if ranges.Source.Version == "" {
+		sum := sha256.Sum256(data)
+		ranges.Source.Version = "sha256:" + hex.EncodeToString(sum[:6])
+	}
+	return ranges, nil
+}
@@ -0,0 +1,247 @@
+package cloudflare
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/rs/zerolog"
+)
+
+const (
+	apiETag         = `"abc123"`
+	apiLastModified = "Mon, 01 Jan 2024 00:00:00 GMT"
+	apiBody         = `{"success": true, "result": {"ipv4_cidrs": ["192.0.2.0/24"], "ipv6_cidrs": ["2001:db8::/32"], "etag": "v42"}}`
+)
+
+// rangesAPI responde /client/v4/ips con ETag y Last-Modified, o 304 si la
+// consulta trae las mismas validaciones
+type rangesAPI struct {
+	mu       sync.Mutex
+	requests []http.Header
+}
+
+func (f *rangesAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Header.Clone())
+	f.mu.Unlock()
+
+	if r.Header.Get("If-None-Match") == apiETag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", apiETag)
+	w.Header().Set("Last-Modified", apiLastModified)
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(apiBody))
+}
+
+func (f *rangesAPI) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.requests)
+}
+
+func (f *rangesAPI) last() http.Header {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[len(f.requests)-1]
+}
+
+func newCacheService(t *testing.T, url string, maxAge time.Duration) *Service {
+	t.Helper()
+	s := &Service{apiURL: url, client: &http.Client{Timeout: 5 * time.Second}, logger: zerolog.Nop()}
+	s.SetCache(t.TempDir(), maxAge)
+	return s
+}
+
+// ageCache hace que la copia en disco parezca obtenida hace age
+func ageCache(t *testing.T, s *Service, age time.Duration) {
+	t.Helper()
+	entry := s.readCache()
+	if entry == nil {
+		t.Fatal("sin caché")
+	}
+	entry.FetchedAt = time.Now().UTC().Add(-age)
+	if err := s.saveCache(entry); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetRangesCache(t *testing.T) {
+	api := &rangesAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	s := newCacheService(t, server.URL, time.Hour)
+	ctx := context.Background()
+
+	// Sin caché: API sin validaciones y la respuesta queda en disco
+	ranges, err := s.GetRanges(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ranges.Source.Source != domain.RangesSourceAPI || ranges.Source.Version != "v42" || !slices.Equal(ranges.IPv4, []string{"192.0.2.0/24"}) {
+		t.Errorf("primera consulta = %+v", ranges)
+	}
+	if h := api.last(); h.Get("If-None-Match") != "" || h.Get("If-Modified-Since") != "" {
+		t.Errorf("consulta sin caché con validaciones: %v", h)
+	}
+	entry := s.readCache()
+	if entry == nil || entry.ETag != apiETag || entry.LastModified != apiLastModified {
+		t.Fatalf("caché = %+v", entry)
+	}
+
+	// Caché fresca: no se consulta la API
+	ranges, err = s.GetRanges(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.count() != 1 || ranges.Source.Source != domain.RangesSourceCache || ranges.Source.Version != "v42" {
+		t.Errorf("caché fresca: %d consultas, origen %+v", api.count(), ranges.Source)
+	}
+
+	// Caché vencida: revalidación condicional, 304 renueva la fecha
+	ageCache(t, s, 2*time.Hour)
+	ranges, err = s.GetRanges(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.count() != 2 {
+		t.Fatalf("caché vencida: %d consultas, esperado 2", api.count())
+	}
+	if h := api.last(); h.Get("If-None-Match") != apiETag || h.Get("If-Modified-Since") != apiLastModified {
+		t.Errorf("validaciones = %q %q", h.Get("If-None-Match"), h.Get("If-Modified-Since"))
+	}
+	if ranges.Source.Source != domain.RangesSourceCache || time.Since(ranges.Source.FetchedAt) > time.Minute {
+		t.Errorf("304: origen %+v", ranges.Source)
+	}
+	if entry := s.readCache(); time.Since(entry.FetchedAt) > time.Minute {
+		t.Errorf("304 no renovó la caché: %v", entry.FetchedAt)
+	}
+
+	// -no-fetch con caché vencida: se usa la copia sin consultar
+	ageCache(t, s, 2*time.Hour)
+	if ranges, err = s.GetRanges(ctx, true); err != nil || ranges.Source.Source != domain.RangesSourceCache {
+		t.Errorf("-no-fetch: %+v %v", ranges.Source, err)
+	}
+	if api.count() != 2 {
+		t.Errorf("-no-fetch consultó la API")
+	}
+}
+
+func TestGetRangesFallback(t *testing.T) {
+	api := &rangesAPI{}
+	server := httptest.NewServer(api)
+	s := newCacheService(t, server.URL, time.Hour)
+	ctx := context.Background()
+	if _, err := s.GetRanges(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	// Error de red con caché vencida: se usa la copia vencida
+	ageCache(t, s, 48*time.Hour)
+	ranges, err := s.GetRanges(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ranges.Source.Source != domain.RangesSourceCache || ranges.Source.Version != "v42" {
+		t.Errorf("caché vencida: %+v", ranges.Source)
+	}
+
+	// Sin caché ni API: los rangos incluidos en el binario
+	empty := newCacheService(t, server.URL, time.Hour)
+	ranges, err = empty.GetRanges(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ranges.Source.Source != domain.RangesSourceDefault || ranges.Source.Version != defaultVersion || len(ranges.IPv4) == 0 {
+		t.Errorf("sin caché: %+v", ranges.Source)
+	}
+
+	// Un error HTTP tampoco reemplaza la caché
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	s.apiURL = failing.URL
+	if ranges, err = s.GetRanges(ctx, false); err != nil || ranges.Source.Source != domain.RangesSourceCache {
+		t.Errorf("API 502: %+v %v", ranges.Source, err)
+	}
+}
+
+func TestGetRangesFile(t *testing.T) {
+	api := &rangesAPI{}
+	server := httptest.NewServer(api)
+	defer server.Close()
+	s := newCacheService(t, server.URL, time.Hour)
+
+	path := filepath.Join(t.TempDir(), "ips-v4")
+	if err := os.WriteFile(path, []byte("192.0.2.0/24\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s.SetRangesFile(path)
+	ranges, err := s.GetRanges(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ranges.Source.Source != domain.RangesSourceFile || ranges.Source.FetchedAt.IsZero() || api.count() != 0 {
+		t.Errorf("archivo: %+v, %d consultas", ranges.Source, api.count())
+	}
+}
+
+func TestParseRanges(t *testing.T) {
+	cache, err := json.Marshal(cacheEntry{
+		FetchedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		Version:   "v7",
+		IPv4:      []string{"198.51.100.0/24"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		ipv4    []string
+		ipv6    []string
+		version string
+	}{
+		{"respuesta de la API", apiBody, []string{"192.0.2.0/24"}, []string{"2001:db8::/32"}, "v42"},
+		{"archivo de caché", string(cache), []string{"198.51.100.0/24"}, nil, "v7"},
+		{"lista de CIDRs", "# ips-v4\n192.0.2.0/24\n\n2001:db8::/32\n", []string{"192.0.2.0/24"}, []string{"2001:db8::/32"}, "sha256:"},
+	}
+	for _, tt := range tests {
+		ranges, err := ParseRanges([]byte(tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !slices.Equal(ranges.IPv4, tt.ipv4) || !slices.Equal(ranges.IPv6, tt.ipv6) || !strings.HasPrefix(ranges.Source.Version, tt.version) {
+			t.Errorf("%s: %v %v %q", tt.name, ranges.IPv4, ranges.IPv6, ranges.Source.Version)
+		}
+		if ranges.Source.Source != domain.RangesSourceFile || ranges.Source.Provider != Owner {
+			t.Errorf("%s: origen %+v", tt.name, ranges.Source)
+		}
+	}
+
+	for name, data := range map[string]string{
+		"json inválido": "{",
+		"sin rangos":    `{"success": true, "result": {}}`,
+		"vacío":         "\n# nada\n",
+		"CIDR inválido": "192.0.2.0/33\n",
+	} {
+		if _, err := ParseRanges([]byte(data)); err == nil {
+			t.Errorf("%s: se esperaba error", name)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package cloudflare
+
+import (
+	"strings"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// productSuffixes asocia sufijos CNAME con el producto de Cloudflare que
+// delatan; estos hosts no siempre resuelven a rangos de Cloudflare
+var productSuffixes = []struct {
+	suffix  string
+	product string
+}{
+	{"cfargotunnel.com", domain.CFProductTunnel},
+	{"pages.dev", domain.CFProductPages},
+	{"workers.dev", domain.CFProductWorkers},
+	{"cdn.cloudflare.net", domain.CFProductPartialCNAME},
+}
+
+// ProductFromCNAME retorna el producto de Cloudflare que indica el destino
+// de un CNAME (tunnel, pages, workers o partial-cname)
+func (s *Service) ProductFromCNAME(target string) (string, bool) {
+	target = strings.TrimSuffix(strings.ToLower(target), ".")
+	for _, p := range productSuffixes {
+		if strings.HasSuffix(target, "."+p.suffix) {
+			return p.product, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,37 @@
+package cloudflare
+
+import (
+	"testing"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/rs/zerolog"
+)
+
+func TestProductFromCNAME(t *testing.T) {
+	service := NewService(zerolog.Nop(), nil)
+
+	tests := []struct {
+		target  string
+		product string
+	}{
+		{"6ff42ae2-765d-4adf-8112-31c55c1551ef.cfargotunnel.com", domain.CFProductTunnel},
+		{"6FF42AE2-765D-4ADF-8112-31C55C1551EF.CFArgoTunnel.com.", domain.CFProductTunnel},
+		{"docs.pages.dev", domain.CFProductPages},
+		{"preview.docs.pages.dev", domain.CFProductPages},
+		{"api.acme.workers.dev", domain.CFProductWorkers},
+		{"www.example.com.cdn.cloudflare.net", domain.CFProductPartialCNAME},
+		// Solo coinciden etiquetas completas
+		{"mypages.dev", ""},
+		{"notcfargotunnel.com", ""},
+		{"pages.dev", ""},
+		// Otros destinos de Cloudflare no delatan un producto
+		{"www.example.com.cloudflare.net", ""},
+		{"edge.example.net", ""},
+	}
+	for _, tt := range tests {
+		product, ok := service.ProductFromCNAME(tt.target)
+		if product != tt.product || ok != (tt.product != "") {
+			t.Errorf("ProductFromCNAME(%s) = %q %v, esperado %q", tt.target, product, ok, tt.product)
+		}
+	}
+}
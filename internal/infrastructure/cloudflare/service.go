@@ -7,76 +7,144 @@ import (
 	"io"
 	"net/http"
 	"net/netip"
+	"strings"
 	"time"
 
 	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/cdn"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/iptrie"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/network"
 	"github.com/rs/zerolog"
 )
 
+// Owner identifica a Cloudflare como dueño de los prefijos compilados
+const Owner = "cloudflare"
+
+// defaultVersion identifica la lista incluida en el binario
+const defaultVersion = "builtin"
+
 type Service struct {
-	apiURL string
-	client *http.Client
-	logger zerolog.Logger
+	apiURL     string
+	client     *http.Client
+	cacheDir   string
+	maxAge     time.Duration
+	rangesFile string
+	logger     zerolog.Logger
 }
 
-func NewService(logger zerolog.Logger) *Service {
+func NewService(logger zerolog.Logger, binder *network.Binder) *Service {
 	return &Service{
 		apiURL: "https://api.cloudflare.com/client/v4/ips",
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-		},
+		client: binder.HTTPClient(10 * time.Second),
+		maxAge: DefaultMaxAge,
 		logger: logger,
 	}
 }
 
+// SetCache habilita la caché en disco de los rangos. Una copia más nueva que
+// maxAge se usa sin consultar la API.
+func (s *Service) SetCache(dir string, maxAge time.Duration) {
+	s.cacheDir = dir
+	s.maxAge = maxAge
+}
+
+// SetRangesFile fija un archivo local de rangos; la API y la caché se ignoran
+func (s *Service) SetRangesFile(path string) {
+	s.rangesFile = path
+}
+
+// GetRanges retorna los rangos vigentes en este orden: archivo explícito
+// (-ranges-file), caché fresca, API (revalidando la caché) y, si todo falla,
+// la caché vencida o los rangos incluidos en el binario
 func (s *Service) GetRanges(ctx context.Context, noFetch bool) (domain.CFRanges, error) {
-	if noFetch {
-		s.logger.Debug().Msg("Usando rangos Cloudflare por defecto")
-		return s.loadDefaultRanges(), nil
+	if s.rangesFile != "" {
+		return s.loadRangesFile(s.rangesFile)
+	}
+
+	cached := s.readCache()
+	if cached != nil && time.Since(cached.FetchedAt) < s.maxAge {
+		s.logger.Debug().Time("fetched_at", cached.FetchedAt).Msg("Usando rangos Cloudflare en caché")
+		return cached.ranges(domain.RangesSourceCache), nil
 	}
 
-	s.logger.Debug().Msg("Obteniendo rangos Cloudflare desde API")
-	return s.fetchRanges(ctx)
+	if !noFetch {
+		s.logger.Debug().Msg("Obteniendo rangos Cloudflare desde API")
+		entry, source, err := s.fetchRanges(ctx, cached)
+		if err == nil {
+			s.writeCache(entry)
+			return entry.ranges(source), nil
+		}
+		s.logger.Warn().Err(err).Msg("Error obteniendo rangos desde API")
+	}
+
+	if cached != nil {
+		s.logger.Warn().
+			Time("fetched_at", cached.FetchedAt).
+			Dur("age", time.Since(cached.FetchedAt).Round(time.Second)).
+			Msg("Usando rangos Cloudflare en caché vencidos")
+		return cached.ranges(domain.RangesSourceCache), nil
+	}
+
+	return s.loadDefaultRanges(), nil
 }
 
-func (s *Service) IsCloudflareIP(ip string, ranges domain.CFRanges) bool {
-	addr, err := netip.ParseAddr(ip)
-	if err != nil {
-		s.logger.Debug().Err(err).Str("ip", ip).Msg("Error parseando IP")
-		return false
+// Compile construye el trie de un conjunto de rangos; el dueño de cada prefijo
+// es "cloudflare". Se compila una vez por conjunto obtenido y se reutiliza
+// para todas las búsquedas.
+func (s *Service) Compile(ranges domain.CFRanges) *iptrie.Trie[string] {
+	builder := iptrie.NewBuilder[string]()
+	for _, prefix := range s.parsePrefixes(ranges.IPv4) {
+		builder.Insert(prefix, Owner)
 	}
+	for _, prefix := range s.parsePrefixes(ranges.IPv6) {
+		builder.Insert(prefix, Owner)
+	}
+	return builder.Build()
+}
 
-	// Convertir rangos de string a netip.Prefix
-	ipv4Prefixes := s.parsePrefixes(ranges.IPv4)
-	ipv6Prefixes := s.parsePrefixes(ranges.IPv6)
+// Cached retorna la copia en caché, sin importar su edad
+func (s *Service) Cached() (domain.CFRanges, bool) {
+	cached := s.readCache()
+	if cached == nil {
+		return domain.CFRanges{}, false
+	}
+	return cached.ranges(domain.RangesSourceCache), true
+}
 
-	if addr.Is4() {
-		for _, prefix := range ipv4Prefixes {
-			if prefix.Contains(addr) {
-				return true
-			}
-		}
-	} else {
-		for _, prefix := range ipv6Prefixes {
-			if prefix.Contains(addr) {
-				return true
-			}
-		}
+// Fetch consulta la API sin usar ni modificar la caché
+func (s *Service) Fetch(ctx context.Context) (domain.CFRanges, error) {
+	entry, source, err := s.fetchRanges(ctx, nil)
+	if err != nil {
+		return domain.CFRanges{}, err
 	}
+	return entry.ranges(source), nil
+}
 
-	return false
+// LoadFile carga rangos desde un archivo local (ver ParseRanges)
+func (s *Service) LoadFile(path string) (domain.CFRanges, error) {
+	return s.loadRangesFile(path)
 }
 
-func (s *Service) fetchRanges(ctx context.Context) (domain.CFRanges, error) {
+// fetchRanges consulta la API y retorna la copia a guardar en caché y el
+// origen resultante. Con una copia en caché envía If-None-Match e
+// If-Modified-Since; un 304 solo renueva la fecha de la copia.
+func (s *Service) fetchRanges(ctx context.Context, cached *cacheEntry) (*cacheEntry, string, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.apiURL, nil)
 	if err != nil {
-		return domain.CFRanges{}, fmt.Errorf("creando request: %w", err)
+		return nil, "", fmt.Errorf("creando request: %w", err)
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
 	}
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		s.logger.Warn().Err(err).Msg("Error obteniendo rangos desde API, usando defaults")
-		return s.loadDefaultRanges(), nil
+		return nil, "", fmt.Errorf("consultando API: %w", err)
 	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
@@ -85,64 +153,64 @@ func (s *Service) fetchRanges(ctx context.Context) (domain.CFRanges, error) {
 		}
 	}(resp.Body)
 
-	if resp.StatusCode != http.StatusOK {
-		s.logger.Warn().Int("status", resp.StatusCode).Msg("API retornó error, usando defaults")
-		return s.loadDefaultRanges(), nil
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		cached.FetchedAt = time.Now().UTC()
+		s.logger.Debug().Str("version", cached.Version).Msg("Rangos Cloudflare revalidados (304)")
+		return cached, domain.RangesSourceCache, nil
 	}
 
-	var apiResponse struct {
-		Success bool `json:"success"`
-		Result  struct {
-			IPv4 []string `json:"ipv4_cidrs"`
-			IPv6 []string `json:"ipv6_cidrs"`
-		} `json:"result"`
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("API retornó status %d", resp.StatusCode)
 	}
 
+	var apiResponse apiRangesResponse
 	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		s.logger.Warn().Err(err).Msg("Error decodificando respuesta, usando defaults")
-		return s.loadDefaultRanges(), nil
+		return nil, "", fmt.Errorf("decodificando respuesta: %w", err)
 	}
 
 	if !apiResponse.Success {
-		s.logger.Warn().Msg("API retornó success=false, usando defaults")
-		return s.loadDefaultRanges(), nil
+		return nil, "", fmt.Errorf("API retornó success=false")
 	}
 
+	entry := &cacheEntry{
+		FetchedAt:    time.Now().UTC(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Version:      apiResponse.Result.ETag,
+		IPv4:         apiResponse.Result.IPv4,
+		IPv6:         apiResponse.Result.IPv6,
+	}
+	if entry.Version == "" {
+		entry.Version = strings.Trim(entry.ETag, `W/"`)
+	}
 	s.logger.Debug().
-		Int("ipv4_ranges", len(apiResponse.Result.IPv4)).
-		Int("ipv6_ranges", len(apiResponse.Result.IPv6)).
+		Int("ipv4_ranges", len(entry.IPv4)).
+		Int("ipv6_ranges", len(entry.IPv6)).
+		Str("version", entry.Version).
 		Msg("Rangos Cloudflare obtenidos desde API")
 
-	return domain.CFRanges{
-		IPv4: apiResponse.Result.IPv4,
-		IPv6: apiResponse.Result.IPv6,
-	}, nil
+	return entry, domain.RangesSourceAPI, nil
 }
 
+// loadDefaultRanges retorna los rangos de Cloudflare del registro incluido en
+// el binario (cdn/data/providers.yaml)
 func (s *Service) loadDefaultRanges() domain.CFRanges {
-	defaultIPv4 := []string{
-		"103.21.244.0/22", "103.22.200.0/22", "103.31.4.0/22",
-		"104.16.0.0/13", "104.24.0.0/14", "108.162.192.0/18",
-		"131.0.72.0/22", "141.101.64.0/18", "162.158.0.0/15",
-		"172.64.0.0/13", "173.245.48.0/20", "188.114.96.0/20",
-		"190.93.240.0/20", "197.234.240.0/22", "198.41.128.0/17",
-	}
-
-	defaultIPv6 := []string{
-		"2400:cb00::/32", "2606:4700::/32", "2803:f800::/32",
-		"2405:b500::/32", "2405:8100::/32", "2a06:98c0::/29",
-		"2c0f:f248::/32",
+	ranges, ok := cdn.BundledRanges(Owner)
+	if !ok {
+		s.logger.Error().Msg("El registro incluido en el binario no tiene rangos de Cloudflare")
 	}
 
-	s.logger.Debug().
-		Int("ipv4_ranges", len(defaultIPv4)).
-		Int("ipv6_ranges", len(defaultIPv6)).
-		Msg("Usando rangos Cloudflare por defecto")
+	s.logger.Warn().
+		Int("ipv4_ranges", len(ranges.IPv4)).
+		Int("ipv6_ranges", len(ranges.IPv6)).
+		Msg("Usando rangos Cloudflare por defecto (pueden estar desactualizados)")
 
-	return domain.CFRanges{
-		IPv4: defaultIPv4,
-		IPv6: defaultIPv6,
+	ranges.Source = domain.RangeSource{
+		Provider: Owner,
+		Source:   domain.RangesSourceDefault,
+		Version:  defaultVersion,
 	}
+	return ranges
 }
 
 func (s *Service) parsePrefixes(cidrs []string) []netip.Prefix {
@@ -0,0 +1,173 @@
+package cloudflare
+
+import (
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/iptrie"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/network"
+	"github.com/rs/zerolog"
+)
+
+const benchLookups = 1_000_000
+
+// benchCorpus genera n direcciones deterministas (75% IPv4, 25% IPv6),
+// forzando que una de cada cuatro caiga dentro de un rango de Cloudflare
+func benchCorpus(b testing.TB, ranges domain.CFRanges, n int) []string {
+	b.Helper()
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	v4 := mustPrefixes(b, ranges.IPv4)
+	v6 := mustPrefixes(b, ranges.IPv6)
+
+	corpus := make([]string, n)
+	for i := range corpus {
+		var addr netip.Addr
+		if i%4 == 3 {
+			var raw [16]byte
+			for j := range raw {
+				raw[j] = byte(rng.Uint32())
+			}
+			if i%8 == 7 {
+				base := v6[rng.IntN(len(v6))].Addr().As16()
+				copy(raw[:4], base[:4])
+			}
+			addr = netip.AddrFrom16(raw)
+		} else {
+			var raw [4]byte
+			for j := range raw {
+				raw[j] = byte(rng.Uint32())
+			}
+			if i%4 == 0 {
+				base := v4[rng.IntN(len(v4))].Addr().As4()
+				copy(raw[:2], base[:2])
+			}
+			addr = netip.AddrFrom4(raw)
+		}
+		corpus[i] = addr.String()
+	}
+	return corpus
+}
+
+func mustPrefixes(b testing.TB, cidrs []string) []netip.Prefix {
+	b.Helper()
+	out := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			b.Fatalf("CIDR inválido %s: %v", cidr, err)
+		}
+		out = append(out, prefix)
+	}
+	return out
+}
+
+func newBenchService(b testing.TB) (*Service, domain.CFRanges) {
+	b.Helper()
+	binder, err := network.NewBinder(domain.ScannerConfig{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	s := NewService(zerolog.Nop(), binder)
+	return s, s.loadDefaultRanges()
+}
+
+// linearMatch replica la clasificación anterior: parsear los CIDR en cada
+// llamada y recorrerlos linealmente
+func linearMatch(s *Service, ip string, ranges domain.CFRanges) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	cidrs := ranges.IPv6
+	if addr.Is4() {
+		cidrs = ranges.IPv4
+	}
+	for _, prefix := range s.parsePrefixes(cidrs) {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// compiledMatch clasifica como el escaneo: trie compilado una vez y una
+// búsqueda por IP en texto
+func compiledMatch(trie *iptrie.Trie[string], ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	_, _, ok := trie.Lookup(addr)
+	return ok
+}
+
+func TestCompileMatchesLinear(t *testing.T) {
+	s, ranges := newBenchService(t)
+	trie := s.Compile(ranges)
+	if trie.Len() != len(ranges.IPv4)+len(ranges.IPv6) {
+		t.Errorf("trie con %d prefijos, esperados %d", trie.Len(), len(ranges.IPv4)+len(ranges.IPv6))
+	}
+
+	for _, ip := range benchCorpus(t, ranges, 10_000) {
+		if compiledMatch(trie, ip) != linearMatch(s, ip, ranges) {
+			t.Fatalf("clasificación distinta para %s", ip)
+		}
+	}
+
+	// Un CIDR inválido se descarta sin afectar al resto
+	trie = s.Compile(domain.CFRanges{IPv4: []string{"104.16.0.0/13", "no-es-un-cidr"}})
+	if trie.Len() != 1 {
+		t.Errorf("trie con %d prefijos, esperado 1", trie.Len())
+	}
+	prefix, owner, ok := trie.Lookup(netip.MustParseAddr("104.16.132.229"))
+	if !ok || owner != Owner || prefix.String() != "104.16.0.0/13" {
+		t.Errorf("Lookup = %s %q %v", prefix, owner, ok)
+	}
+}
+
+func BenchmarkCompiledMatch(b *testing.B) {
+	s, ranges := newBenchService(b)
+	corpus := benchCorpus(b, ranges, benchLookups)
+	trie := s.Compile(ranges)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		compiledMatch(trie, corpus[i%benchLookups])
+	}
+}
+
+func BenchmarkLinearMatch(b *testing.B) {
+	s, ranges := newBenchService(b)
+	corpus := benchCorpus(b, ranges, benchLookups)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		linearMatch(s, corpus[i%benchLookups], ranges)
+	}
+}
+
+// BenchmarkTrieLookup1M mide el costo de 1M búsquedas sobre direcciones ya
+// parseadas; ns/lookup es el costo por búsqueda
+func BenchmarkTrieLookup1M(b *testing.B) {
+	s, ranges := newBenchService(b)
+	corpus := benchCorpus(b, ranges, benchLookups)
+	addrs := make([]netip.Addr, len(corpus))
+	for i, ip := range corpus {
+		addrs[i] = netip.MustParseAddr(ip)
+	}
+	trie := s.Compile(ranges)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, addr := range addrs {
+			trie.Lookup(addr)
+		}
+	}
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*benchLookups), "ns/lookup")
+}
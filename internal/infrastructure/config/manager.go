@@ -2,10 +2,16 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/portscan"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,27 +22,56 @@ type ConfigManager struct {
 func NewConfigManager() *ConfigManager {
 	return &ConfigManager{
 		defaultConfig: domain.ScannerConfig{
-			Threads:     10,
-			Retries:     2,
-			Backoff:     500 * time.Millisecond,
-			Timeout:     5 * time.Second,
-			Delay:       0,
-			FollowCNAME: false,
-			IncludeCF:   false,
-			NoFetchCF:   false,
-			OutputFmt:   "text",
-			Wordlist:    "dom.txt",
+			Threads:         10,
+			Retries:         2,
+			Backoff:         500 * time.Millisecond,
+			Timeout:         5 * time.Second,
+			Delay:           0,
+			FollowCNAME:     false,
+			IncludeCF:       false,
+			NoFetchCF:       false,
+			OutputFmt:       "text",
+			Wordlist:        "dom.txt",
+			IPFamily:        domain.IPFamilyAny,
+			CacheDir:        DefaultCacheDir(),
+			RangesMaxAge:    24 * time.Hour,
+			ProbeTimeout:    5 * time.Second,
+			EnrichThreads:   20,
+			EnrichTimeout:   10 * time.Second,
+			MaxCandidates:   50,
+			SPFMaxLookups:   10,
+			TopCandidates:   5,
+			VHostMax:        500,
+			SweepPrefix:     24,
+			SweepMax:        256,
+			SweepRate:       20,
+			ScanPorts:       portscan.DefaultPorts,
+			PortConcurrency: 200,
+			PortTimeout:     1500 * time.Millisecond,
+			TLSPorts:        []int{443},
 		},
 	}
 }
 
+// DefaultCacheDir retorna el directorio de caché del usuario para cloudrip
+// (ej: ~/.cache/cloudrip); vacío si no se puede determinar
+func DefaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "cloudrip")
+}
+
 func (cm *ConfigManager) LoadFromFile(path string) (*domain.ScannerConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("error leyendo archivo de configuración: %w", err)
 	}
 
-	var config domain.ScannerConfig
+	// cache_dir vacío deshabilita la caché, igual que -cache-dir "": el
+	// default se aplica solo si la clave no está en el archivo
+	config := domain.ScannerConfig{CacheDir: cm.defaultConfig.CacheDir}
 
 	// Intentar parsear como YAML primero
 	if err := yaml.Unmarshal(data, &config); err != nil {
@@ -92,6 +127,126 @@ func (cm *ConfigManager) Validate(config *domain.ScannerConfig) error {
 		return fmt.Errorf("formato de salida inválido: %s. Debe ser 'text' o 'json'", config.OutputFmt)
 	}
 
+	// Validar opciones de red
+	switch config.IPFamily {
+	case "", domain.IPFamilyAny, domain.IPFamilyIPv4, domain.IPFamilyIPv6:
+	default:
+		return fmt.Errorf("familia de direcciones inválida: %s. Debe ser 'any', 'ipv4' o 'ipv6'", config.IPFamily)
+	}
+	if config.Resolver != "" {
+		host := config.Resolver
+		if h, _, err := net.SplitHostPort(config.Resolver); err == nil {
+			host = h
+		}
+		if _, err := netip.ParseAddr(host); err != nil {
+			return fmt.Errorf("servidor DNS inválido: %s", config.Resolver)
+		}
+	}
+	if config.SourceIP != "" {
+		addr, err := netip.ParseAddr(config.SourceIP)
+		if err != nil {
+			return fmt.Errorf("IP de origen inválida: %s", config.SourceIP)
+		}
+		addr = addr.Unmap()
+		if (config.IPFamily == domain.IPFamilyIPv4 && !addr.Is4()) || (config.IPFamily == domain.IPFamilyIPv6 && !addr.Is6()) {
+			return fmt.Errorf("la IP de origen %s no corresponde a la familia %s", config.SourceIP, config.IPFamily)
+		}
+	}
+
+	// Validar transcripts DNS
+	if config.RecordDNS != "" && config.ReplayDNS != "" {
+		return fmt.Errorf("no se puede grabar y reproducir un transcript DNS a la vez")
+	}
+	if config.ReplayDNS != "" {
+		if _, err := os.Stat(config.ReplayDNS); os.IsNotExist(err) {
+			return fmt.Errorf("el transcript DNS no existe: %s", config.ReplayDNS)
+		}
+		// Un replay debe ser reproducible: las etapas que salen a la red no
+		// están en el transcript
+		if stages := networkStages(config); len(stages) > 0 {
+			return fmt.Errorf("replay_dns no es compatible con etapas que usan la red: %s", strings.Join(stages, ", "))
+		}
+	}
+
+	// Validar rangos
+	if config.RangesMaxAge < 0 {
+		return fmt.Errorf("ranges_max_age no puede ser negativo")
+	}
+	if config.RangesFile != "" {
+		if _, err := os.Stat(config.RangesFile); os.IsNotExist(err) {
+			return fmt.Errorf("el archivo de rangos no existe: %s", config.RangesFile)
+		}
+	}
+	if config.CloudRangesDir != "" {
+		if info, err := os.Stat(config.CloudRangesDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("el directorio de rangos cloud no existe: %s", config.CloudRangesDir)
+		}
+	}
+
+	if config.CFAPIURL != "" {
+		if u, err := url.Parse(config.CFAPIURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("cf_api_url inválida: %s", config.CFAPIURL)
+		}
+	}
+
+	if config.EnrichThreads < 0 {
+		return fmt.Errorf("enrich_threads no puede ser negativo")
+	}
+	if config.EnrichTimeout < 0 {
+		return fmt.Errorf("enrich_timeout no puede ser negativo")
+	}
+	if config.ProbeTimeout < 0 {
+		return fmt.Errorf("probe_timeout no puede ser negativo")
+	}
+	if config.MaxCandidates < 0 {
+		return fmt.Errorf("max_candidates no puede ser negativo")
+	}
+	if config.SPFMaxLookups < 0 {
+		return fmt.Errorf("spf_max_lookups no puede ser negativo")
+	}
+	// En el archivo de configuración 0 equivale a omitir la clave (ver
+	// applyDefaults); por CLI se rechaza
+	if config.SweepPrefix < 20 || config.SweepPrefix > 31 {
+		return fmt.Errorf("sweep_prefix debe estar entre 20 y 31")
+	}
+	if config.SweepMax < 1 || config.SweepMax > 4096 {
+		return fmt.Errorf("sweep_max debe estar entre 1 y 4096")
+	}
+	if config.SweepRate < 1 || config.SweepRate > 100 {
+		return fmt.Errorf("sweep_rate debe estar entre 1 y 100 conexiones por segundo")
+	}
+	if config.ScanPorts != "" {
+		if _, err := portscan.ParsePorts(config.ScanPorts); err != nil {
+			return fmt.Errorf("scan_ports inválido: %w", err)
+		}
+	}
+	if config.VHostMax < 0 {
+		return fmt.Errorf("vhost_max no puede ser negativo")
+	}
+	if config.PortConcurrency < 0 {
+		return fmt.Errorf("port_concurrency no puede ser negativo")
+	}
+	if config.PortTimeout < 0 {
+		return fmt.Errorf("port_timeout no puede ser negativo")
+	}
+	if config.TopCandidates < 0 {
+		return fmt.Errorf("top_candidates no puede ser negativo")
+	}
+	known := domain.DefaultScoreWeights()
+	for name, weight := range config.ScoreWeights {
+		if _, ok := known[strings.ToLower(name)]; !ok {
+			return fmt.Errorf("señal de puntaje desconocida: %s", name)
+		}
+		if weight < 0 {
+			return fmt.Errorf("peso negativo para la señal %s", name)
+		}
+	}
+	for _, port := range config.TLSPorts {
+		if port < 1 || port > 65535 {
+			return fmt.Errorf("puerto TLS inválido: %d", port)
+		}
+	}
+
 	// Validar que el wordlist existe si se especificó
 	if config.Wordlist != "" {
 		if _, err := os.Stat(config.Wordlist); os.IsNotExist(err) {
@@ -102,6 +257,34 @@ func (cm *ConfigManager) Validate(config *domain.ScannerConfig) error {
 	return nil
 }
 
+// networkStages retorna las etapas habilitadas que consultan la red fuera del
+// resolver DNS
+func networkStages(config *domain.ScannerConfig) []string {
+	stages := []struct {
+		enabled bool
+		name    string
+	}{
+		{config.HTTPProbe, "http_probe"},
+		{config.VerifyOrigins, "verify_origins"},
+		{config.Favicons, "favicons"},
+		{config.TLSProbe, "tls_probe"},
+		{config.Sweep, "sweep"},
+		{config.PortScan, "port_scan"},
+		{config.VHosts, "vhosts"},
+		{config.Enrich, "enrich"},
+		{config.Tech, "tech"},
+		{config.CFAudit, "cf_audit"},
+		{config.CloudRangesDir != "", "cloud_ranges_dir"},
+	}
+	var enabled []string
+	for _, stage := range stages {
+		if stage.enabled {
+			enabled = append(enabled, stage.name)
+		}
+	}
+	return enabled
+}
+
 func (cm *ConfigManager) applyDefaults(config domain.ScannerConfig) domain.ScannerConfig {
 	if config.Threads == 0 {
 		config.Threads = cm.defaultConfig.Threads
@@ -121,6 +304,54 @@ func (cm *ConfigManager) applyDefaults(config domain.ScannerConfig) domain.Scann
 	if config.Wordlist == "" {
 		config.Wordlist = cm.defaultConfig.Wordlist
 	}
+	if config.IPFamily == "" {
+		config.IPFamily = cm.defaultConfig.IPFamily
+	}
+	if config.RangesMaxAge == 0 {
+		config.RangesMaxAge = cm.defaultConfig.RangesMaxAge
+	}
+	if config.EnrichThreads == 0 {
+		config.EnrichThreads = cm.defaultConfig.EnrichThreads
+	}
+	if config.EnrichTimeout == 0 {
+		config.EnrichTimeout = cm.defaultConfig.EnrichTimeout
+	}
+	if config.ProbeTimeout == 0 {
+		config.ProbeTimeout = cm.defaultConfig.ProbeTimeout
+	}
+	if config.MaxCandidates == 0 {
+		config.MaxCandidates = cm.defaultConfig.MaxCandidates
+	}
+	if config.SPFMaxLookups == 0 {
+		config.SPFMaxLookups = cm.defaultConfig.SPFMaxLookups
+	}
+	if config.TopCandidates == 0 {
+		config.TopCandidates = cm.defaultConfig.TopCandidates
+	}
+	if config.ScanPorts == "" {
+		config.ScanPorts = cm.defaultConfig.ScanPorts
+	}
+	if config.PortConcurrency == 0 {
+		config.PortConcurrency = cm.defaultConfig.PortConcurrency
+	}
+	if config.PortTimeout == 0 {
+		config.PortTimeout = cm.defaultConfig.PortTimeout
+	}
+	if config.VHostMax == 0 {
+		config.VHostMax = cm.defaultConfig.VHostMax
+	}
+	if config.SweepPrefix == 0 {
+		config.SweepPrefix = cm.defaultConfig.SweepPrefix
+	}
+	if config.SweepMax == 0 {
+		config.SweepMax = cm.defaultConfig.SweepMax
+	}
+	if config.SweepRate == 0 {
+		config.SweepRate = cm.defaultConfig.SweepRate
+	}
+	if len(config.TLSPorts) == 0 {
+		config.TLSPorts = cm.defaultConfig.TLSPorts
+	}
 
 	return config
 }
@@ -128,18 +359,34 @@ func (cm *ConfigManager) applyDefaults(config domain.ScannerConfig) domain.Scann
 // CreateDefaultConfig crea un archivo de configuración por defecto
 func (cm *ConfigManager) CreateDefaultConfig(path string) error {
 	defaultConfig := &domain.ScannerConfig{
-		Domain:      "example.com",
-		Wordlist:    "dom.txt",
-		Threads:     10,
-		Retries:     2,
-		Backoff:     500 * time.Millisecond,
-		Timeout:     5 * time.Second,
-		Delay:       0,
-		FollowCNAME: false,
-		IncludeCF:   false,
-		NoFetchCF:   false,
-		Output:      "results.txt",
-		OutputFmt:   "text",
+		Domain:          "example.com",
+		Wordlist:        "dom.txt",
+		Threads:         10,
+		Retries:         2,
+		Backoff:         500 * time.Millisecond,
+		Timeout:         5 * time.Second,
+		Delay:           0,
+		FollowCNAME:     false,
+		IncludeCF:       false,
+		NoFetchCF:       false,
+		Output:          "results.txt",
+		OutputFmt:       "text",
+		IPFamily:        domain.IPFamilyAny,
+		CacheDir:        DefaultCacheDir(),
+		RangesMaxAge:    24 * time.Hour,
+		ProbeTimeout:    5 * time.Second,
+		EnrichThreads:   20,
+		EnrichTimeout:   10 * time.Second,
+		MaxCandidates:   50,
+		SPFMaxLookups:   10,
+		TopCandidates:   5,
+		VHostMax:        500,
+		SweepPrefix:     24,
+		SweepMax:        256,
+		SweepRate:       20,
+		ScanPorts:       portscan.DefaultPorts,
+		PortConcurrency: 200,
+		PortTimeout:     1500 * time.Millisecond,
 	}
 
 	return cm.SaveToFile(defaultConfig, path)
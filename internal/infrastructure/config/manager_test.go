@@ -0,0 +1,71 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFromFileCacheDir(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want string
+	}{
+		{"sin clave usa el default", "domain: example.com\n", DefaultCacheDir()},
+		{"vacío deshabilita la caché", "domain: example.com\ncache_dir: \"\"\n", ""},
+		{"explícito", "domain: example.com\ncache_dir: /tmp/cloudrip\n", "/tmp/cloudrip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			wordlist := filepath.Join(dir, "dom.txt")
+			if err := os.WriteFile(wordlist, []byte("www\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml+"wordlist: "+wordlist+"\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			config, err := NewConfigManager().LoadFromFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.CacheDir != tt.want {
+				t.Errorf("CacheDir = %q, esperado %q", config.CacheDir, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSweep(t *testing.T) {
+	wordlist := filepath.Join(t.TempDir(), "dom.txt")
+	if err := os.WriteFile(wordlist, []byte("www\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name              string
+		prefix, max, rate int
+		ok                bool
+	}{
+		{"defaults", 24, 256, 20, true},
+		{"límites", 20, 4096, 100, true},
+		{"límites inferiores", 31, 1, 1, true},
+		{"prefix 0", 0, 256, 20, false},
+		{"prefix 32", 32, 256, 20, false},
+		{"max 0", 24, 0, 20, false},
+		{"max 4097", 24, 4097, 20, false},
+		{"rate 0", 24, 256, 0, false},
+		{"rate 101", 24, 256, 101, false},
+	}
+	for _, tt := range tests {
+		manager := NewConfigManager()
+		config := manager.defaultConfig
+		config.Domain, config.Wordlist = "example.com", wordlist
+		config.SweepPrefix, config.SweepMax, config.SweepRate = tt.prefix, tt.max, tt.rate
+		if err := manager.Validate(&config); (err == nil) != tt.ok {
+			t.Errorf("%s: Validate = %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// PacketSink recibe cada mensaje DNS que el resolver intercambia en la red
+type PacketSink interface {
+	WritePacket(ts time.Time, src, dst netip.AddrPort, payload []byte) error
+}
+
+// SetCapture envía a sink cada consulta y respuesta DNS. Debe llamarse antes
+// de iniciar el escaneo. Los mensajes que viajan por TCP se registran sin el
+// prefijo de longitud, como si fueran datagramas UDP.
+func (r *Resolver) SetCapture(sink PacketSink) {
+	dial := r.resolver.Dial
+	r.resolver.Dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+		conn, err := dial(ctx, network, address)
+		if err != nil {
+			return nil, err
+		}
+		cc := newCaptureConn(conn, sink, r.logger)
+		// El resolver Go distingue UDP de TCP por la interfaz net.PacketConn
+		if pc, ok := conn.(net.PacketConn); ok {
+			return &capturePacketConn{captureConn: cc, pc: pc}, nil
+		}
+		return cc, nil
+	}
+}
+
+type captureConn struct {
+	net.Conn
+	sink   PacketSink
+	stream bool
+	local  netip.AddrPort
+	remote netip.AddrPort
+	logger zerolog.Logger
+
+	mu   sync.Mutex
+	wbuf []byte
+	rbuf []byte
+}
+
+func newCaptureConn(conn net.Conn, sink PacketSink, logger zerolog.Logger) *captureConn {
+	_, stream := conn.(*net.TCPConn)
+	return &captureConn{
+		Conn:   conn,
+		sink:   sink,
+		stream: stream,
+		local:  addrPort(conn.LocalAddr()),
+		remote: addrPort(conn.RemoteAddr()),
+		logger: logger,
+	}
+}
+
+func (c *captureConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.capture(b[:n], true)
+	}
+	return n, err
+}
+
+func (c *captureConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.capture(b[:n], false)
+	}
+	return n, err
+}
+
+// capturePacketConn conserva la interfaz net.PacketConn de las conexiones UDP
+type capturePacketConn struct {
+	*captureConn
+	pc net.PacketConn
+}
+
+func (c *capturePacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	n, addr, err := c.pc.ReadFrom(b)
+	if n > 0 {
+		c.capture(b[:n], false)
+	}
+	return n, addr, err
+}
+
+func (c *capturePacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	n, err := c.pc.WriteTo(b, addr)
+	if n > 0 {
+		c.capture(b[:n], true)
+	}
+	return n, err
+}
+
+func (c *captureConn) capture(data []byte, outgoing bool) {
+	src, dst := c.remote, c.local
+	if outgoing {
+		src, dst = c.local, c.remote
+	}
+
+	if !c.stream {
+		c.emit(src, dst, data)
+		return
+	}
+
+	// TCP: reensamblar mensajes usando el prefijo de 2 bytes
+	c.mu.Lock()
+	buf := &c.rbuf
+	if outgoing {
+		buf = &c.wbuf
+	}
+	*buf = append(*buf, data...)
+	var messages [][]byte
+	for len(*buf) >= 2 {
+		size := int(binary.BigEndian.Uint16(*buf))
+		if len(*buf) < 2+size {
+			break
+		}
+		messages = append(messages, append([]byte(nil), (*buf)[2:2+size]...))
+		*buf = (*buf)[2+size:]
+	}
+	c.mu.Unlock()
+
+	for _, msg := range messages {
+		c.emit(src, dst, msg)
+	}
+}
+
+func (c *captureConn) emit(src, dst netip.AddrPort, payload []byte) {
+	if err := c.sink.WritePacket(time.Now(), src, dst, payload); err != nil {
+		c.logger.Debug().Err(err).Msg("Error capturando paquete DNS")
+	}
+}
+
+func addrPort(addr net.Addr) netip.AddrPort {
+	switch a := addr.(type) {
+	case *net.UDPAddr:
+		return a.AddrPort()
+	case *net.TCPAddr:
+		return a.AddrPort()
+	default:
+		ap, _ := netip.ParseAddrPort(addr.String())
+		return ap
+	}
+}
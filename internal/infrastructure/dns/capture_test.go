@@ -0,0 +1,89 @@
+package dns
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// memorySink guarda los paquetes capturados
+type memorySink struct {
+	mu      sync.Mutex
+	packets []capturedPacket
+}
+
+type capturedPacket struct {
+	src, dst netip.AddrPort
+	payload  string
+}
+
+func (s *memorySink) WritePacket(_ time.Time, src, dst netip.AddrPort, payload []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.packets = append(s.packets, capturedPacket{src: src, dst: dst, payload: string(payload)})
+	return nil
+}
+
+func TestCaptureKeepsPacketConn(t *testing.T) {
+	server, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	go func() {
+		buf := make([]byte, 512)
+		n, addr, err := server.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		_, _ = server.WriteTo([]byte("pong:"+string(buf[:n])), addr)
+	}()
+
+	sink := &memorySink{}
+	r := &Resolver{
+		resolver: &net.Resolver{Dial: (&net.Dialer{}).DialContext},
+		logger:   zerolog.Nop(),
+	}
+	r.SetCapture(sink)
+
+	conn, err := r.resolver.Dial(context.Background(), "udp", server.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Sin net.PacketConn el resolver Go trataría la conexión como TCP
+	pc, ok := conn.(net.PacketConn)
+	if !ok {
+		t.Fatalf("la conexión capturada %T no implementa net.PacketConn", conn)
+	}
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 512)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil || string(buf[:n]) != "pong:ping" {
+		t.Fatalf("ReadFrom = %q, %v", buf[:n], err)
+	}
+
+	local := addrPort(conn.LocalAddr())
+	remote := addrPort(server.LocalAddr())
+	want := []capturedPacket{
+		{src: local, dst: remote, payload: "ping"},
+		{src: remote, dst: local, payload: "pong:ping"},
+	}
+	if len(sink.packets) != len(want) {
+		t.Fatalf("%d paquetes capturados, esperados %d", len(sink.packets), len(want))
+	}
+	for i, p := range want {
+		if sink.packets[i] != p {
+			t.Errorf("paquete %d = %+v, esperado %+v", i, sink.packets[i], p)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/ports"
+	"github.com/rs/zerolog"
+)
+
+// dropWait es la espera de una respuesta descartada cuando el contexto no tiene deadline
+const dropWait = 5 * time.Second
+
+// FaultConfig describe las fallas a inyectar. Los porcentajes van de 0 a 100.
+type FaultConfig struct {
+	Latency  LatencyDist
+	Timeout  float64
+	ServFail float64
+	Truncate float64
+	Drop     float64
+	// Seed fija la secuencia de fallas; nil usa una semilla aleatoria
+	Seed *uint64
+}
+
+// LatencyDist es una distribución de latencia: fixed, uniform, normal o exp
+type LatencyDist struct {
+	Kind   string
+	Min    time.Duration
+	Max    time.Duration
+	Mean   time.Duration
+	StdDev time.Duration
+}
+
+// FaultStats cuenta las fallas inyectadas
+type FaultStats struct {
+	Queries   int `json:"queries"`
+	Timeouts  int `json:"timeouts"`
+	ServFails int `json:"servfails"`
+	Truncated int `json:"truncated"`
+	Dropped   int `json:"dropped"`
+}
+
+// ParseFaultSpec interpreta especificaciones como
+// "latency=uniform:10ms-200ms,timeout=5%,servfail=2%,truncate=1%,drop=3%,seed=42".
+// Latencias: fixed:50ms | uniform:10ms-200ms | normal:100ms/30ms | exp:50ms
+func ParseFaultSpec(spec string) (FaultConfig, error) {
+	var cfg FaultConfig
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return cfg, fmt.Errorf("falla inválida %q: se espera clave=valor", part)
+		}
+
+		var err error
+		switch strings.ToLower(key) {
+		case "latency":
+			cfg.Latency, err = parseLatency(value)
+		case "timeout":
+			cfg.Timeout, err = parsePercent(value)
+		case "servfail":
+			cfg.ServFail, err = parsePercent(value)
+		case "truncate":
+			cfg.Truncate, err = parsePercent(value)
+		case "drop":
+			cfg.Drop, err = parsePercent(value)
+		case "seed":
+			var seed uint64
+			if seed, err = strconv.ParseUint(value, 10, 64); err == nil {
+				cfg.Seed = &seed
+			}
+		default:
+			err = fmt.Errorf("clave desconocida")
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("falla inválida %q: %w", part, err)
+		}
+	}
+
+	if total := cfg.Timeout + cfg.ServFail + cfg.Truncate + cfg.Drop; total > 100 {
+		return cfg, fmt.Errorf("la suma de porcentajes de fallas (%.1f%%) supera 100%%", total)
+	}
+
+	return cfg, nil
+}
+
+func parsePercent(value string) (float64, error) {
+	p, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64)
+	if err != nil {
+		return 0, err
+	}
+	if p < 0 || p > 100 {
+		return 0, fmt.Errorf("porcentaje fuera de rango: %s", value)
+	}
+	return p, nil
+}
+
+func parseLatency(value string) (LatencyDist, error) {
+	kind, args, ok := strings.Cut(value, ":")
+	if !ok {
+		return LatencyDist{}, fmt.Errorf("se espera tipo:parámetros")
+	}
+
+	dist := LatencyDist{Kind: kind}
+	var err error
+	switch kind {
+	case "fixed", "exp":
+		dist.Mean, err = time.ParseDuration(args)
+	case "uniform":
+		lo, hi, found := strings.Cut(args, "-")
+		if !found {
+			return dist, fmt.Errorf("uniform requiere min-max")
+		}
+		if dist.Min, err = time.ParseDuration(lo); err == nil {
+			dist.Max, err = time.ParseDuration(hi)
+		}
+		if err == nil && dist.Max < dist.Min {
+			err = fmt.Errorf("max menor que min")
+		}
+	case "normal":
+		mean, stddev, found := strings.Cut(args, "/")
+		if !found {
+			return dist, fmt.Errorf("normal requiere media/desviación")
+		}
+		if dist.Mean, err = time.ParseDuration(mean); err == nil {
+			dist.StdDev, err = time.ParseDuration(stddev)
+		}
+	default:
+		err = fmt.Errorf("distribución desconocida: %s", kind)
+	}
+
+	return dist, err
+}
+
+// FaultInjector decora un ports.DNSResolver agregando latencia y fallas
+// (timeouts, SERVFAIL, truncamiento y respuestas descartadas) por porcentaje
+type FaultInjector struct {
+	inner  ports.DNSResolver
+	config FaultConfig
+	mu     sync.Mutex
+	rng    *rand.Rand
+	stats  FaultStats
+	logger zerolog.Logger
+}
+
+func NewFaultInjector(inner ports.DNSResolver, config FaultConfig, logger zerolog.Logger) *FaultInjector {
+	seed := uint64(time.Now().UnixNano())
+	if config.Seed != nil {
+		seed = *config.Seed
+	}
+	return &FaultInjector{
+		inner:  inner,
+		config: config,
+		rng:    rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15)),
+		logger: logger,
+	}
+}
+
+// Fallas que puede sufrir una consulta
+const (
+	faultNone = iota
+	faultTimeout
+	faultServFail
+	faultTruncate
+	faultDrop
+)
+
+func (f *FaultInjector) LookupIP(ctx context.Context, fqdn string) ([]string, error) {
+	fault, err := f.inject(ctx, fqdn)
+	if err != nil {
+		return nil, err
+	}
+
+	ips, err := f.inner.LookupIP(ctx, fqdn)
+	if err == nil && fault == faultTruncate && len(ips) > 1 {
+		// Respuesta truncada: solo sobrevive el primer registro
+		ips = ips[:1]
+	}
+	return ips, err
+}
+
+func (f *FaultInjector) LookupCNAME(ctx context.Context, fqdn string) (string, error) {
+	fault, err := f.inject(ctx, fqdn)
+	if err != nil {
+		return "", err
+	}
+	if fault == faultTruncate {
+		return "", nil
+	}
+	return f.inner.LookupCNAME(ctx, fqdn)
+}
+
+func (f *FaultInjector) LookupTXT(ctx context.Context, fqdn string) ([]string, error) {
+	fault, err := f.inject(ctx, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	records, err := f.inner.LookupTXT(ctx, fqdn)
+	if err == nil && fault == faultTruncate && len(records) > 1 {
+		records = records[:1]
+	}
+	return records, err
+}
+
+func (f *FaultInjector) LookupMX(ctx context.Context, fqdn string) ([]string, error) {
+	fault, err := f.inject(ctx, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	hosts, err := f.inner.LookupMX(ctx, fqdn)
+	if err == nil && fault == faultTruncate && len(hosts) > 1 {
+		hosts = hosts[:1]
+	}
+	return hosts, err
+}
+
+func (f *FaultInjector) LookupAddr(ctx context.Context, ip string) ([]string, error) {
+	fault, err := f.inject(ctx, ip)
+	if err != nil {
+		return nil, err
+	}
+	names, err := f.inner.LookupAddr(ctx, ip)
+	if err == nil && fault == faultTruncate && len(names) > 1 {
+		names = names[:1]
+	}
+	return names, err
+}
+
+// inject aplica la latencia y decide la falla de la consulta
+func (f *FaultInjector) inject(ctx context.Context, fqdn string) (int, error) {
+	f.mu.Lock()
+	delay := f.sampleLatency()
+	roll := f.rng.Float64() * 100
+	fault := faultNone
+	switch {
+	case roll < f.config.Timeout:
+		fault = faultTimeout
+		f.stats.Timeouts++
+	case roll < f.config.Timeout+f.config.ServFail:
+		fault = faultServFail
+		f.stats.ServFails++
+	case roll < f.config.Timeout+f.config.ServFail+f.config.Truncate:
+		fault = faultTruncate
+		f.stats.Truncated++
+	case roll < f.config.Timeout+f.config.ServFail+f.config.Truncate+f.config.Drop:
+		fault = faultDrop
+		f.stats.Dropped++
+	}
+	f.stats.Queries++
+	f.mu.Unlock()
+
+	if fault == faultDrop {
+		// Sin respuesta: esperar hasta que el cliente se rinda
+		wait := dropWait
+		if deadline, ok := ctx.Deadline(); ok {
+			wait = time.Until(deadline)
+		}
+		delay = max(delay, wait)
+	}
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fault, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	timeoutErr := &net.DNSError{Err: "i/o timeout", Name: fqdn, Server: "fault-injector", IsTimeout: true, IsTemporary: true}
+	switch fault {
+	case faultTimeout:
+		f.logger.Debug().Str("fqdn", fqdn).Msg("Falla inyectada: timeout")
+		return fault, timeoutErr
+	case faultDrop:
+		f.logger.Debug().Str("fqdn", fqdn).Msg("Falla inyectada: respuesta descartada")
+		return fault, timeoutErr
+	case faultServFail:
+		f.logger.Debug().Str("fqdn", fqdn).Msg("Falla inyectada: SERVFAIL")
+		return fault, &net.DNSError{Err: "server misbehaving", Name: fqdn, Server: "fault-injector", IsTemporary: true}
+	}
+	return fault, nil
+}
+
+// sampleLatency debe llamarse con f.mu tomado
+func (f *FaultInjector) sampleLatency() time.Duration {
+	d := f.config.Latency
+	switch d.Kind {
+	case "fixed":
+		return d.Mean
+	case "uniform":
+		return d.Min + time.Duration(f.rng.Int64N(int64(d.Max-d.Min)+1))
+	case "normal":
+		v := float64(d.Mean) + f.rng.NormFloat64()*float64(d.StdDev)
+		return time.Duration(math.Max(0, v))
+	case "exp":
+		return time.Duration(f.rng.ExpFloat64() * float64(d.Mean))
+	default:
+		return 0
+	}
+}
+
+// Stats retorna los contadores de fallas inyectadas
+func (f *FaultInjector) Stats() FaultStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stats
+}
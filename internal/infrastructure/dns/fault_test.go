@@ -0,0 +1,196 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParseFaultSpec(t *testing.T) {
+	seed := func(v uint64) *uint64 { return &v }
+	tests := []struct {
+		spec    string
+		want    FaultConfig
+		wantErr bool
+	}{
+		{spec: "", want: FaultConfig{}},
+		{
+			spec: "latency=uniform:10ms-200ms, timeout=5%,servfail=2,truncate=1%,drop=3.5%,seed=42",
+			want: FaultConfig{
+				Latency:  LatencyDist{Kind: "uniform", Min: 10 * time.Millisecond, Max: 200 * time.Millisecond},
+				Timeout:  5,
+				ServFail: 2,
+				Truncate: 1,
+				Drop:     3.5,
+				Seed:     seed(42),
+			},
+		},
+		{spec: "seed=0", want: FaultConfig{Seed: seed(0)}},
+		{spec: "latency=fixed:50ms", want: FaultConfig{Latency: LatencyDist{Kind: "fixed", Mean: 50 * time.Millisecond}}},
+		{spec: "latency=normal:100ms/30ms", want: FaultConfig{Latency: LatencyDist{Kind: "normal", Mean: 100 * time.Millisecond, StdDev: 30 * time.Millisecond}}},
+		{spec: "latency=exp:50ms", want: FaultConfig{Latency: LatencyDist{Kind: "exp", Mean: 50 * time.Millisecond}}},
+		{spec: "timeout", wantErr: true},
+		{spec: "jitter=5%", wantErr: true},
+		{spec: "timeout=120%", wantErr: true},
+		{spec: "timeout=-1%", wantErr: true},
+		{spec: "timeout=60%,drop=50%", wantErr: true},
+		{spec: "latency=uniform:200ms-10ms", wantErr: true},
+		{spec: "latency=uniform:10ms", wantErr: true},
+		{spec: "latency=normal:100ms", wantErr: true},
+		{spec: "latency=pareto:1ms", wantErr: true},
+		{spec: "latency=50ms", wantErr: true},
+		{spec: "seed=-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFaultSpec(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseFaultSpec(%q) debería fallar", tt.spec)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseFaultSpec(%q): %v", tt.spec, err)
+			continue
+		}
+		if (got.Seed == nil) != (tt.want.Seed == nil) || (got.Seed != nil && *got.Seed != *tt.want.Seed) {
+			t.Errorf("ParseFaultSpec(%q) seed = %v, esperado %v", tt.spec, got.Seed, tt.want.Seed)
+		}
+		got.Seed, tt.want.Seed = nil, nil
+		if got != tt.want {
+			t.Errorf("ParseFaultSpec(%q) = %+v, esperado %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+// faultSequence retorna qué consultas fallaron con una semilla dada
+func faultSequence(t *testing.T, spec string) []bool {
+	t.Helper()
+	cfg, err := ParseFaultSpec(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := &stubResolver{ips: map[string][]string{"www.example.com": {"192.0.2.1"}}, calls: make(map[string]int)}
+	injector := NewFaultInjector(stub, cfg, zerolog.Nop())
+	var failed []bool
+	for range 50 {
+		_, err := injector.LookupIP(context.Background(), "www.example.com")
+		failed = append(failed, err != nil)
+	}
+	return failed
+}
+
+func TestFaultInjectorSeed(t *testing.T) {
+	for _, spec := range []string{"servfail=50%,seed=0", "servfail=50%,seed=42"} {
+		first, second := faultSequence(t, spec), faultSequence(t, spec)
+		if !slices.Equal(first, second) {
+			t.Errorf("%s: la misma semilla produjo secuencias distintas", spec)
+		}
+		if !slices.Contains(first, true) || !slices.Contains(first, false) {
+			t.Errorf("%s: se esperaban consultas fallidas y exitosas: %v", spec, first)
+		}
+	}
+	if slices.Equal(faultSequence(t, "servfail=50%,seed=0"), faultSequence(t, "servfail=50%,seed=42")) {
+		t.Error("semillas distintas produjeron la misma secuencia")
+	}
+}
+
+func TestFaultInjectorFaults(t *testing.T) {
+	ctx := context.Background()
+	stub := &stubResolver{
+		ips:    map[string][]string{"www.example.com": {"192.0.2.1", "192.0.2.2"}},
+		cnames: map[string]string{"www.example.com": "edge.cdn.net"},
+		txt:    map[string][]string{"example.com": {"v=spf1 -all", "google-site-verification=x"}},
+		calls:  make(map[string]int),
+	}
+
+	var dnsErr *net.DNSError
+	t.Run("timeout", func(t *testing.T) {
+		injector := NewFaultInjector(stub, FaultConfig{Timeout: 100}, zerolog.Nop())
+		if _, err := injector.LookupIP(ctx, "www.example.com"); !errors.As(err, &dnsErr) || !dnsErr.IsTimeout {
+			t.Errorf("se esperaba timeout, got %v", err)
+		}
+		if stats := injector.Stats(); stats.Queries != 1 || stats.Timeouts != 1 {
+			t.Errorf("stats = %+v", stats)
+		}
+	})
+
+	t.Run("servfail", func(t *testing.T) {
+		injector := NewFaultInjector(stub, FaultConfig{ServFail: 100}, zerolog.Nop())
+		_, err := injector.LookupTXT(ctx, "example.com")
+		if !errors.As(err, &dnsErr) || dnsErr.IsTimeout || dnsErr.IsNotFound || !dnsErr.IsTemporary {
+			t.Errorf("se esperaba SERVFAIL, got %v", err)
+		}
+		if stats := injector.Stats(); stats.ServFails != 1 {
+			t.Errorf("stats = %+v", stats)
+		}
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		injector := NewFaultInjector(stub, FaultConfig{Truncate: 100}, zerolog.Nop())
+		if ips, err := injector.LookupIP(ctx, "www.example.com"); err != nil || len(ips) != 1 {
+			t.Errorf("LookupIP truncado = %v, %v", ips, err)
+		}
+		if records, err := injector.LookupTXT(ctx, "example.com"); err != nil || len(records) != 1 {
+			t.Errorf("LookupTXT truncado = %v, %v", records, err)
+		}
+		if target, err := injector.LookupCNAME(ctx, "www.example.com"); err != nil || target != "" {
+			t.Errorf("LookupCNAME truncado = %q, %v", target, err)
+		}
+		if stats := injector.Stats(); stats.Queries != 3 || stats.Truncated != 3 {
+			t.Errorf("stats = %+v", stats)
+		}
+	})
+
+	t.Run("drop", func(t *testing.T) {
+		injector := NewFaultInjector(stub, FaultConfig{Drop: 100}, zerolog.Nop())
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if _, err := injector.LookupIP(ctx, "www.example.com"); err == nil {
+			t.Error("una respuesta descartada debería fallar")
+		}
+		// Espera hasta el deadline del contexto, no los 5s por defecto
+		if elapsed := time.Since(start); elapsed < 40*time.Millisecond || elapsed > time.Second {
+			t.Errorf("espera de %v con deadline de 50ms", elapsed)
+		}
+		if stats := injector.Stats(); stats.Dropped != 1 {
+			t.Errorf("stats = %+v", stats)
+		}
+	})
+
+	t.Run("latency", func(t *testing.T) {
+		cfg := FaultConfig{Latency: LatencyDist{Kind: "fixed", Mean: 30 * time.Millisecond}}
+		injector := NewFaultInjector(stub, cfg, zerolog.Nop())
+		start := time.Now()
+		if ips, err := injector.LookupIP(ctx, "www.example.com"); err != nil || len(ips) != 2 {
+			t.Errorf("LookupIP = %v, %v", ips, err)
+		}
+		if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+			t.Errorf("latencia de %v, esperada al menos 30ms", elapsed)
+		}
+	})
+}
+
+func TestSampleLatencyBounds(t *testing.T) {
+	seed := uint64(7)
+	cfg := FaultConfig{Latency: LatencyDist{Kind: "uniform", Min: 10 * time.Millisecond, Max: 20 * time.Millisecond}, Seed: &seed}
+	injector := NewFaultInjector(nil, cfg, zerolog.Nop())
+	for range 1000 {
+		if d := injector.sampleLatency(); d < 10*time.Millisecond || d > 20*time.Millisecond {
+			t.Fatalf("latencia uniforme fuera de rango: %v", d)
+		}
+	}
+
+	injector.config.Latency = LatencyDist{Kind: "normal", Mean: time.Millisecond, StdDev: 10 * time.Millisecond}
+	for range 1000 {
+		if d := injector.sampleLatency(); d < 0 {
+			t.Fatalf("latencia normal negativa: %v", d)
+		}
+	}
+}
@@ -6,27 +6,46 @@ import (
 	"strings"
 	"time"
 
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/network"
 	"github.com/rs/zerolog"
 )
 
 type Resolver struct {
 	resolver *net.Resolver
+	network  string
 	logger   zerolog.Logger
 }
 
-func NewResolver(logger zerolog.Logger) *Resolver {
+func NewResolver(logger zerolog.Logger, binder *network.Binder) *Resolver {
 	return &Resolver{
 		resolver: &net.Resolver{
 			PreferGo: true,
+			// La familia limita los registros consultados, no cómo se llega
+			// al servidor DNS
+			Dial: binder.DialResolver,
 		},
-		logger: logger,
+		network: binder.IPNetwork(),
+		logger:  logger,
+	}
+}
+
+// SetNameserver fuerza todas las consultas hacia addr (host o host:port)
+// en lugar de los servidores de /etc/resolv.conf
+func (r *Resolver) SetNameserver(addr string) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "53")
+	}
+	dial := r.resolver.Dial
+	r.resolver.Dial = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return dial(ctx, network, addr)
 	}
 }
 
 func (r *Resolver) LookupIP(ctx context.Context, fqdn string) ([]string, error) {
 	r.logger.Debug().Str("fqdn", fqdn).Msg("Resolviendo IPs")
 
-	ips, err := r.resolver.LookupIPAddr(ctx, fqdn)
+	// Con "ip4"/"ip6" el resolver Go solo consulta A o AAAA respectivamente
+	ips, err := r.resolver.LookupIP(ctx, r.network, fqdn)
 	if err != nil {
 		r.logger.Debug().Err(err).Str("fqdn", fqdn).Msg("Error resolviendo IPs")
 		return nil, err
@@ -34,7 +53,7 @@ func (r *Resolver) LookupIP(ctx context.Context, fqdn string) ([]string, error)
 
 	result := make([]string, 0, len(ips))
 	for _, ip := range ips {
-		result = append(result, ip.IP.String())
+		result = append(result, ip.String())
 	}
 
 	r.logger.Debug().Str("fqdn", fqdn).Int("ips", len(result)).Msg("IPs resueltas")
@@ -57,6 +76,48 @@ func (r *Resolver) LookupCNAME(ctx context.Context, fqdn string) (string, error)
 	return target, nil
 }
 
+func (r *Resolver) LookupTXT(ctx context.Context, fqdn string) ([]string, error) {
+	r.logger.Debug().Str("fqdn", fqdn).Msg("Resolviendo TXT")
+
+	records, err := r.resolver.LookupTXT(ctx, fqdn)
+	if err != nil {
+		r.logger.Debug().Err(err).Str("fqdn", fqdn).Msg("Error resolviendo TXT")
+		return nil, err
+	}
+	return records, nil
+}
+
+func (r *Resolver) LookupMX(ctx context.Context, fqdn string) ([]string, error) {
+	r.logger.Debug().Str("fqdn", fqdn).Msg("Resolviendo MX")
+
+	// net.Resolver ya ordena por preferencia
+	records, err := r.resolver.LookupMX(ctx, fqdn)
+	if err != nil {
+		r.logger.Debug().Err(err).Str("fqdn", fqdn).Msg("Error resolviendo MX")
+		return nil, err
+	}
+
+	hosts := make([]string, 0, len(records))
+	for _, mx := range records {
+		hosts = append(hosts, strings.TrimSuffix(mx.Host, "."))
+	}
+	return hosts, nil
+}
+
+func (r *Resolver) LookupAddr(ctx context.Context, ip string) ([]string, error) {
+	r.logger.Debug().Str("ip", ip).Msg("Resolviendo PTR")
+
+	names, err := r.resolver.LookupAddr(ctx, ip)
+	if err != nil {
+		r.logger.Debug().Err(err).Str("ip", ip).Msg("Error resolviendo PTR")
+		return nil, err
+	}
+	for i, name := range names {
+		names[i] = strings.TrimSuffix(name, ".")
+	}
+	return names, nil
+}
+
 // LookupIPWithRetry implementa reintentos con backoff
 func (r *Resolver) LookupIPWithRetry(ctx context.Context, fqdn string, retries int, backoff time.Duration) ([]string, error) {
 	var lastErr error
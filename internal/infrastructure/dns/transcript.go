@@ -0,0 +1,318 @@
+package dns
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/ports"
+	"github.com/rs/zerolog"
+)
+
+// Operaciones registradas en el transcript
+const (
+	opLookupIP    = "ip"
+	opLookupCNAME = "cname"
+	opLookupTXT   = "txt"
+	opLookupMX    = "mx"
+	opLookupPTR   = "ptr"
+)
+
+// transcriptEntry es una pregunta y su respuesta tal como las vio ports.DNSResolver.
+// Las claves son cortas para mantener el archivo compacto (una entrada JSON por línea).
+type transcriptEntry struct {
+	Op        string   `json:"op"`
+	Name      string   `json:"q"`
+	Answer    []string `json:"a,omitempty"`
+	Err       string   `json:"e,omitempty"`
+	NotFound  bool     `json:"nx,omitempty"`
+	Timeout   bool     `json:"to,omitempty"`
+	Temporary bool     `json:"tmp,omitempty"`
+}
+
+type transcriptKey struct {
+	op   string
+	name string
+}
+
+// Recorder decora un ports.DNSResolver y guarda cada pregunta/respuesta en un
+// transcript JSONL (comprimido con gzip si la ruta termina en .gz)
+type Recorder struct {
+	inner  ports.DNSResolver
+	mu     sync.Mutex
+	file   *os.File
+	gz     *gzip.Writer
+	writer *bufio.Writer
+	count  int
+	logger zerolog.Logger
+}
+
+func NewRecorder(inner ports.DNSResolver, path string, logger zerolog.Logger) (*Recorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creando transcript: %w", err)
+	}
+
+	r := &Recorder{
+		inner:  inner,
+		file:   file,
+		logger: logger,
+	}
+
+	var w io.Writer = file
+	if strings.HasSuffix(path, ".gz") {
+		r.gz = gzip.NewWriter(file)
+		w = r.gz
+	}
+	r.writer = bufio.NewWriter(w)
+
+	return r, nil
+}
+
+func (r *Recorder) LookupIP(ctx context.Context, fqdn string) ([]string, error) {
+	ips, err := r.inner.LookupIP(ctx, fqdn)
+	r.record(opLookupIP, fqdn, ips, err)
+	return ips, err
+}
+
+func (r *Recorder) LookupCNAME(ctx context.Context, fqdn string) (string, error) {
+	target, err := r.inner.LookupCNAME(ctx, fqdn)
+	var answer []string
+	if target != "" {
+		answer = []string{target}
+	}
+	r.record(opLookupCNAME, fqdn, answer, err)
+	return target, err
+}
+
+func (r *Recorder) LookupTXT(ctx context.Context, fqdn string) ([]string, error) {
+	records, err := r.inner.LookupTXT(ctx, fqdn)
+	r.record(opLookupTXT, fqdn, records, err)
+	return records, err
+}
+
+func (r *Recorder) LookupMX(ctx context.Context, fqdn string) ([]string, error) {
+	hosts, err := r.inner.LookupMX(ctx, fqdn)
+	r.record(opLookupMX, fqdn, hosts, err)
+	return hosts, err
+}
+
+func (r *Recorder) LookupAddr(ctx context.Context, ip string) ([]string, error) {
+	names, err := r.inner.LookupAddr(ctx, ip)
+	r.record(opLookupPTR, ip, names, err)
+	return names, err
+}
+
+func (r *Recorder) record(op, fqdn string, answer []string, err error) {
+	// Las cancelaciones del escaneo no son respuestas DNS
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	entry := transcriptEntry{
+		Op:     op,
+		Name:   normalizeName(fqdn),
+		Answer: answer,
+	}
+	if err != nil {
+		entry.Err = err.Error()
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) {
+			entry.Err = dnsErr.Err
+			entry.NotFound = dnsErr.IsNotFound
+			entry.Timeout = dnsErr.IsTimeout
+			entry.Temporary = dnsErr.IsTemporary
+		}
+	}
+
+	data, mErr := json.Marshal(entry)
+	if mErr != nil {
+		r.logger.Warn().Err(mErr).Str("fqdn", fqdn).Msg("Error serializando entrada de transcript")
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, wErr := r.writer.Write(append(data, '\n')); wErr != nil {
+		r.logger.Warn().Err(wErr).Str("fqdn", fqdn).Msg("Error escribiendo transcript")
+		return
+	}
+	r.count++
+}
+
+// Close vuelca el buffer y cierra el archivo del transcript
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.writer.Flush(); err != nil {
+		return fmt.Errorf("escribiendo transcript: %w", err)
+	}
+	if r.gz != nil {
+		if err := r.gz.Close(); err != nil {
+			return fmt.Errorf("comprimiendo transcript: %w", err)
+		}
+	}
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("cerrando transcript: %w", err)
+	}
+
+	r.logger.Info().Str("path", r.file.Name()).Int("entries", r.count).Msg("Transcript DNS guardado")
+	return nil
+}
+
+// Replayer implementa ports.DNSResolver sirviendo las respuestas de un
+// transcript, sin acceso a la red. Si una pregunta se grabó varias veces
+// (reintentos) las respuestas se sirven en orden y se repite la última.
+type Replayer struct {
+	mu      sync.Mutex
+	entries map[transcriptKey][]transcriptEntry
+	next    map[transcriptKey]int
+	logger  zerolog.Logger
+}
+
+func NewReplayer(path string, logger zerolog.Logger) (*Replayer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("abriendo transcript: %w", err)
+	}
+	defer func(file *os.File) {
+		err := file.Close()
+		if err != nil {
+			logger.Warn().Err(err).Msg("Error cerrando transcript")
+		}
+	}(file)
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("descomprimiendo transcript: %w", err)
+		}
+		defer func(gz *gzip.Reader) {
+			err := gz.Close()
+			if err != nil {
+				logger.Warn().Err(err).Msg("Error cerrando transcript comprimido")
+			}
+		}(gz)
+		reader = gz
+	}
+
+	r := &Replayer{
+		entries: make(map[transcriptKey][]transcriptEntry),
+		next:    make(map[transcriptKey]int),
+		logger:  logger,
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	total := 0
+	for line := 1; scanner.Scan(); line++ {
+		data := strings.TrimSpace(scanner.Text())
+		if data == "" {
+			continue
+		}
+		var entry transcriptEntry
+		if err := json.Unmarshal([]byte(data), &entry); err != nil {
+			return nil, fmt.Errorf("transcript línea %d: %w", line, err)
+		}
+		key := transcriptKey{op: entry.Op, name: normalizeName(entry.Name)}
+		r.entries[key] = append(r.entries[key], entry)
+		total++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("leyendo transcript: %w", err)
+	}
+
+	logger.Info().Str("path", path).Int("entries", total).Msg("Transcript DNS cargado")
+	return r, nil
+}
+
+func (r *Replayer) LookupIP(_ context.Context, fqdn string) ([]string, error) {
+	entry, err := r.lookup(opLookupIP, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), entry.Answer...), nil
+}
+
+func (r *Replayer) LookupCNAME(_ context.Context, fqdn string) (string, error) {
+	entry, err := r.lookup(opLookupCNAME, fqdn)
+	if err != nil {
+		return "", err
+	}
+	if len(entry.Answer) == 0 {
+		return "", nil
+	}
+	return entry.Answer[0], nil
+}
+
+func (r *Replayer) LookupTXT(_ context.Context, fqdn string) ([]string, error) {
+	entry, err := r.lookup(opLookupTXT, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), entry.Answer...), nil
+}
+
+func (r *Replayer) LookupMX(_ context.Context, fqdn string) ([]string, error) {
+	entry, err := r.lookup(opLookupMX, fqdn)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), entry.Answer...), nil
+}
+
+func (r *Replayer) LookupAddr(_ context.Context, ip string) ([]string, error) {
+	entry, err := r.lookup(opLookupPTR, ip)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string(nil), entry.Answer...), nil
+}
+
+func (r *Replayer) lookup(op, fqdn string) (transcriptEntry, error) {
+	key := transcriptKey{op: op, name: normalizeName(fqdn)}
+
+	r.mu.Lock()
+	entries := r.entries[key]
+	idx := r.next[key]
+	if idx < len(entries)-1 {
+		r.next[key] = idx + 1
+	}
+	r.mu.Unlock()
+
+	if len(entries) == 0 {
+		r.logger.Debug().Str("op", op).Str("fqdn", fqdn).Msg("Pregunta ausente en transcript")
+		return transcriptEntry{}, &net.DNSError{
+			Err:        "pregunta ausente en transcript",
+			Name:       fqdn,
+			IsNotFound: true,
+		}
+	}
+
+	entry := entries[idx]
+	if entry.Err != "" {
+		return transcriptEntry{}, &net.DNSError{
+			Err:         entry.Err,
+			Name:        fqdn,
+			IsNotFound:  entry.NotFound,
+			IsTimeout:   entry.Timeout,
+			IsTemporary: entry.Temporary,
+		}
+	}
+	return entry, nil
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
@@ -0,0 +1,122 @@
+package dns
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// stubResolver responde desde mapas fijos; los nombres ausentes son NXDOMAIN
+type stubResolver struct {
+	ips    map[string][]string
+	cnames map[string]string
+	txt    map[string][]string
+	calls  map[string]int
+}
+
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (s *stubResolver) LookupIP(_ context.Context, fqdn string) ([]string, error) {
+	s.calls[fqdn]++
+	// La primera consulta a flaky.example.com agota el tiempo
+	if fqdn == "flaky.example.com" && s.calls[fqdn] == 1 {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: fqdn, IsTimeout: true, IsTemporary: true}
+	}
+	if ips, ok := s.ips[fqdn]; ok {
+		return ips, nil
+	}
+	return nil, notFound(fqdn)
+}
+
+func (s *stubResolver) LookupCNAME(_ context.Context, fqdn string) (string, error) {
+	if target, ok := s.cnames[fqdn]; ok {
+		return target, nil
+	}
+	return "", notFound(fqdn)
+}
+
+func (s *stubResolver) LookupTXT(_ context.Context, fqdn string) ([]string, error) {
+	if records, ok := s.txt[fqdn]; ok {
+		return records, nil
+	}
+	return nil, notFound(fqdn)
+}
+
+func (s *stubResolver) LookupMX(_ context.Context, fqdn string) ([]string, error) {
+	return nil, notFound(fqdn)
+}
+
+func (s *stubResolver) LookupAddr(_ context.Context, ip string) ([]string, error) {
+	return nil, notFound(ip)
+}
+
+func TestTranscriptRoundTrip(t *testing.T) {
+	for _, name := range []string{"scan.jsonl", "scan.jsonl.gz"} {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			path := filepath.Join(t.TempDir(), name)
+			stub := &stubResolver{
+				ips:    map[string][]string{"www.example.com": {"192.0.2.1", "2001:db8::1"}, "flaky.example.com": {"192.0.2.9"}},
+				cnames: map[string]string{"www.example.com": "edge.cdn.net"},
+				txt:    map[string][]string{"example.com": {"v=spf1 ip4:192.0.2.0/28 -all"}},
+				calls:  make(map[string]int),
+			}
+
+			recorder, err := NewRecorder(stub, path, zerolog.Nop())
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = recorder.LookupIP(ctx, "www.example.com")
+			_, _ = recorder.LookupCNAME(ctx, "www.example.com")
+			_, _ = recorder.LookupTXT(ctx, "example.com")
+			_, _ = recorder.LookupIP(ctx, "missing.example.com")
+			_, _ = recorder.LookupIP(ctx, "flaky.example.com")
+			_, _ = recorder.LookupIP(ctx, "flaky.example.com")
+			if err := recorder.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			replayer, err := NewReplayer(path, zerolog.Nop())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ips, err := replayer.LookupIP(ctx, "WWW.example.com.")
+			if err != nil || !slices.Equal(ips, []string{"192.0.2.1", "2001:db8::1"}) {
+				t.Errorf("LookupIP = %v, %v", ips, err)
+			}
+			if target, err := replayer.LookupCNAME(ctx, "www.example.com"); err != nil || target != "edge.cdn.net" {
+				t.Errorf("LookupCNAME = %q, %v", target, err)
+			}
+			if records, err := replayer.LookupTXT(ctx, "example.com"); err != nil || len(records) != 1 {
+				t.Errorf("LookupTXT = %v, %v", records, err)
+			}
+
+			var dnsErr *net.DNSError
+			if _, err := replayer.LookupIP(ctx, "missing.example.com"); !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+				t.Errorf("se esperaba NXDOMAIN grabado, got %v", err)
+			}
+
+			// Los reintentos se sirven en el orden grabado y se repite el último
+			if _, err := replayer.LookupIP(ctx, "flaky.example.com"); !errors.As(err, &dnsErr) || !dnsErr.IsTimeout {
+				t.Errorf("se esperaba el timeout grabado, got %v", err)
+			}
+			for range 2 {
+				if ips, err := replayer.LookupIP(ctx, "flaky.example.com"); err != nil || !slices.Equal(ips, []string{"192.0.2.9"}) {
+					t.Errorf("reintento = %v, %v", ips, err)
+				}
+			}
+
+			if _, err := replayer.LookupIP(ctx, "never.example.com"); !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+				t.Errorf("una pregunta ausente debería ser NXDOMAIN, got %v", err)
+			}
+		})
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/alexperezortuno/cloudrip/internal/core/domain"
 	"github.com/rs/zerolog"
@@ -53,7 +56,7 @@ func (r *Repository) LoadWordlist(path string) ([]string, error) {
 	return lines, nil
 }
 
-func (r *Repository) SaveResults(results map[string][]domain.ResultEntry, config domain.ScannerConfig) error {
+func (r *Repository) SaveResults(result *domain.ScanResult, config domain.ScannerConfig) error {
 	if config.Output == "" {
 		return nil
 	}
@@ -65,15 +68,42 @@ func (r *Repository) SaveResults(results map[string][]domain.ResultEntry, config
 
 	switch strings.ToLower(config.OutputFmt) {
 	case "json":
-		return r.saveJSON(results, config.Output)
+		return r.saveJSON(result, config.Output)
 	case "text":
-		return r.saveText(results, config.Output)
+		return r.saveText(result, config.Output)
 	default:
 		return fmt.Errorf("formato no soportado: %s", config.OutputFmt)
 	}
 }
 
-func (r *Repository) saveJSON(results map[string][]domain.ResultEntry, path string) error {
+// JSONSchemaVersion es la versión del documento JSON de salida. La versión 1
+// (sin metadatos) era un arreglo plano de resultados; LoadResults acepta
+// ambas.
+const JSONSchemaVersion = 2
+
+// jsonOutput es el documento JSON de salida: metadatos del escaneo y la
+// lista plana de resultados
+type jsonOutput struct {
+	Schema      int                   `json:"schema_version"`
+	Domain      string                `json:"domain"`
+	GeneratedAt time.Time             `json:"generated_at"`
+	Duration    string                `json:"duration"`
+	TotalFound  int                   `json:"total_found"`
+	Ranges      domain.RangeSource    `json:"ranges"`
+	Hosts       []domain.HostSummary  `json:"hosts"`
+	Results     []domain.ResultEntry  `json:"results"`
+	Audit       *domain.AccountAudit  `json:"audit,omitempty"`
+	Origins     []domain.OriginCheck  `json:"origins,omitempty"`
+	Certs       []domain.CertCheck    `json:"certificates,omitempty"`
+	Favicons    []domain.FaviconCheck `json:"favicons,omitempty"`
+	Leads       []domain.OriginLead   `json:"leads,omitempty"`
+	Candidates  []domain.OriginScore  `json:"candidates,omitempty"`
+	Sweep       []domain.SweepHit     `json:"sweep,omitempty"`
+	Ports       []domain.PortScan     `json:"ports,omitempty"`
+	VHosts      []domain.VHostCheck   `json:"vhosts,omitempty"`
+}
+
+func (r *Repository) saveJSON(result *domain.ScanResult, path string) error {
 	file, err := os.Create(path)
 	if err != nil {
 		return fmt.Errorf("creando archivo: %w", err)
@@ -85,36 +115,41 @@ func (r *Repository) saveJSON(results map[string][]domain.ResultEntry, path stri
 		}
 	}(file)
 
-	// Convertir a slice plana y ordenar
-	var flatResults []domain.ResultEntry
-	keys := make([]string, 0, len(results))
-	for k := range results {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	for _, key := range keys {
-		entries := results[key]
-		sort.Slice(entries, func(i, j int) bool {
-			if entries[i].Type == entries[j].Type {
-				return entries[i].IP < entries[j].IP
-			}
-			return entries[i].Type < entries[j].Type
-		})
-		flatResults = append(flatResults, entries...)
+	output := jsonOutput{
+		Schema:      JSONSchemaVersion,
+		Domain:      result.Domain,
+		GeneratedAt: time.Now().UTC(),
+		Duration:    result.Duration.String(),
+		TotalFound:  result.TotalFound,
+		Ranges:      result.Ranges,
+		Hosts:       result.Hosts,
+		Results:     flattenResults(result.Results),
+		Audit:       result.Audit,
+		Origins:     result.Origins,
+		Certs:       result.Certs,
+		Favicons:    result.Favicons,
+		Leads:       result.Leads,
+		Candidates:  result.Candidates,
+		Sweep:       result.Sweep,
+		Ports:       result.Ports,
+		VHosts:      result.VHosts,
 	}
 
 	encoder := json.NewEncoder(file)
 	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(flatResults); err != nil {
+	if err := encoder.Encode(output); err != nil {
 		return fmt.Errorf("escribiendo JSON: %w", err)
 	}
 
-	r.logger.Debug().Str("path", path).Int("results", len(flatResults)).Msg("Resultados guardados en JSON")
+	r.logger.Debug().Str("path", path).Int("results", len(output.Results)).Msg("Resultados guardados en JSON")
 	return nil
 }
 
-func (r *Repository) saveText(results map[string][]domain.ResultEntry, path string) error {
+// saveText escribe un resultado por línea separado por tabs (fqdn, ip, tipo,
+// proveedor y cloud si los hay) y al final el resumen de exposición por host
+// (IPs detrás de un CDN/WAF sobre el total)
+// precedido por comentarios con los metadatos del escaneo
+func (r *Repository) saveText(result *domain.ScanResult, path string) error {
 	file, err := os.Create(path)
 	if err != nil {
 		return fmt.Errorf("creando archivo: %w", err)
@@ -127,20 +162,335 @@ func (r *Repository) saveText(results map[string][]domain.ResultEntry, path stri
 	}(file)
 
 	writer := bufio.NewWriter(file)
-	defer func(writer *bufio.Writer) {
-		err := writer.Flush()
-		if err != nil {
-			r.logger.Warn().Err(err).Msg("Error guardando resultados")
+
+	ranges := result.Ranges
+	fmt.Fprintf(writer, "# domain: %s\n", result.Domain)
+	fmt.Fprintf(writer, "# generated_at: %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(writer, "# ranges: %s source=%s version=%s", ranges.Provider, ranges.Source, ranges.Version)
+	if !ranges.FetchedAt.IsZero() {
+		fmt.Fprintf(writer, " fetched_at=%s", ranges.FetchedAt.Format(time.RFC3339))
+	}
+	fmt.Fprintln(writer)
+
+	entries := flattenResults(result.Results)
+	for _, entry := range entries {
+		ip := entry.IP
+		if ip == "" {
+			ip = "-"
+		}
+		line := []string{entry.FQDN, ip, entry.Type}
+		if entry.Provider != "" {
+			line = append(line, "provider="+entry.Provider)
+		}
+		if entry.Product != "" {
+			line = append(line, "product="+entry.Product)
+		}
+		if entry.CNAME != "" {
+			line = append(line, "cname="+entry.CNAME)
 		}
-	}(writer)
+		if entry.CNAMEProvider != "" && entry.CNAMEProvider != entry.Provider {
+			line = append(line, "cname_provider="+entry.CNAMEProvider)
+		}
+		if entry.Cloud != nil {
+			line = append(line, "cloud="+strings.Join([]string{entry.Cloud.Provider, entry.Cloud.Region, entry.Cloud.Service}, "/"))
+		}
+		if entry.HTTP != nil {
+			provider := entry.HTTP.Provider
+			if provider == "" {
+				provider = "none"
+			}
+			line = append(line, fmt.Sprintf("http=%s/%d", provider, entry.HTTP.Status))
+		}
+		if entry.Mismatch != "" {
+			line = append(line, "mismatch="+strings.ReplaceAll(entry.Mismatch, " ", ","))
+		}
+		if len(entry.Evidence) > 0 {
+			line = append(line, "evidence="+strings.Join(entry.Evidence, ","))
+		}
+		if len(entry.Ports) > 0 {
+			line = append(line, "ports="+joinPorts(entry.Ports))
+		}
+		for _, web := range entry.Web {
+			line = append(line, fmt.Sprintf("%s=%d %s %dB %dms", web.Scheme, web.Status, web.FinalURL, web.ContentLength, web.ResponseMS))
+			if web.Title != "" {
+				line = append(line, fmt.Sprintf("%s_title=%q", web.Scheme, web.Title))
+			}
+			if web.Server != "" {
+				line = append(line, web.Scheme+"_server="+web.Server)
+			}
+			if len(web.Redirects) > 0 {
+				line = append(line, web.Scheme+"_redirects="+strings.Join(web.Redirects, ","))
+			}
+			if len(web.Tech) > 0 {
+				techs := make([]string, 0, len(web.Tech))
+				for _, tech := range web.Tech {
+					techs = append(techs, tech.String())
+				}
+				line = append(line, web.Scheme+"_tech="+strings.Join(techs, ","))
+			}
+		}
+		fmt.Fprintln(writer, strings.Join(line, "\t"))
+	}
+
+	if len(result.Hosts) > 0 {
+		fmt.Fprintln(writer, "# hosts:")
+		for _, host := range result.Hosts {
+			line := fmt.Sprintf("# %s\t%s\t%d/%d", host.FQDN, host.Status, host.Proxied, host.Total)
+			if len(host.Providers) > 0 {
+				line += "\t" + strings.Join(host.Providers, ",")
+			}
+			if len(host.Products) > 0 {
+				line += "\tproducts=" + strings.Join(host.Products, ",")
+			}
+			if len(host.Tech) > 0 {
+				line += "\ttech=" + strings.Join(host.Tech, ",")
+			}
+			if host.Note != "" {
+				line += "\t" + host.Note
+			}
+			fmt.Fprintln(writer, line)
+		}
+	}
+
+	if len(result.Candidates) > 0 {
+		writeCandidates(writer, result.Candidates)
+	}
+
+	if len(result.Leads) > 0 {
+		writeLeads(writer, result.Leads)
+	}
 
-	// Ordenar resultados
+	if len(result.Ports) > 0 {
+		writePorts(writer, result.Ports)
+	}
+
+	if len(result.Origins) > 0 {
+		writeOrigins(writer, result.Origins)
+	}
+
+	if len(result.Certs) > 0 {
+		writeCerts(writer, result.Certs)
+	}
+
+	if len(result.Favicons) > 0 {
+		writeFavicons(writer, result.Favicons)
+	}
+
+	if len(result.Sweep) > 0 {
+		writeSweep(writer, result.Sweep)
+	}
+
+	if len(result.VHosts) > 0 {
+		writeVHosts(writer, result.VHosts)
+	}
+
+	if result.Audit != nil {
+		writeAudit(writer, result.Audit)
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("escribiendo resultados: %w", err)
+	}
+
+	r.logger.Debug().Str("path", path).Int("results", len(entries)).Msg("Resultados guardados en texto")
+	return nil
+}
+
+// writeCandidates escribe los mejores candidatos a origen de cada host con
+// el aporte de cada señal
+func writeCandidates(writer *bufio.Writer, scores []domain.OriginScore) {
+	fmt.Fprintln(writer, "# origin candidates:")
+	for _, score := range scores {
+		signals := make([]string, 0, len(score.Signals))
+		for _, signal := range score.Signals {
+			part := fmt.Sprintf("%s=%.2f", signal.Name, signal.Value)
+			if signal.Detail != "" {
+				part += "(" + signal.Detail + ")"
+			}
+			signals = append(signals, part)
+		}
+		fmt.Fprintf(writer, "# %s\t%s\tscore=%.3f\tsignals=%s\n", score.FQDN, score.IP, score.Score, strings.Join(signals, ";"))
+	}
+}
+
+// writeLeads escribe las pistas SPF/MX con su cadena de procedencia
+func writeLeads(writer *bufio.Writer, leads []domain.OriginLead) {
+	fmt.Fprintln(writer, "# leads:")
+	for _, lead := range leads {
+		address, candidates := lead.IP, ""
+		if address == "" {
+			address = lead.Prefix
+			candidates = fmt.Sprintf("\tcandidates=%d", len(lead.IPs))
+		}
+		fmt.Fprintf(writer, "# %s\t%s%s\tchain=%s\n", address, lead.Source, candidates, strings.Join(lead.Chain, " > "))
+	}
+}
+
+// writePorts escribe los puertos abiertos de cada IP candidata escaneada
+func writePorts(writer *bufio.Writer, scans []domain.PortScan) {
+	fmt.Fprintln(writer, "# ports:")
+	for _, scan := range scans {
+		open := "none"
+		if len(scan.Open) > 0 {
+			open = joinPorts(scan.Open)
+		}
+		fmt.Fprintf(writer, "# %s\topen=%s\n", scan.IP, open)
+	}
+}
+
+func joinPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = strconv.Itoa(port)
+	}
+	return strings.Join(parts, ",")
+}
+
+// writeOrigins escribe las verificaciones confirmed y likely; las unrelated
+// solo se cuentan
+func writeOrigins(writer *bufio.Writer, checks []domain.OriginCheck) {
+	unrelated := 0
+	fmt.Fprintln(writer, "# origins:")
+	for _, check := range checks {
+		if check.Verdict == domain.OriginUnrelated {
+			unrelated++
+			continue
+		}
+		address := check.IP
+		if check.Port != 0 {
+			address = net.JoinHostPort(check.IP, strconv.Itoa(check.Port))
+		}
+		line := fmt.Sprintf("# %s\t%s\t%s\tscore=%.3f\tstatus=%d\tvia=%s", check.FQDN, address, check.Verdict, check.Score, check.Status, check.Via)
+		if check.Title != "" {
+			line += "\ttitle=" + check.Title
+		}
+		fmt.Fprintln(writer, line)
+	}
+	fmt.Fprintf(writer, "# unrelated: %d\n", unrelated)
+}
+
+// writeCerts escribe el certificado hoja de cada IP candidata
+func writeCerts(writer *bufio.Writer, checks []domain.CertCheck) {
+	fmt.Fprintln(writer, "# certificates:")
+	for _, check := range checks {
+		sni := check.SNI
+		if sni == "" {
+			sni = "-"
+		}
+		match := check.Match
+		if match == "" {
+			match = "none"
+		}
+		leaf := check.Chain[0]
+		line := []string{
+			fmt.Sprintf("# %s:%d", check.IP, check.Port),
+			"sni=" + sni,
+			"match=" + match,
+			"spki=" + leaf.SPKI,
+			"subject=" + leaf.Subject,
+		}
+		if len(check.Names) > 0 {
+			line = append(line, "names="+strings.Join(check.Names, ","))
+		}
+		if len(check.Related) > 0 {
+			line = append(line, "related="+strings.Join(check.Related, ","))
+		}
+		fmt.Fprintln(writer, strings.Join(line, "\t"))
+	}
+}
+
+// writeSweep escribe los vecinos de orígenes confirmados con el certificado
+// del dominio
+func writeSweep(writer *bufio.Writer, hits []domain.SweepHit) {
+	fmt.Fprintln(writer, "# sweep:")
+	for _, hit := range hits {
+		sni := hit.Cert.SNI
+		if sni == "" {
+			sni = "-"
+		}
+		fmt.Fprintf(writer, "# %s:%d\tseed=%s\tnetblock=%s\tsni=%s\tmatch=%s\tnames=%s\n",
+			hit.Cert.IP, hit.Cert.Port, hit.Seed, hit.Netblock, sni, hit.Cert.Match, strings.Join(hit.Cert.Names, ","))
+	}
+}
+
+// writeVHosts escribe los virtual hosts de cada origen; known indica que el
+// DNS ya apunta el nombre a ese origen
+func writeVHosts(writer *bufio.Writer, checks []domain.VHostCheck) {
+	fmt.Fprintln(writer, "# vhosts:")
+	for _, check := range checks {
+		address := check.IP
+		if check.Port != 0 {
+			address = net.JoinHostPort(check.IP, strconv.Itoa(check.Port))
+		}
+		fmt.Fprintf(writer, "# %s\tdefault\tstatus=%d\ttitle=%q\n", address, check.Status, check.Title)
+		for _, vhost := range check.VHosts {
+			kind := "known"
+			if vhost.Hidden {
+				kind = "hidden"
+			}
+			fmt.Fprintf(writer, "# %s\t%s\tstatus=%d\ttitle=%q\tcluster=%d\tbaseline=%.2f\t%s\n",
+				address, vhost.Name, vhost.Status, vhost.Title, vhost.Cluster, vhost.Baseline, kind)
+		}
+	}
+}
+
+// writeFavicons escribe los íconos de cada host y las IPs que los sirven
+func writeFavicons(writer *bufio.Writer, checks []domain.FaviconCheck) {
+	fmt.Fprintln(writer, "# favicons:")
+	for _, check := range checks {
+		for _, favicon := range check.Favicons {
+			fmt.Fprintf(writer, "# %s\t%s\tmmh3=%d\tsha256=%s\n", check.FQDN, favicon.Path, favicon.MMH3, favicon.SHA256)
+		}
+		for _, match := range check.Matches {
+			fmt.Fprintf(writer, "# %s\tmatch=%s\t%s\tmmh3=%d\n", check.FQDN, match.IP, match.Favicon.Path, match.Favicon.MMH3)
+		}
+	}
+}
+
+// writeAudit escribe la auditoría de cuenta como comentarios, una sección por
+// tipo de hallazgo
+func writeAudit(writer *bufio.Writer, audit *domain.AccountAudit) {
+	if audit.Error != "" {
+		fmt.Fprintf(writer, "# audit: zone=%s error=%s\n", audit.Zone, audit.Error)
+		return
+	}
+	fmt.Fprintf(writer, "# audit: zone=%s records=%d\n", audit.Zone, audit.Records)
+
+	sections := []struct {
+		name     string
+		findings []domain.AuditFinding
+	}{
+		{"grey_clouded", audit.GreyClouded},
+		{"proxied_exposed", audit.ProxiedExposed},
+		{"not_in_zone", audit.NotInZone},
+	}
+	for _, section := range sections {
+		fmt.Fprintf(writer, "# %s: %d\n", section.name, len(section.findings))
+		for _, finding := range section.findings {
+			line := []string{"# " + finding.Name}
+			if finding.Type != "" {
+				line = append(line, finding.Type, finding.Content)
+			}
+			if len(finding.IPs) > 0 {
+				line = append(line, "ips="+strings.Join(finding.IPs, ","))
+			}
+			if !finding.Found {
+				line = append(line, "no encontrado por el escaneo")
+			}
+			fmt.Fprintln(writer, strings.Join(line, "\t"))
+		}
+	}
+}
+
+// flattenResults ordena los resultados por FQDN, tipo e IP
+func flattenResults(results map[string][]domain.ResultEntry) []domain.ResultEntry {
 	keys := make([]string, 0, len(results))
 	for k := range results {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 
+	flat := make([]domain.ResultEntry, 0, len(results))
 	for _, key := range keys {
 		entries := results[key]
 		sort.Slice(entries, func(i, j int) bool {
@@ -149,13 +499,9 @@ func (r *Repository) saveText(results map[string][]domain.ResultEntry, path stri
 			}
 			return entries[i].Type < entries[j].Type
 		})
-		for _, entry := range entries {
-			r.logger.Info().Str("fqdn", entry.FQDN).Str("ip", entry.IP).Str("type", entry.Type).Msg("Result")
-		}
+		flat = append(flat, entries...)
 	}
-
-	r.logger.Debug().Str("path", path).Int("entries", len(results)).Msg("Results saved to text file")
-	return nil
+	return flat
 }
 
 func (r *Repository) LoadConfig(path string) (*domain.ScannerConfig, error) {
@@ -0,0 +1,146 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// LoadResults lee resultados guardados por SaveResults, en JSON o texto. El
+// formato se detecta por el contenido.
+func (r *Repository) LoadResults(path string) (*domain.ScanResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("leyendo resultados: %w", err)
+	}
+
+	var result *domain.ScanResult
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case len(trimmed) > 0 && trimmed[0] == '{':
+		result, err = parseJSONResults(trimmed)
+	case len(trimmed) > 0 && trimmed[0] == '[':
+		result, err = parseLegacyJSONResults(trimmed)
+	default:
+		result, err = parseTextResults(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parseando %s: %w", path, err)
+	}
+
+	r.logger.Debug().Str("path", path).Int("results", result.TotalFound).Msg("Resultados cargados")
+	return result, nil
+}
+
+func parseJSONResults(data []byte) (*domain.ScanResult, error) {
+	var output jsonOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, err
+	}
+	if output.Schema > JSONSchemaVersion {
+		return nil, fmt.Errorf("versión de esquema %d no soportada (máximo %d)", output.Schema, JSONSchemaVersion)
+	}
+
+	result := &domain.ScanResult{
+		Domain:     output.Domain,
+		TotalFound: len(output.Results),
+		Ranges:     output.Ranges,
+		Hosts:      output.Hosts,
+		Results:    make(map[string][]domain.ResultEntry),
+		Audit:      output.Audit,
+		Origins:    output.Origins,
+		Certs:      output.Certs,
+		Favicons:   output.Favicons,
+		Leads:      output.Leads,
+		Candidates: output.Candidates,
+		Sweep:      output.Sweep,
+		Ports:      output.Ports,
+		VHosts:     output.VHosts,
+	}
+	if d, err := time.ParseDuration(output.Duration); err == nil {
+		result.Duration = d
+	}
+	for _, entry := range output.Results {
+		result.Results[entry.FQDN] = append(result.Results[entry.FQDN], entry)
+	}
+	return result, nil
+}
+
+// parseLegacyJSONResults interpreta la salida JSON de la versión 1 del
+// esquema: un arreglo plano de resultados sin metadatos
+func parseLegacyJSONResults(data []byte) (*domain.ScanResult, error) {
+	var entries []domain.ResultEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	result := &domain.ScanResult{
+		TotalFound: len(entries),
+		Results:    make(map[string][]domain.ResultEntry),
+	}
+	for _, entry := range entries {
+		result.Results[entry.FQDN] = append(result.Results[entry.FQDN], entry)
+	}
+	return result, nil
+}
+
+// parseTextResults interpreta las líneas "fqdn\tip\ttipo\tclave=valor..." de
+// la salida en texto; los comentarios solo aportan el dominio
+func parseTextResults(data []byte) (*domain.ScanResult, error) {
+	result := &domain.ScanResult{Results: make(map[string][]domain.ResultEntry)}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if value, ok := strings.CutPrefix(line, "# domain: "); ok {
+			result.Domain = strings.TrimSpace(value)
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("línea inválida: %q", line)
+		}
+		entry := domain.ResultEntry{FQDN: fields[0], IP: fields[1], Type: fields[2]}
+		if entry.IP == "-" {
+			entry.IP = ""
+		}
+		for _, field := range fields[3:] {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "provider":
+				entry.Provider, entry.Proxied = value, true
+			case "product":
+				entry.Product = value
+			case "cname":
+				entry.CNAME = value
+			case "cname_provider":
+				entry.CNAMEProvider = value
+			case "evidence":
+				entry.Evidence = strings.Split(value, ",")
+			case "ports":
+				for _, field := range strings.Split(value, ",") {
+					if port, err := strconv.Atoi(field); err == nil {
+						entry.Ports = append(entry.Ports, port)
+					}
+				}
+			}
+		}
+		result.Results[entry.FQDN] = append(result.Results[entry.FQDN], entry)
+		result.TotalFound++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,72 @@
+package file
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/rs/zerolog"
+)
+
+func TestJSONSchemaRoundTrip(t *testing.T) {
+	repo := NewRepository(zerolog.Nop())
+	path := filepath.Join(t.TempDir(), "results.json")
+	result := &domain.ScanResult{
+		Domain:     "example.com",
+		TotalFound: 1,
+		Results: map[string][]domain.ResultEntry{
+			"www.example.com": {{FQDN: "www.example.com", IP: "192.0.2.10", Type: "A"}},
+		},
+	}
+	if err := repo.SaveResults(result, domain.ScannerConfig{Output: path, OutputFmt: "json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var header struct {
+		Schema int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil || header.Schema != JSONSchemaVersion {
+		t.Errorf("schema_version = %d %v, esperado %d", header.Schema, err, JSONSchemaVersion)
+	}
+
+	loaded, err := repo.LoadResults(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Domain != "example.com" || len(loaded.Results["www.example.com"]) != 1 {
+		t.Errorf("LoadResults = %+v", loaded)
+	}
+}
+
+func TestLoadResultsSchemaVersions(t *testing.T) {
+	repo := NewRepository(zerolog.Nop())
+	dir := t.TempDir()
+
+	// Versión 1: arreglo plano sin metadatos
+	legacy := filepath.Join(dir, "v1.json")
+	data := `[{"fqdn": "www.example.com", "ip": "192.0.2.10", "type": "A"}, {"fqdn": "www.example.com", "ip": "2001:db8::10", "type": "AAAA"}]`
+	if err := os.WriteFile(legacy, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := repo.LoadResults(legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.TotalFound != 2 || len(loaded.Results["www.example.com"]) != 2 {
+		t.Errorf("LoadResults v1 = %+v", loaded)
+	}
+
+	future := filepath.Join(dir, "v99.json")
+	if err := os.WriteFile(future, []byte(`{"schema_version": 99, "results": []}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.LoadResults(future); err == nil {
+		t.Error("se esperaba error con una versión de esquema futura")
+	}
+}
@@ -0,0 +1,76 @@
+package fixturedns
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tipos de falla inyectables
+const (
+	FailServFail = "servfail"
+	FailRefused  = "refused"
+	FailNXDomain = "nxdomain"
+	FailDrop     = "drop"
+	FailTruncate = "truncate"
+	FailDelay    = "delay"
+)
+
+// Failure es una regla de falla aplicada a las consultas que coinciden con
+// Pattern: un nombre exacto, "*.sufijo" o "*" para todas
+type Failure struct {
+	Pattern string
+	Type    uint16 // 0 = cualquier tipo
+	Kind    string
+	Delay   time.Duration
+}
+
+// ParseFailure interpreta reglas como "www.example.com=servfail",
+// "*.example.com/AAAA=drop" o "*=delay:200ms"
+func ParseFailure(spec string) (Failure, error) {
+	target, action, ok := strings.Cut(spec, "=")
+	if !ok {
+		return Failure{}, fmt.Errorf("falla inválida %q: se espera patrón=acción", spec)
+	}
+
+	var f Failure
+	pattern, typeName, hasType := strings.Cut(target, "/")
+	f.Pattern = canonical(pattern)
+	if hasType {
+		t, ok := ParseType(typeName)
+		if !ok {
+			return Failure{}, fmt.Errorf("falla inválida %q: tipo desconocido %s", spec, typeName)
+		}
+		f.Type = t
+	}
+
+	kind, arg, _ := strings.Cut(strings.ToLower(action), ":")
+	f.Kind = kind
+	switch kind {
+	case FailServFail, FailRefused, FailNXDomain, FailDrop, FailTruncate:
+	case FailDelay:
+		d, err := time.ParseDuration(arg)
+		if err != nil {
+			return Failure{}, fmt.Errorf("falla inválida %q: %w", spec, err)
+		}
+		f.Delay = d
+	default:
+		return Failure{}, fmt.Errorf("falla inválida %q: acción desconocida %s", spec, kind)
+	}
+
+	return f, nil
+}
+
+func (f Failure) matches(q Question) bool {
+	if f.Type != 0 && f.Type != q.Type {
+		return false
+	}
+	switch {
+	case f.Pattern == "*":
+		return true
+	case strings.HasPrefix(f.Pattern, "*."):
+		return strings.HasSuffix(q.Name, f.Pattern[1:])
+	default:
+		return q.Name == f.Pattern
+	}
+}
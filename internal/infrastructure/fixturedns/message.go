@@ -0,0 +1,292 @@
+package fixturedns
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Tipos de registro soportados
+const (
+	TypeA     uint16 = 1
+	TypeNS    uint16 = 2
+	TypeCNAME uint16 = 5
+	TypeSOA   uint16 = 6
+	TypePTR   uint16 = 12
+	TypeMX    uint16 = 15
+	TypeTXT   uint16 = 16
+	TypeAAAA  uint16 = 28
+	TypeSRV   uint16 = 33
+	TypeOPT   uint16 = 41
+	TypeNSEC  uint16 = 47
+	TypeAXFR  uint16 = 252
+	TypeANY   uint16 = 255
+
+	ClassINET uint16 = 1
+	ClassANY  uint16 = 255
+)
+
+// Códigos de respuesta
+const (
+	RcodeSuccess  = 0
+	RcodeFormErr  = 1
+	RcodeServFail = 2
+	RcodeNXDomain = 3
+	RcodeNotImp   = 4
+	RcodeRefused  = 5
+)
+
+// Bits de la cabecera
+const (
+	flagQR = 1 << 15
+	flagAA = 1 << 10
+	flagTC = 1 << 9
+	flagRD = 1 << 8
+
+	headerLen     = 12
+	maxPointers   = 16
+	minUDPPayload = 512
+)
+
+var typeNames = map[uint16]string{
+	TypeA: "A", TypeNS: "NS", TypeCNAME: "CNAME", TypeSOA: "SOA", TypePTR: "PTR",
+	TypeMX: "MX", TypeTXT: "TXT", TypeAAAA: "AAAA", TypeSRV: "SRV", TypeOPT: "OPT",
+	TypeNSEC: "NSEC", TypeAXFR: "AXFR", TypeANY: "ANY",
+}
+
+// TypeString retorna el mnemónico de un tipo (TYPEnn si es desconocido)
+func TypeString(t uint16) string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", t)
+}
+
+// ParseType interpreta un mnemónico de tipo (A, AAAA, TYPE65...)
+func ParseType(s string) (uint16, bool) {
+	s = strings.ToUpper(s)
+	for t, name := range typeNames {
+		if name == s {
+			return t, true
+		}
+	}
+	var n uint16
+	if _, err := fmt.Sscanf(s, "TYPE%d", &n); err == nil {
+		return n, true
+	}
+	return 0, false
+}
+
+var errShortMessage = errors.New("mensaje DNS truncado")
+
+// Question es la pregunta de un mensaje DNS
+type Question struct {
+	Name  string
+	Type  uint16
+	Class uint16
+}
+
+// Record es un registro con su RDATA ya codificada en formato wire
+type Record struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	TTL   uint32
+	Data  []byte
+	Text  string // RDATA en formato de presentación (para logs)
+}
+
+// Message es un mensaje DNS simplificado (sin compresión al escribir)
+type Message struct {
+	ID         uint16
+	Flags      uint16
+	Rcode      int
+	Questions  []Question
+	Answers    []Record
+	Authority  []Record
+	Additional []Record
+	// UDPSize es el tamaño anunciado por EDNS0 en la consulta (0 si no hay OPT)
+	UDPSize int
+}
+
+// ParseQuery decodifica una consulta (cabecera, pregunta y OPT si existe)
+func ParseQuery(buf []byte) (*Message, error) {
+	if len(buf) < headerLen {
+		return nil, errShortMessage
+	}
+
+	msg := &Message{
+		ID:    binary.BigEndian.Uint16(buf[0:]),
+		Flags: binary.BigEndian.Uint16(buf[2:]),
+	}
+	qd := int(binary.BigEndian.Uint16(buf[4:]))
+	an := int(binary.BigEndian.Uint16(buf[6:]))
+	ns := int(binary.BigEndian.Uint16(buf[8:]))
+	ar := int(binary.BigEndian.Uint16(buf[10:]))
+
+	off := headerLen
+	for i := 0; i < qd; i++ {
+		name, next, err := readName(buf, off)
+		if err != nil {
+			return nil, err
+		}
+		if next+4 > len(buf) {
+			return nil, errShortMessage
+		}
+		msg.Questions = append(msg.Questions, Question{
+			Name:  name,
+			Type:  binary.BigEndian.Uint16(buf[next:]),
+			Class: binary.BigEndian.Uint16(buf[next+2:]),
+		})
+		off = next + 4
+	}
+
+	// Solo interesa el OPT de la sección adicional; el resto se salta
+	for i := 0; i < an+ns+ar; i++ {
+		_, next, err := readName(buf, off)
+		if err != nil {
+			return nil, err
+		}
+		if next+10 > len(buf) {
+			return nil, errShortMessage
+		}
+		rrType := binary.BigEndian.Uint16(buf[next:])
+		rrClass := binary.BigEndian.Uint16(buf[next+2:])
+		rdLen := int(binary.BigEndian.Uint16(buf[next+8:]))
+		if rrType == TypeOPT {
+			msg.UDPSize = int(rrClass)
+		}
+		off = next + 10 + rdLen
+		if off > len(buf) {
+			return nil, errShortMessage
+		}
+	}
+
+	return msg, nil
+}
+
+// Pack codifica el mensaje. Si maxSize > 0 se escriben registros completos
+// mientras entren y, si falta alguna respuesta o registro de autoridad, se
+// marca TC; los contadores de la cabecera reflejan lo escrito.
+func (m *Message) Pack(maxSize int) []byte {
+	flags := m.Flags&^0x000f | uint16(m.Rcode&0x0f)
+	fits := func(buf []byte) bool { return maxSize <= 0 || len(buf) <= maxSize }
+
+	buf := make([]byte, headerLen, 512)
+	questions := len(m.Questions)
+	for _, q := range m.Questions {
+		buf = appendName(buf, q.Name)
+		buf = binary.BigEndian.AppendUint16(buf, q.Type)
+		buf = binary.BigEndian.AppendUint16(buf, q.Class)
+	}
+	if !fits(buf) {
+		// Ni la pregunta entra: solo la cabecera
+		buf, questions = buf[:headerLen], 0
+		flags |= flagTC
+	}
+
+	var counts [3]int
+	full := false
+	for i, section := range [][]Record{m.Answers, m.Authority, m.Additional} {
+		for _, rr := range section {
+			if full {
+				break
+			}
+			next := appendRecord(buf, rr)
+			if !fits(next) {
+				full = true
+				// Omitir adicionales no es truncar la respuesta (RFC 2181 §9)
+				if i < 2 {
+					flags |= flagTC
+				}
+				break
+			}
+			buf = next
+			counts[i]++
+		}
+	}
+
+	binary.BigEndian.PutUint16(buf[0:], m.ID)
+	binary.BigEndian.PutUint16(buf[2:], flags)
+	binary.BigEndian.PutUint16(buf[4:], uint16(questions))
+	binary.BigEndian.PutUint16(buf[6:], uint16(counts[0]))
+	binary.BigEndian.PutUint16(buf[8:], uint16(counts[1]))
+	binary.BigEndian.PutUint16(buf[10:], uint16(counts[2]))
+	return buf
+}
+
+func appendRecord(buf []byte, rr Record) []byte {
+	buf = appendName(buf, rr.Name)
+	buf = binary.BigEndian.AppendUint16(buf, rr.Type)
+	buf = binary.BigEndian.AppendUint16(buf, rr.Class)
+	buf = binary.BigEndian.AppendUint32(buf, rr.TTL)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(rr.Data)))
+	return append(buf, rr.Data...)
+}
+
+// appendName codifica un nombre absoluto sin compresión
+func appendName(buf []byte, name string) []byte {
+	name = strings.TrimSuffix(name, ".")
+	if name != "" {
+		for _, label := range strings.Split(name, ".") {
+			buf = append(buf, byte(len(label)))
+			buf = append(buf, label...)
+		}
+	}
+	return append(buf, 0)
+}
+
+// readName decodifica un nombre (con soporte de compresión) y retorna el
+// offset siguiente al nombre en el mensaje original
+func readName(buf []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+	for hops := 0; ; {
+		if off >= len(buf) {
+			return "", 0, errShortMessage
+		}
+		length := int(buf[off])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.ToLower(strings.Join(labels, ".")), next, nil
+		case length&0xc0 == 0xc0:
+			if off+1 >= len(buf) {
+				return "", 0, errShortMessage
+			}
+			if hops++; hops > maxPointers {
+				return "", 0, errors.New("demasiados punteros de compresión")
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(buf[off:]) & 0x3fff)
+		default:
+			if off+1+length > len(buf) {
+				return "", 0, errShortMessage
+			}
+			labels = append(labels, string(buf[off+1:off+1+length]))
+			off += 1 + length
+		}
+	}
+}
+
+// Reply crea una respuesta autoritativa vacía para la consulta
+func (m *Message) Reply() *Message {
+	return &Message{
+		ID:        m.ID,
+		Flags:     flagQR | flagAA | m.Flags&flagRD,
+		Questions: m.Questions,
+	}
+}
+
+// udpLimit retorna el tamaño máximo de respuesta UDP permitido por la consulta
+func (m *Message) udpLimit() int {
+	if m.UDPSize > minUDPPayload {
+		return m.UDPSize
+	}
+	return minUDPPayload
+}
@@ -0,0 +1,60 @@
+package fixturedns
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestPackTruncates(t *testing.T) {
+	record := func(name string, data ...byte) Record {
+		return Record{Name: name, Type: TypeA, Class: ClassINET, TTL: 60, Data: data}
+	}
+	msg := &Message{
+		ID:        0x4242,
+		Flags:     flagQR | flagAA,
+		Questions: []Question{{Name: "example.com", Type: TypeA, Class: ClassINET}},
+		Answers: []Record{
+			record("example.com", 192, 0, 2, 1),
+			record("example.com", 192, 0, 2, 2),
+			record("example.com", 192, 0, 2, 3),
+		},
+		Authority:  []Record{record("example.com", 192, 0, 2, 4)},
+		Additional: []Record{record("ns.example.com", 192, 0, 2, 5)},
+	}
+	// Cabecera 12, pregunta 17 y cada registro A de example.com 27 bytes
+	full := len(msg.Pack(0))
+
+	tests := []struct {
+		name       string
+		maxSize    int
+		counts     [4]int
+		truncated  bool
+		wantLength int
+	}{
+		{"entra completo", full, [4]int{1, 3, 1, 1}, false, full},
+		{"sin adicionales", full - 1, [4]int{1, 3, 1, 0}, false, 12 + 17 + 4*27},
+		{"dos respuestas", 12 + 17 + 2*27 + 26, [4]int{1, 2, 0, 0}, true, 12 + 17 + 2*27},
+		{"sin respuestas", 12 + 17 + 26, [4]int{1, 0, 0, 0}, true, 12 + 17},
+		{"ni la pregunta", 20, [4]int{0, 0, 0, 0}, true, 12},
+	}
+	for _, tt := range tests {
+		buf := msg.Pack(tt.maxSize)
+		if len(buf) != tt.wantLength || len(buf) > tt.maxSize {
+			t.Errorf("%s: %d bytes, esperado %d (máximo %d)", tt.name, len(buf), tt.wantLength, tt.maxSize)
+		}
+		var counts [4]int
+		for i := range counts {
+			counts[i] = int(binary.BigEndian.Uint16(buf[4+2*i:]))
+		}
+		if counts != tt.counts {
+			t.Errorf("%s: contadores = %v, esperado %v", tt.name, counts, tt.counts)
+		}
+		if got := binary.BigEndian.Uint16(buf[2:])&flagTC != 0; got != tt.truncated {
+			t.Errorf("%s: TC = %v, esperado %v", tt.name, got, tt.truncated)
+		}
+		// Los contadores deben coincidir con el cuerpo: recorrerlo no falla
+		if _, err := ParseQuery(buf); err != nil {
+			t.Errorf("%s: mensaje inválido: %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package fixturedns_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/core/service"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/cdn"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/cloudflare"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/dns"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/file"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/fixturedns"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/network"
+	"github.com/rs/zerolog"
+)
+
+// TestScanAgainstFixture ejecuta un escaneo completo contra el servidor de
+// fixtures: wildcards, cadenas CNAME y fallas inyectadas
+func TestScanAgainstFixture(t *testing.T) {
+	logger := zerolog.Nop()
+	zone, err := fixturedns.LoadZoneFile("../../../fixtures/example.com.zone", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := fixturedns.NewServer(zone, logger)
+	for _, spec := range []string{
+		"admin.example.com=servfail",
+		"mail.example.com=drop",
+		// Fuerza el reintento por TCP; la respuesta debe llegar igual
+		"staging.example.com/AAAA=truncate",
+	} {
+		failure, err := fixturedns.ParseFailure(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.AddFailure(failure)
+	}
+	if err := server.Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	wordlist := filepath.Join(t.TempDir(), "wordlist.txt")
+	words := []string{"admin", "api", "shop", "edge", "staging", "mail", "foo.dev", "bar.dev", "missing"}
+	if err := os.WriteFile(wordlist, []byte(strings.Join(words, "\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := domain.ScannerConfig{
+		Domain:    "example.com",
+		Wordlist:  wordlist,
+		Threads:   4,
+		Retries:   1,
+		Backoff:   10 * time.Millisecond,
+		Timeout:   300 * time.Millisecond,
+		IPFamily:  domain.IPFamilyAny,
+		NoFetchCF: true,
+	}
+	binder, err := network.NewBinder(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolver := dns.NewResolver(logger, binder)
+	resolver.SetNameserver(server.Addr())
+	registry, err := cdn.NewRegistry(logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := service.NewMetricsCollector()
+
+	scanner := service.NewScanner(
+		resolver,
+		cloudflare.NewService(logger, binder),
+		registry,
+		nil,
+		file.NewRepository(logger),
+		nil,
+		metrics,
+		service.NewHealthChecker(metrics),
+		logger,
+	)
+	result, err := scanner.Scan(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ips := func(fqdn string) []string {
+		var out []string
+		for _, entry := range result.Answers[fqdn] {
+			out = append(out, entry.IP)
+		}
+		slices.Sort(out)
+		return out
+	}
+	want := map[string][]string{
+		"api.example.com":     {"104.16.133.229", "2606:4700::6810:85e5"},
+		"shop.example.com":    {"104.16.133.229", "2606:4700::6810:85e5"},
+		"edge.example.com":    {"104.16.133.229", "2606:4700::6810:85e5"},
+		"staging.example.com": {"198.51.100.7", "2001:db8::7"},
+		"foo.dev.example.com": {"198.51.100.42"},
+		"bar.dev.example.com": {"198.51.100.42"},
+	}
+	for fqdn, wantIPs := range want {
+		if got := ips(fqdn); !slices.Equal(got, wantIPs) {
+			t.Errorf("%s = %v, esperado %v", fqdn, got, wantIPs)
+		}
+	}
+	// SERVFAIL, respuestas descartadas y NXDOMAIN no producen resultados
+	for _, fqdn := range []string{"admin.example.com", "mail.example.com", "missing.example.com"} {
+		if got := ips(fqdn); len(got) > 0 {
+			t.Errorf("%s no debería resolver: %v", fqdn, got)
+		}
+	}
+
+	// La cadena shop → api → edge termina en rangos de Cloudflare
+	for _, entry := range result.Answers["shop.example.com"] {
+		if entry.Provider != domain.ProviderCloudflare || !entry.Proxied {
+			t.Errorf("shop: %+v, se esperaba cloudflare", entry)
+		}
+	}
+	status := make(map[string]string)
+	for _, host := range result.Hosts {
+		status[host.FQDN] = host.Status
+	}
+	for fqdn, want := range map[string]string{
+		"shop.example.com":    domain.HostProxied,
+		"staging.example.com": domain.HostExposed,
+		"foo.dev.example.com": domain.HostExposed,
+	} {
+		if status[fqdn] != want {
+			t.Errorf("%s estado = %q, esperado %q", fqdn, status[fqdn], want)
+		}
+	}
+
+	// Los hosts expuestos quedan en los resultados; los protegidos no
+	if _, ok := result.Results["staging.example.com"]; !ok {
+		t.Error("staging.example.com debería estar en los resultados")
+	}
+	if _, ok := result.Results["shop.example.com"]; ok {
+		t.Error("shop.example.com está detrás de Cloudflare y no debería reportarse")
+	}
+}
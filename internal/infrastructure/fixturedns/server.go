@@ -0,0 +1,393 @@
+package fixturedns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	maxCNAMEChain  = 8
+	axfrChunk      = 100
+	tcpIdleTimeout = 10 * time.Second
+	maxTCPMessage  = 65535
+)
+
+// Server es un servidor DNS autoritativo de fixtures que sirve una zona por
+// UDP y TCP. Pensado para tests de integración y entrenamiento: no hace
+// recursión y solo responde por la zona cargada.
+type Server struct {
+	zone      *Zone
+	allowAXFR bool
+	logger    zerolog.Logger
+
+	mu       sync.RWMutex
+	failures []Failure
+	conns    map[net.Conn]struct{}
+
+	udp  net.PacketConn
+	tcp  net.Listener
+	wg   sync.WaitGroup
+	done chan struct{}
+}
+
+func NewServer(zone *Zone, logger zerolog.Logger) *Server {
+	return &Server{
+		zone:      zone,
+		allowAXFR: true,
+		logger:    logger.With().Str("component", "fixture_dns").Logger(),
+		conns:     make(map[net.Conn]struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// SetAllowAXFR habilita o deshabilita las transferencias de zona
+func (s *Server) SetAllowAXFR(allow bool) {
+	s.allowAXFR = allow
+}
+
+// AddFailure agrega una regla de falla; se puede llamar con el servidor activo
+func (s *Server) AddFailure(f Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, f)
+}
+
+// ClearFailures elimina todas las reglas de falla
+func (s *Server) ClearFailures() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = nil
+}
+
+// Start escucha en addr (ej: 127.0.0.1:0) por UDP y TCP en el mismo puerto
+func (s *Server) Start(addr string) error {
+	tcp, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("escuchando TCP: %w", err)
+	}
+	// Usar el puerto asignado a TCP también para UDP
+	udp, err := net.ListenPacket("udp", tcp.Addr().String())
+	if err != nil {
+		_ = tcp.Close()
+		return fmt.Errorf("escuchando UDP: %w", err)
+	}
+	s.tcp, s.udp = tcp, udp
+
+	s.wg.Add(2)
+	go s.serveUDP()
+	go s.serveTCP()
+
+	s.logger.Info().Str("addr", s.Addr()).Str("zone", s.zone.Origin).Msg("Servidor DNS de fixtures iniciado")
+	return nil
+}
+
+// Addr retorna la dirección host:port en la que escucha el servidor
+func (s *Server) Addr() string {
+	return s.tcp.Addr().String()
+}
+
+// Resolver retorna un net.Resolver que consulta exclusivamente a este servidor
+func (s *Server) Resolver() *net.Resolver {
+	addr := s.Addr()
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
+
+// Close detiene el servidor y espera a que terminen las conexiones
+func (s *Server) Close() error {
+	close(s.done)
+	errUDP := s.udp.Close()
+	errTCP := s.tcp.Close()
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return errors.Join(errUDP, errTCP)
+}
+
+func (s *Server) serveUDP() {
+	defer s.wg.Done()
+
+	buf := make([]byte, maxTCPMessage)
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			if s.closed() {
+				return
+			}
+			s.logger.Debug().Err(err).Msg("Error leyendo UDP")
+			continue
+		}
+		query := append([]byte(nil), buf[:n]...)
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for _, resp := range s.handle(query, false) {
+				if _, err := s.udp.WriteTo(resp, addr); err != nil {
+					s.logger.Debug().Err(err).Msg("Error escribiendo UDP")
+				}
+			}
+		}()
+	}
+}
+
+func (s *Server) serveTCP() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			if s.closed() {
+				return
+			}
+			s.logger.Debug().Err(err).Msg("Error aceptando TCP")
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(conn)
+		}()
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	s.mu.Lock()
+	s.conns[conn] = struct{}{}
+	s.mu.Unlock()
+
+	defer func(conn net.Conn) {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}(conn)
+
+	for {
+		if err := conn.SetDeadline(time.Now().Add(tcpIdleTimeout)); err != nil {
+			return
+		}
+		var size [2]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		query := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+
+		for _, resp := range s.handle(query, true) {
+			frame := binary.BigEndian.AppendUint16(nil, uint16(len(resp)))
+			if _, err := conn.Write(append(frame, resp...)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (s *Server) closed() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// handle procesa una consulta y retorna cero o más mensajes de respuesta
+// (más de uno solo en AXFR)
+func (s *Server) handle(buf []byte, stream bool) [][]byte {
+	query, err := ParseQuery(buf)
+	if err != nil {
+		s.logger.Debug().Err(err).Msg("Consulta malformada")
+		return nil
+	}
+
+	reply := query.Reply()
+	if len(query.Questions) != 1 {
+		reply.Rcode = RcodeFormErr
+		return [][]byte{reply.Pack(0)}
+	}
+	q := query.Questions[0]
+	q.Name = canonical(q.Name)
+
+	s.logger.Debug().
+		Str("name", q.Name).
+		Str("type", TypeString(q.Type)).
+		Bool("tcp", stream).
+		Msg("Consulta recibida")
+
+	failure, failed := s.matchFailure(q)
+	if failed {
+		if failure.Delay > 0 {
+			select {
+			case <-time.After(failure.Delay):
+			case <-s.done:
+				return nil
+			}
+		}
+		switch failure.Kind {
+		case FailDrop:
+			return nil
+		case FailServFail:
+			reply.Rcode = RcodeServFail
+			return [][]byte{reply.Pack(0)}
+		case FailRefused:
+			reply.Rcode = RcodeRefused
+			return [][]byte{reply.Pack(0)}
+		case FailNXDomain:
+			reply.Rcode = RcodeNXDomain
+			reply.Authority = []Record{s.zone.soa()}
+			return [][]byte{reply.Pack(0)}
+		}
+	}
+
+	if q.Type == TypeAXFR {
+		return s.transfer(reply, q, stream)
+	}
+
+	s.answer(reply, q)
+
+	if failed && failure.Kind == FailTruncate && !stream {
+		// Forzar TC para que el cliente reintente por TCP
+		reply.Answers, reply.Authority, reply.Additional = nil, nil, nil
+		reply.Flags |= flagTC
+	}
+
+	limit := 0
+	if !stream {
+		limit = query.udpLimit()
+	}
+	return [][]byte{reply.Pack(limit)}
+}
+
+// answer resuelve la pregunta contra la zona siguiendo cadenas CNAME
+func (s *Server) answer(reply *Message, q Question) {
+	if q.Class != ClassINET && q.Class != ClassANY {
+		reply.Rcode = RcodeNotImp
+		return
+	}
+	if !s.zone.contains(q.Name) {
+		reply.Rcode = RcodeRefused
+		reply.Flags &^= flagAA
+		return
+	}
+
+	name := q.Name
+	for hops := 0; hops <= maxCNAMEChain; hops++ {
+		rrs, found := s.zone.find(name)
+		if !found {
+			if hops == 0 {
+				reply.Rcode = RcodeNXDomain
+			}
+			reply.Authority = append([]Record{s.zone.soa()}, s.zone.coveringNSEC(name)...)
+			return
+		}
+
+		var cname *Record
+		var matches []Record
+		for i := range rrs {
+			switch {
+			case q.Type == TypeANY || rrs[i].Type == q.Type:
+				matches = append(matches, rrs[i])
+			case rrs[i].Type == TypeCNAME:
+				cname = &rrs[i]
+			}
+		}
+
+		if len(matches) > 0 {
+			reply.Answers = append(reply.Answers, matches...)
+			return
+		}
+		if cname == nil {
+			// NODATA: el nombre existe pero no tiene el tipo pedido
+			reply.Authority = append([]Record{s.zone.soa()}, s.zone.lookupType(name, TypeNSEC)...)
+			return
+		}
+
+		reply.Answers = append(reply.Answers, *cname)
+		target := readRDataName(cname.Data)
+		if !s.zone.contains(target) {
+			// Fuera de la zona: el cliente debe seguir la cadena por su cuenta
+			return
+		}
+		name = target
+	}
+
+	s.logger.Debug().Str("name", q.Name).Msg("Cadena CNAME demasiado larga")
+	reply.Rcode = RcodeServFail
+}
+
+// transfer responde un AXFR: SOA, todos los registros y SOA de nuevo
+func (s *Server) transfer(reply *Message, q Question, stream bool) [][]byte {
+	if !stream || !s.allowAXFR || q.Name != s.zone.Origin {
+		reply.Rcode = RcodeRefused
+		return [][]byte{reply.Pack(0)}
+	}
+
+	soa := s.zone.soa()
+	records := []Record{soa}
+	for _, rr := range s.zone.Records() {
+		if rr.Type != TypeSOA || rr.Name != s.zone.Origin {
+			records = append(records, rr)
+		}
+	}
+	records = append(records, soa)
+
+	var out [][]byte
+	for start := 0; start < len(records); start += axfrChunk {
+		end := min(start+axfrChunk, len(records))
+		msg := *reply
+		msg.Answers = records[start:end]
+		out = append(out, msg.Pack(0))
+	}
+
+	s.logger.Info().Str("zone", s.zone.Origin).Int("records", len(records)).Msg("AXFR servido")
+	return out
+}
+
+func (s *Server) matchFailure(q Question) (Failure, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, f := range s.failures {
+		if f.matches(q) {
+			return f, true
+		}
+	}
+	return Failure{}, false
+}
+
+// readRDataName decodifica un nombre sin compresión al inicio de una RDATA
+func readRDataName(data []byte) string {
+	var labels []string
+	for off := 0; off < len(data) && data[off] != 0; {
+		length := int(data[off])
+		if off+1+length > len(data) {
+			break
+		}
+		labels = append(labels, string(data[off+1:off+1+length]))
+		off += 1 + length
+	}
+	return strings.ToLower(strings.Join(labels, "."))
+}
@@ -0,0 +1,265 @@
+package fixturedns
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const zoneFile = "../../../fixtures/example.com.zone"
+
+// startServer sirve la zona de fixtures en un puerto libre de 127.0.0.1
+func startServer(t *testing.T) *Server {
+	t.Helper()
+	zone, err := LoadZoneFile(zoneFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(zone, zerolog.Nop())
+	if err := server.Start("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := server.Close(); err != nil {
+			t.Errorf("Close: %v", err)
+		}
+	})
+	return server
+}
+
+func query(name string, qtype, class uint16) []byte {
+	msg := &Message{ID: 0x1234, Flags: flagRD, Questions: []Question{{Name: name, Type: qtype, Class: class}}}
+	return msg.Pack(0)
+}
+
+// parseReply decodifica la cabecera y la sección de respuestas
+func parseReply(t *testing.T, buf []byte) *Message {
+	t.Helper()
+	if len(buf) < headerLen {
+		t.Fatalf("respuesta truncada: %d bytes", len(buf))
+	}
+	flags := binary.BigEndian.Uint16(buf[2:])
+	msg := &Message{ID: binary.BigEndian.Uint16(buf), Flags: flags, Rcode: int(flags & 0x0f)}
+	qd := int(binary.BigEndian.Uint16(buf[4:]))
+	an := int(binary.BigEndian.Uint16(buf[6:]))
+
+	off := headerLen
+	for range qd {
+		_, next, err := readName(buf, off)
+		if err != nil {
+			t.Fatal(err)
+		}
+		off = next + 4
+	}
+	for range an {
+		name, next, err := readName(buf, off)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rdLen := int(binary.BigEndian.Uint16(buf[next+8:]))
+		msg.Answers = append(msg.Answers, Record{
+			Name:  name,
+			Type:  binary.BigEndian.Uint16(buf[next:]),
+			Class: binary.BigEndian.Uint16(buf[next+2:]),
+			Data:  buf[next+10 : next+10+rdLen],
+		})
+		off = next + 10 + rdLen
+	}
+	return msg
+}
+
+func exchangeUDP(t *testing.T, server *Server, q []byte) *Message {
+	t.Helper()
+	conn, err := net.Dial("udp", server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Write(q); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, maxTCPMessage)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parseReply(t, buf[:n])
+}
+
+// exchangeTCP envía una consulta y lee una respuesta, o todas las de un AXFR
+// hasta el SOA final
+func exchangeTCP(t *testing.T, server *Server, q []byte) []*Message {
+	t.Helper()
+	conn, err := net.Dial("tcp", server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(q))), q...)); err != nil {
+		t.Fatal(err)
+	}
+
+	var replies []*Message
+	soas := 0
+	for {
+		var size [2]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			t.Fatalf("leyendo respuesta TCP: %v", err)
+		}
+		buf := make([]byte, binary.BigEndian.Uint16(size[:]))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			t.Fatal(err)
+		}
+		reply := parseReply(t, buf)
+		replies = append(replies, reply)
+		for _, rr := range reply.Answers {
+			if rr.Type == TypeSOA {
+				soas++
+			}
+		}
+		if reply.Rcode != RcodeSuccess || soas != 1 {
+			return replies
+		}
+	}
+}
+
+func TestServerAnswers(t *testing.T) {
+	server := startServer(t)
+
+	tests := []struct {
+		name      string
+		qname     string
+		qtype     uint16
+		class     uint16
+		rcode     int
+		wantTypes []uint16
+	}{
+		{"registro directo", "admin.example.com", TypeA, ClassINET, RcodeSuccess, []uint16{TypeA}},
+		{"wildcard", "foo.dev.example.com", TypeA, ClassINET, RcodeSuccess, []uint16{TypeA}},
+		{"cadena CNAME", "shop.example.com", TypeA, ClassINET, RcodeSuccess, []uint16{TypeCNAME, TypeCNAME, TypeA}},
+		{"CNAME fuera de zona", "docs.example.com", TypeA, ClassINET, RcodeSuccess, []uint16{TypeCNAME}},
+		{"NODATA", "admin.example.com", TypeAAAA, ClassINET, RcodeSuccess, nil},
+		{"NXDOMAIN", "missing.example.com", TypeA, ClassINET, RcodeNXDomain, nil},
+		{"clase ANY", "admin.example.com", TypeA, ClassANY, RcodeSuccess, []uint16{TypeA}},
+		{"clase CHAOS", "admin.example.com", TypeA, 3, RcodeNotImp, nil},
+		{"fuera de la zona", "example.org", TypeA, ClassINET, RcodeRefused, nil},
+		{"AXFR por UDP", "example.com", TypeAXFR, ClassINET, RcodeRefused, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := exchangeUDP(t, server, query(tt.qname, tt.qtype, tt.class))
+			if reply.ID != 0x1234 || reply.Flags&flagQR == 0 {
+				t.Errorf("cabecera inesperada: id=%#x flags=%#x", reply.ID, reply.Flags)
+			}
+			if reply.Rcode != tt.rcode {
+				t.Errorf("rcode = %d, esperado %d", reply.Rcode, tt.rcode)
+			}
+			var types []uint16
+			for _, rr := range reply.Answers {
+				types = append(types, rr.Type)
+			}
+			if !slices.Equal(types, tt.wantTypes) {
+				t.Errorf("tipos de respuesta = %v, esperados %v", types, tt.wantTypes)
+			}
+		})
+	}
+
+	// El registro sintetizado desde el wildcard lleva el nombre consultado
+	reply := exchangeUDP(t, server, query("foo.dev.example.com", TypeA, ClassINET))
+	if len(reply.Answers) == 1 && reply.Answers[0].Name != "foo.dev.example.com" {
+		t.Errorf("nombre del wildcard = %q", reply.Answers[0].Name)
+	}
+}
+
+func TestServerAXFR(t *testing.T) {
+	server := startServer(t)
+
+	replies := exchangeTCP(t, server, query("example.com", TypeAXFR, ClassINET))
+	var records []Record
+	for _, reply := range replies {
+		if reply.Rcode != RcodeSuccess {
+			t.Fatalf("rcode = %d", reply.Rcode)
+		}
+		records = append(records, reply.Answers...)
+	}
+	if len(records) < 3 || records[0].Type != TypeSOA || records[len(records)-1].Type != TypeSOA {
+		t.Fatalf("el AXFR debe empezar y terminar con SOA: %d registros", len(records))
+	}
+	// Todos los registros de la zona más el SOA repetido al final
+	if want := len(server.zone.Records()) + 1; len(records) != want {
+		t.Errorf("%d registros transferidos, esperados %d", len(records), want)
+	}
+
+	server.SetAllowAXFR(false)
+	replies = exchangeTCP(t, server, query("example.com", TypeAXFR, ClassINET))
+	if len(replies) != 1 || replies[0].Rcode != RcodeRefused {
+		t.Errorf("con AXFR deshabilitado se esperaba REFUSED")
+	}
+}
+
+func TestServerFailures(t *testing.T) {
+	server := startServer(t)
+	for _, spec := range []string{
+		"admin.example.com=servfail",
+		"*.dev.example.com/A=nxdomain",
+		"staging.example.com/A=truncate",
+		"mail.example.com=drop",
+		"edge.example.com=delay:100ms",
+	} {
+		failure, err := ParseFailure(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		server.AddFailure(failure)
+	}
+
+	if reply := exchangeUDP(t, server, query("admin.example.com", TypeA, ClassINET)); reply.Rcode != RcodeServFail {
+		t.Errorf("admin: rcode = %d, esperado SERVFAIL", reply.Rcode)
+	}
+	if reply := exchangeUDP(t, server, query("foo.dev.example.com", TypeA, ClassINET)); reply.Rcode != RcodeNXDomain {
+		t.Errorf("wildcard: rcode = %d, esperado NXDOMAIN", reply.Rcode)
+	}
+	// La regla con tipo no afecta a otros tipos
+	if reply := exchangeUDP(t, server, query("foo.dev.example.com", TypeTXT, ClassINET)); reply.Rcode != RcodeSuccess {
+		t.Errorf("wildcard TXT: rcode = %d", reply.Rcode)
+	}
+
+	// Truncate: por UDP llega TC sin respuestas, por TCP la respuesta completa
+	if reply := exchangeUDP(t, server, query("staging.example.com", TypeA, ClassINET)); reply.Flags&flagTC == 0 || len(reply.Answers) != 0 {
+		t.Errorf("staging UDP: se esperaba TC sin respuestas (flags=%#x)", reply.Flags)
+	}
+	if replies := exchangeTCP(t, server, query("staging.example.com", TypeA, ClassINET)); len(replies[0].Answers) != 1 {
+		t.Errorf("staging TCP: %d respuestas", len(replies[0].Answers))
+	}
+
+	// El resolver Go ve cada falla como un error distinto
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	_, err := server.Resolver().LookupHost(ctx, "mail.example.com")
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || !dnsErr.IsTimeout {
+		t.Errorf("mail: se esperaba timeout, got %v", err)
+	}
+
+	start := time.Now()
+	if addrs, err := server.Resolver().LookupHost(context.Background(), "edge.example.com"); err != nil || len(addrs) != 2 {
+		t.Errorf("edge = %v, %v", addrs, err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("edge respondió en %v pese al delay de 100ms", elapsed)
+	}
+
+	server.ClearFailures()
+	if reply := exchangeUDP(t, server, query("admin.example.com", TypeA, ClassINET)); reply.Rcode != RcodeSuccess {
+		t.Errorf("tras ClearFailures: rcode = %d", reply.Rcode)
+	}
+}
@@ -0,0 +1,495 @@
+package fixturedns
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const defaultTTL = 3600
+
+// Zone es una zona autoritativa cargada en memoria
+type Zone struct {
+	Origin  string
+	records map[string][]Record
+	// names contiene además los "empty non-terminals" (ej: b.example.com si
+	// solo existe a.b.example.com)
+	names map[string]bool
+}
+
+// LoadZoneFile carga un archivo de zona en formato maestro (RFC 1035)
+func LoadZoneFile(path, origin string) (*Zone, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("abriendo zona: %w", err)
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	return ParseZone(file, origin)
+}
+
+// ParseZone interpreta una zona en formato maestro. Soporta $ORIGIN, $TTL,
+// paréntesis multilínea, comentarios y los tipos A, AAAA, CNAME, NS, SOA,
+// PTR, MX, TXT, SRV y NSEC.
+func ParseZone(r io.Reader, origin string) (*Zone, error) {
+	p := &zoneParser{
+		origin: canonical(origin),
+		ttl:    defaultTTL,
+	}
+	zone := &Zone{
+		records: make(map[string][]Record),
+		names:   make(map[string]bool),
+	}
+
+	scanner := bufio.NewScanner(r)
+	var (
+		pending   []string
+		startLine int
+		depth     int
+	)
+	for line := 1; scanner.Scan(); line++ {
+		tokens, blankOwner, err := tokenize(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("zona línea %d: %w", line, err)
+		}
+		if len(pending) == 0 {
+			startLine = line
+			if blankOwner && len(tokens) > 0 {
+				tokens = append([]string{""}, tokens...)
+			}
+		}
+		for _, tok := range tokens {
+			switch tok {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			default:
+				pending = append(pending, tok)
+			}
+		}
+		if depth > 0 || len(pending) == 0 {
+			continue
+		}
+
+		rr, ok, err := p.parseEntry(pending)
+		if err != nil {
+			return nil, fmt.Errorf("zona línea %d: %w", startLine, err)
+		}
+		if ok {
+			zone.add(rr)
+		}
+		pending = nil
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("leyendo zona: %w", err)
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("zona: paréntesis sin cerrar en línea %d", startLine)
+	}
+
+	zone.Origin = p.origin
+	if zone.Origin == "" {
+		return nil, fmt.Errorf("zona sin $ORIGIN")
+	}
+	if len(zone.lookupType(zone.Origin, TypeSOA)) == 0 {
+		return nil, fmt.Errorf("la zona %s no tiene registro SOA", zone.Origin)
+	}
+
+	return zone, nil
+}
+
+func (z *Zone) add(rr Record) {
+	z.records[rr.Name] = append(z.records[rr.Name], rr)
+	for name := rr.Name; name != "" && name != z.Origin; name = parent(name) {
+		z.names[name] = true
+	}
+	z.names[rr.Name] = true
+}
+
+// Records retorna todos los registros en orden canónico (útil para AXFR)
+func (z *Zone) Records() []Record {
+	names := make([]string, 0, len(z.records))
+	for name := range z.records {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return canonicalLess(names[i], names[j]) })
+
+	var out []Record
+	for _, name := range names {
+		out = append(out, z.records[name]...)
+	}
+	return out
+}
+
+func (z *Zone) lookupType(name string, t uint16) []Record {
+	var out []Record
+	for _, rr := range z.records[name] {
+		if t == TypeANY || rr.Type == t {
+			out = append(out, rr)
+		}
+	}
+	return out
+}
+
+func (z *Zone) soa() Record {
+	return z.lookupType(z.Origin, TypeSOA)[0]
+}
+
+// contains indica si un nombre pertenece a la zona
+func (z *Zone) contains(name string) bool {
+	return name == z.Origin || strings.HasSuffix(name, "."+z.Origin)
+}
+
+// find retorna los registros de un nombre, sintetizando desde un wildcard
+// (RFC 4592) cuando el nombre no existe
+func (z *Zone) find(name string) ([]Record, bool) {
+	if z.names[name] {
+		return z.records[name], true
+	}
+
+	// Buscar el "closest encloser" y su wildcard
+	for encloser := parent(name); encloser != "" && z.contains(encloser); encloser = parent(encloser) {
+		if !z.names[encloser] && encloser != z.Origin {
+			continue
+		}
+		wildcard := z.records["*."+encloser]
+		if len(wildcard) == 0 {
+			return nil, false
+		}
+		synth := make([]Record, len(wildcard))
+		for i, rr := range wildcard {
+			rr.Name = name
+			synth[i] = rr
+		}
+		return synth, true
+	}
+	return nil, false
+}
+
+// coveringNSEC retorna el NSEC cuyo intervalo cubre un nombre inexistente
+func (z *Zone) coveringNSEC(name string) []Record {
+	var best *Record
+	for owner, rrs := range z.records {
+		if !canonicalLess(owner, name) {
+			continue
+		}
+		for i := range rrs {
+			if rrs[i].Type != TypeNSEC {
+				continue
+			}
+			if best == nil || canonicalLess(best.Name, owner) {
+				best = &rrs[i]
+			}
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return []Record{*best}
+}
+
+type zoneParser struct {
+	origin    string
+	ttl       uint32
+	lastOwner string
+}
+
+// parseEntry interpreta una entrada ya tokenizada; ok=false para directivas
+func (p *zoneParser) parseEntry(tokens []string) (Record, bool, error) {
+	switch strings.ToUpper(tokens[0]) {
+	case "$ORIGIN":
+		if len(tokens) < 2 {
+			return Record{}, false, fmt.Errorf("$ORIGIN sin valor")
+		}
+		p.origin = p.absolute(tokens[1])
+		return Record{}, false, nil
+	case "$TTL":
+		if len(tokens) < 2 {
+			return Record{}, false, fmt.Errorf("$TTL sin valor")
+		}
+		ttl, err := strconv.ParseUint(tokens[1], 10, 32)
+		if err != nil {
+			return Record{}, false, fmt.Errorf("$TTL inválido: %w", err)
+		}
+		p.ttl = uint32(ttl)
+		return Record{}, false, nil
+	}
+
+	owner := tokens[0]
+	switch owner {
+	case "":
+		if p.lastOwner == "" {
+			return Record{}, false, fmt.Errorf("registro sin propietario")
+		}
+		owner = p.lastOwner
+	case "@":
+		owner = p.origin
+	default:
+		owner = p.absolute(owner)
+	}
+	p.lastOwner = owner
+
+	rr := Record{Name: owner, Class: ClassINET, TTL: p.ttl}
+	rest := tokens[1:]
+	// TTL y clase son opcionales y pueden venir en cualquier orden
+	for len(rest) > 0 {
+		if ttl, err := strconv.ParseUint(rest[0], 10, 32); err == nil {
+			rr.TTL = uint32(ttl)
+			rest = rest[1:]
+			continue
+		}
+		if strings.EqualFold(rest[0], "IN") {
+			rest = rest[1:]
+			continue
+		}
+		break
+	}
+	if len(rest) == 0 {
+		return Record{}, false, fmt.Errorf("registro %s sin tipo", owner)
+	}
+
+	t, ok := ParseType(rest[0])
+	if !ok {
+		return Record{}, false, fmt.Errorf("tipo desconocido: %s", rest[0])
+	}
+	rr.Type = t
+	rr.Text = strings.Join(rest[1:], " ")
+
+	data, err := p.packRData(t, rest[1:])
+	if err != nil {
+		return Record{}, false, fmt.Errorf("%s %s: %w", owner, rest[0], err)
+	}
+	rr.Data = data
+
+	return rr, true, nil
+}
+
+func (p *zoneParser) packRData(t uint16, args []string) ([]byte, error) {
+	need := func(n int) error {
+		if len(args) < n {
+			return fmt.Errorf("se esperan %d campos, hay %d", n, len(args))
+		}
+		return nil
+	}
+
+	switch t {
+	case TypeA, TypeAAAA:
+		if err := need(1); err != nil {
+			return nil, err
+		}
+		addr, err := netip.ParseAddr(args[0])
+		if err != nil {
+			return nil, err
+		}
+		if t == TypeA {
+			if !addr.Is4() {
+				return nil, fmt.Errorf("%s no es IPv4", args[0])
+			}
+			b := addr.As4()
+			return b[:], nil
+		}
+		if !addr.Is6() {
+			return nil, fmt.Errorf("%s no es IPv6", args[0])
+		}
+		b := addr.As16()
+		return b[:], nil
+	case TypeNS, TypeCNAME, TypePTR:
+		if err := need(1); err != nil {
+			return nil, err
+		}
+		return appendName(nil, p.absolute(args[0])), nil
+	case TypeMX:
+		if err := need(2); err != nil {
+			return nil, err
+		}
+		pref, err := strconv.ParseUint(args[0], 10, 16)
+		if err != nil {
+			return nil, err
+		}
+		buf := binary.BigEndian.AppendUint16(nil, uint16(pref))
+		return appendName(buf, p.absolute(args[1])), nil
+	case TypeSRV:
+		if err := need(4); err != nil {
+			return nil, err
+		}
+		var buf []byte
+		for _, field := range args[:3] {
+			v, err := strconv.ParseUint(field, 10, 16)
+			if err != nil {
+				return nil, err
+			}
+			buf = binary.BigEndian.AppendUint16(buf, uint16(v))
+		}
+		return appendName(buf, p.absolute(args[3])), nil
+	case TypeTXT:
+		if err := need(1); err != nil {
+			return nil, err
+		}
+		var buf []byte
+		for _, s := range args {
+			for len(s) > 255 {
+				buf = append(buf, 255)
+				buf = append(buf, s[:255]...)
+				s = s[255:]
+			}
+			buf = append(buf, byte(len(s)))
+			buf = append(buf, s...)
+		}
+		return buf, nil
+	case TypeSOA:
+		if err := need(7); err != nil {
+			return nil, err
+		}
+		buf := appendName(nil, p.absolute(args[0]))
+		buf = appendName(buf, p.absolute(args[1]))
+		for _, field := range args[2:7] {
+			v, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			buf = binary.BigEndian.AppendUint32(buf, uint32(v))
+		}
+		return buf, nil
+	case TypeNSEC:
+		if err := need(1); err != nil {
+			return nil, err
+		}
+		buf := appendName(nil, p.absolute(args[0]))
+		types := make([]uint16, 0, len(args)-1)
+		for _, name := range args[1:] {
+			tt, ok := ParseType(name)
+			if !ok {
+				return nil, fmt.Errorf("tipo desconocido en bitmap NSEC: %s", name)
+			}
+			types = append(types, tt)
+		}
+		return append(buf, typeBitmap(types)...), nil
+	default:
+		return nil, fmt.Errorf("tipo no soportado en zona: %s", TypeString(t))
+	}
+}
+
+func (p *zoneParser) absolute(name string) string {
+	if name == "@" {
+		return p.origin
+	}
+	if strings.HasSuffix(name, ".") {
+		return canonical(name)
+	}
+	if p.origin == "" {
+		return canonical(name)
+	}
+	return canonical(name + "." + p.origin)
+}
+
+// typeBitmap codifica la lista de tipos de un NSEC (RFC 4034 §4.1.2)
+func typeBitmap(types []uint16) []byte {
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	windows := map[byte][]byte{}
+	var order []byte
+	for _, t := range types {
+		window, bit := byte(t>>8), byte(t&0xff)
+		bitmap, ok := windows[window]
+		if !ok {
+			order = append(order, window)
+		}
+		for len(bitmap) <= int(bit/8) {
+			bitmap = append(bitmap, 0)
+		}
+		bitmap[bit/8] |= 0x80 >> (bit % 8)
+		windows[window] = bitmap
+	}
+
+	var buf []byte
+	for _, window := range order {
+		bitmap := windows[window]
+		buf = append(buf, window, byte(len(bitmap)))
+		buf = append(buf, bitmap...)
+	}
+	return buf
+}
+
+// tokenize separa una línea en tokens respetando comillas y comentarios.
+// blankOwner indica que la línea empieza con espacio (mismo propietario).
+func tokenize(line string) ([]string, bool, error) {
+	blankOwner := len(line) > 0 && (line[0] == ' ' || line[0] == '\t')
+
+	var (
+		tokens  []string
+		current strings.Builder
+		inQuote bool
+		hasTok  bool
+	)
+	flush := func() {
+		if hasTok {
+			tokens = append(tokens, current.String())
+			current.Reset()
+			hasTok = false
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inQuote && c == '\\' && i+1 < len(line):
+			i++
+			current.WriteByte(line[i])
+		case c == '"':
+			inQuote = !inQuote
+			hasTok = true
+			if !inQuote {
+				flush()
+			}
+		case inQuote:
+			current.WriteByte(c)
+		case c == ';':
+			flush()
+			return tokens, blankOwner, nil
+		case c == ' ' || c == '\t':
+			flush()
+		case c == '(' || c == ')':
+			flush()
+			tokens = append(tokens, string(c))
+		default:
+			current.WriteByte(c)
+			hasTok = true
+		}
+	}
+	if inQuote {
+		return nil, false, fmt.Errorf("comillas sin cerrar")
+	}
+	flush()
+	return tokens, blankOwner, nil
+}
+
+func canonical(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+func parent(name string) string {
+	_, rest, found := strings.Cut(name, ".")
+	if !found {
+		return ""
+	}
+	return rest
+}
+
+// canonicalLess compara nombres en orden canónico DNSSEC (RFC 4034 §6.1)
+func canonicalLess(a, b string) bool {
+	la, lb := strings.Split(a, "."), strings.Split(b, ".")
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if la[i] != lb[j] {
+			return la[i] < lb[j]
+		}
+	}
+	return len(la) < len(lb)
+}
@@ -0,0 +1,198 @@
+package httpprobe
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// maxIcons limita los <link rel=icon> consultados por sitio
+const maxIcons = 4
+
+var (
+	linkPattern = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+	relPattern  = regexp.MustCompile(`(?is)\brel\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	hrefPattern = regexp.MustCompile(`(?is)\bhref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+)
+
+// Favicons obtiene /favicon.ico y los íconos declarados con <link rel=icon>
+// en la página principal, pidiéndolos a la IP con host como Host/SNI. Solo
+// se consultan íconos del mismo host.
+func (p *Prober) Favicons(ctx context.Context, ip, host string) ([]domain.Favicon, error) {
+	paths := []string{"/favicon.ico"}
+	if page, err := p.FetchAny(ctx, ip, host, "/"); err == nil {
+		for _, path := range iconPaths(page.Body, host) {
+			if path != "/favicon.ico" {
+				paths = append(paths, path)
+			}
+		}
+	} else if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	var favicons []domain.Favicon
+	var lastErr error
+	for _, path := range paths {
+		resp, err := p.FetchAny(ctx, ip, host, path)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if !isIcon(resp) {
+			continue
+		}
+		favicon := HashFavicon(resp.Body)
+		favicon.Path = path
+		favicons = append(favicons, favicon)
+	}
+	if len(favicons) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return favicons, nil
+}
+
+// isIcon descarta respuestas de error y páginas HTML servidas en lugar del ícono
+func isIcon(resp *Response) bool {
+	if resp.StatusCode != http.StatusOK || len(resp.Body) == 0 {
+		return false
+	}
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	return !strings.HasPrefix(contentType, "text/html")
+}
+
+// iconPaths extrae las rutas de los <link rel="icon"> del mismo host
+func iconPaths(body []byte, host string) []string {
+	base := &url.URL{Scheme: "https", Host: host, Path: "/"}
+
+	var paths []string
+	seen := make(map[string]bool)
+	for _, tag := range linkPattern.FindAll(body, -1) {
+		rel := attr(relPattern, tag)
+		if !hasIconRel(rel) {
+			continue
+		}
+		href := attr(hrefPattern, tag)
+		if href == "" || strings.HasPrefix(href, "data:") {
+			continue
+		}
+		ref, err := url.Parse(href)
+		if err != nil {
+			continue
+		}
+		resolved := base.ResolveReference(ref)
+		if !strings.EqualFold(resolved.Hostname(), host) {
+			continue
+		}
+		path := resolved.RequestURI()
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+		if len(paths) == maxIcons {
+			break
+		}
+	}
+	return paths
+}
+
+func attr(pattern *regexp.Regexp, tag []byte) string {
+	m := pattern.FindSubmatch(tag)
+	if m == nil {
+		return ""
+	}
+	for _, group := range m[1:] {
+		if len(group) > 0 {
+			return strings.TrimSpace(string(group))
+		}
+	}
+	return ""
+}
+
+func hasIconRel(rel string) bool {
+	for _, token := range strings.Fields(strings.ToLower(rel)) {
+		if token == "icon" || token == "apple-touch-icon" {
+			return true
+		}
+	}
+	return false
+}
+
+// HashFavicon calcula el mmh3 compatible con Shodan (http.favicon.hash) y el
+// SHA-256 del ícono
+func HashFavicon(data []byte) domain.Favicon {
+	sum := sha256.Sum256(data)
+	return domain.Favicon{
+		MMH3:   mmh3(shodanBase64(data)),
+		SHA256: hex.EncodeToString(sum[:]),
+		Size:   len(data),
+	}
+}
+
+// shodanBase64 replica base64.encodebytes de Python: líneas de 76
+// caracteres, cada una terminada en salto de línea
+func shodanBase64(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var b strings.Builder
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76])
+		b.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded)
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+// mmh3 es MurmurHash3 x86 de 32 bits con semilla 0, como entero con signo
+func mmh3(data []byte) int32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	var h uint32
+	nblocks := len(data) / 4
+	for i := 0; i < nblocks; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+
+	var k uint32
+	tail := data[nblocks*4:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return int32(h)
+}
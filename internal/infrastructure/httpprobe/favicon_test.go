@@ -0,0 +1,80 @@
+package httpprobe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestMMH3(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int32
+	}{
+		{"", 0},
+		{"hello", 613153351},
+		{"foo", -156908512},
+		{"The quick brown fox jumps over the lazy dog", 776992547},
+	}
+	for _, tt := range tests {
+		if got := mmh3([]byte(tt.input)); got != tt.want {
+			t.Errorf("mmh3(%q) = %d, esperado %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestShodanBase64(t *testing.T) {
+	got := string(shodanBase64(make([]byte, 60)))
+	want := "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA\nAAAA\n"
+	if got != want {
+		t.Errorf("shodanBase64 = %q, esperado %q", got, want)
+	}
+}
+
+func TestFavicons(t *testing.T) {
+	icon := []byte("\x00\x00\x01\x00fake-icon-bytes")
+	png := []byte("\x89PNG\r\n\x1a\nfake-png-bytes")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head>
+<link rel="stylesheet" href="/app.css">
+<link rel="shortcut icon" href="/static/icon.png?v=2">
+<link rel=icon href="https://cdn.other.net/icon.png">
+</head></html>`))
+	})
+	mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/x-icon")
+		_, _ = w.Write(icon)
+	})
+	mux.HandleFunc("/static/icon.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write(png)
+	})
+
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	prober := NewProber(nil, 2*time.Second, zerolog.Nop())
+	port := serverPort(t, server)
+	prober.SetPorts(port, port)
+
+	favicons, err := prober.Favicons(context.Background(), "127.0.0.1", "shop.example.com")
+	if err != nil {
+		t.Fatalf("Favicons: %v", err)
+	}
+	if len(favicons) != 2 {
+		t.Fatalf("favicons = %+v, esperados 2 (el ícono de otro host se ignora)", favicons)
+	}
+	if favicons[0].Path != "/favicon.ico" || favicons[1].Path != "/static/icon.png?v=2" {
+		t.Errorf("rutas = %q, %q", favicons[0].Path, favicons[1].Path)
+	}
+	if want := HashFavicon(icon); favicons[0].MMH3 != want.MMH3 || favicons[0].SHA256 != want.SHA256 {
+		t.Errorf("hash de /favicon.ico = %+v, esperado %+v", favicons[0], want)
+	}
+}
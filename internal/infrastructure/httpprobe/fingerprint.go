@@ -0,0 +1,107 @@
+package httpprobe
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// signal es una evidencia de fronting en una cabecera: presencia, o valor
+// que contiene contains (sin distinguir mayúsculas)
+type signal struct {
+	header   string
+	contains string
+}
+
+// signatures por proveedor; los nombres coinciden con el registro de proveedores
+var signatures = []struct {
+	provider string
+	signals  []signal
+}{
+	{"cloudflare", []signal{
+		{header: "Cf-Ray"},
+		{header: "Cf-Cache-Status"},
+		{header: "Server", contains: "cloudflare"},
+		{header: "Cf-Mitigated"},
+	}},
+	{"akamai", []signal{
+		{header: "Server", contains: "akamaighost"},
+		{header: "Akamai-Grn"},
+		{header: "X-Akamai-Transformed"},
+		{header: "X-Akamai-Request-Id"},
+		{header: "Akamai-Cache-Status"},
+	}},
+	{"fastly", []signal{
+		{header: "X-Fastly-Request-Id"},
+		{header: "Fastly-Debug-Digest"},
+		{header: "X-Served-By", contains: "cache-"},
+		{header: "Fastly-Restarts"},
+	}},
+	{"cloudfront", []signal{
+		{header: "X-Amz-Cf-Id"},
+		{header: "X-Amz-Cf-Pop"},
+		{header: "Via", contains: "cloudfront"},
+	}},
+	{"imperva", []signal{
+		{header: "X-Iinfo"},
+		{header: "X-Cdn", contains: "incapsula"},
+		{header: "X-Cdn", contains: "imperva"},
+		{header: "Set-Cookie", contains: "incap_ses_"},
+	}},
+	{"sucuri", []signal{
+		{header: "X-Sucuri-Id"},
+		{header: "X-Sucuri-Cache"},
+		{header: "Server", contains: "sucuri"},
+	}},
+}
+
+// Fingerprint identifica el CDN/WAF que sirvió la respuesta. Gana el
+// proveedor con más señales; sin señales, Provider queda vacío.
+func Fingerprint(resp *Response) domain.HTTPFingerprint {
+	fp := domain.HTTPFingerprint{
+		Scheme: resp.Scheme,
+		Status: resp.StatusCode,
+		Server: resp.Header.Get("Server"),
+	}
+
+	best := 0
+	for _, sig := range signatures {
+		var matched []string
+		for _, s := range sig.signals {
+			if s.matches(resp.Header) {
+				matched = append(matched, strings.ToLower(s.header))
+			}
+		}
+		if len(matched) > best {
+			best = len(matched)
+			fp.Provider = sig.provider
+			fp.Signals = matched
+		}
+	}
+	return fp
+}
+
+func (s signal) matches(header http.Header) bool {
+	values := header.Values(s.header)
+	if s.contains == "" {
+		return len(values) > 0
+	}
+	for _, v := range values {
+		if strings.Contains(strings.ToLower(v), s.contains) {
+			return true
+		}
+	}
+	return false
+}
+
+// Probe consulta la IP con host como Host/SNI (HTTPS y luego HTTP) y
+// retorna la huella de la respuesta
+func (p *Prober) Probe(ctx context.Context, ip, host string) (domain.HTTPFingerprint, error) {
+	resp, err := p.FetchAny(ctx, ip, host, "/")
+	if err != nil {
+		return domain.HTTPFingerprint{}, err
+	}
+	return Fingerprint(resp), nil
+}
@@ -0,0 +1,181 @@
+package httpprobe
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/rs/zerolog"
+)
+
+// maxBody limita el cuerpo leído por respuesta
+const maxBody = 256 << 10
+
+// DialFunc abre conexiones salientes (ej: network.Binder.DialContext)
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// Request describe una consulta HTTP a una IP concreta usando host como
+// Host/SNI, sin pasar por DNS
+type Request struct {
+	IP     string
+	Host   string
+	Scheme string // https o http
+	Port   int    // 0: el puerto estándar del esquema
+	Path   string
+}
+
+// Response es una respuesta HTTP con el cuerpo ya leído (hasta maxBody)
+type Response struct {
+	Scheme        string
+	StatusCode    int
+	Header        http.Header
+	Body          []byte
+	ContentLength int64 // -1 si el servidor no lo informa
+	TLS           *tls.ConnectionState
+}
+
+// Prober hace peticiones HTTP/HTTPS a IPs concretas. Las conexiones no se
+// reutilizan: el pool de net/http agrupa por host y mezclaría IPs distintas.
+type Prober struct {
+	client    *http.Client
+	httpPort  int
+	httpsPort int
+	userAgent string
+	detector  TechDetector
+	logger    zerolog.Logger
+}
+
+// TechDetector reconoce tecnologías en una respuesta (ej: techdetect.Detector)
+type TechDetector interface {
+	Detect(header http.Header, body []byte) []domain.Technology
+}
+
+type dialIPKey struct{}
+
+func NewProber(dial DialFunc, timeout time.Duration, logger zerolog.Logger) *Prober {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			ip, _ := ctx.Value(dialIPKey{}).(string)
+			if ip == "" {
+				return dial(ctx, network, address)
+			}
+			_, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			return dial(ctx, network, net.JoinHostPort(ip, port))
+		},
+		// El objetivo es el origen: su certificado puede no coincidir
+		TLSClientConfig:       &tls.Config{InsecureSkipVerify: true},
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		DisableKeepAlives:     true,
+	}
+
+	return &Prober{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		httpPort:  80,
+		httpsPort: 443,
+		userAgent: "Mozilla/5.0 (compatible; cloudrip)",
+		logger:    logger.With().Str("component", "httpprobe").Logger(),
+	}
+}
+
+// SetPorts cambia los puertos HTTP y HTTPS (ej: servidores httptest)
+func (p *Prober) SetPorts(httpPort, httpsPort int) {
+	p.httpPort = httpPort
+	p.httpsPort = httpsPort
+}
+
+// SetTechDetector habilita la detección de tecnologías en Visit
+func (p *Prober) SetTechDetector(detector TechDetector) {
+	p.detector = detector
+}
+
+// Fetch ejecuta una petición GET sin seguir redirecciones
+func (p *Prober) Fetch(ctx context.Context, r Request) (*Response, error) {
+	port := p.httpsPort
+	if r.Scheme == "http" {
+		port = p.httpPort
+	}
+	if r.Port != 0 {
+		port = r.Port
+	}
+	path := r.Path
+	if path == "" {
+		path = "/"
+	}
+
+	url := fmt.Sprintf("%s://%s%s", r.Scheme, net.JoinHostPort(r.Host, strconv.Itoa(port)), path)
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, dialIPKey{}, r.IP), http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creando request: %w", err)
+	}
+	req.Host = r.Host
+	req.Header.Set("User-Agent", p.userAgent)
+	req.Header.Set("Accept", "*/*")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("consultando %s en %s: %w", url, r.IP, err)
+	}
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			p.logger.Debug().Err(err).Msg("Error cerrando body de respuesta")
+		}
+	}(resp.Body)
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("leyendo respuesta: %w", err)
+	}
+
+	return &Response{
+		Scheme:        r.Scheme,
+		StatusCode:    resp.StatusCode,
+		Header:        resp.Header,
+		Body:          body,
+		ContentLength: resp.ContentLength,
+		TLS:           resp.TLS,
+	}, nil
+}
+
+// FetchAny intenta HTTPS y luego HTTP, retornando la primera respuesta
+func (p *Prober) FetchAny(ctx context.Context, ip, host, path string) (*Response, error) {
+	return p.FetchPort(ctx, ip, 0, host, path)
+}
+
+// FetchPort intenta HTTPS y luego HTTP en un puerto concreto (0: los
+// estándar), retornando la primera respuesta
+func (p *Prober) FetchPort(ctx context.Context, ip string, port int, host, path string) (*Response, error) {
+	var errs []error
+	for _, scheme := range []string{"https", "http"} {
+		resp, err := p.Fetch(ctx, Request{IP: ip, Host: host, Scheme: scheme, Port: port, Path: path})
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
@@ -0,0 +1,147 @@
+package httpprobe
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const testHost = "www.example.com"
+
+// serverPort retorna el puerto en el que escucha un servidor httptest
+func serverPort(t *testing.T, server *httptest.Server) int {
+	t.Helper()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func withHeaders(headers map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range headers {
+			w.Header().Set(k, v)
+		}
+		w.Header().Set("X-Seen-Host", r.Host)
+		w.WriteHeader(http.StatusForbidden)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	tests := []struct {
+		name     string
+		tls      bool
+		headers  map[string]string
+		scheme   string
+		provider string
+	}{
+		{
+			name:     "cloudflare",
+			tls:      true,
+			headers:  map[string]string{"Server": "cloudflare", "CF-RAY": "8a1b2c3d4e5f6a7b-SCL", "CF-Cache-Status": "DYNAMIC"},
+			scheme:   "https",
+			provider: "cloudflare",
+		},
+		{
+			name:     "akamai por http",
+			headers:  map[string]string{"Server": "AkamaiGHost", "X-Akamai-Transformed": "9 - 0 pmb=mRUM,1"},
+			scheme:   "http",
+			provider: "akamai",
+		},
+		{
+			name:     "fastly",
+			tls:      true,
+			headers:  map[string]string{"X-Served-By": "cache-scl2220-SCL", "X-Fastly-Request-ID": "0f1e2d3c"},
+			scheme:   "https",
+			provider: "fastly",
+		},
+		{
+			name:    "sin cdn",
+			tls:     true,
+			headers: map[string]string{"Server": "nginx/1.25.3"},
+			scheme:  "https",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var server *httptest.Server
+			if tt.tls {
+				server = httptest.NewTLSServer(withHeaders(tt.headers))
+			} else {
+				server = httptest.NewServer(withHeaders(tt.headers))
+			}
+			defer server.Close()
+
+			prober := NewProber(nil, 2*time.Second, zerolog.Nop())
+			port := serverPort(t, server)
+			prober.SetPorts(port, port)
+
+			fp, err := prober.Probe(context.Background(), "127.0.0.1", testHost)
+			if err != nil {
+				t.Fatalf("Probe: %v", err)
+			}
+			if fp.Provider != tt.provider {
+				t.Errorf("provider = %q, esperado %q (signals %v)", fp.Provider, tt.provider, fp.Signals)
+			}
+			if fp.Scheme != tt.scheme {
+				t.Errorf("scheme = %q, esperado %q", fp.Scheme, tt.scheme)
+			}
+			if fp.Status != http.StatusForbidden {
+				t.Errorf("status = %d, esperado %d", fp.Status, http.StatusForbidden)
+			}
+			if tt.provider != "" && len(fp.Signals) == 0 {
+				t.Error("se esperaban señales")
+			}
+		})
+	}
+}
+
+func TestFetchSendsHost(t *testing.T) {
+	server := httptest.NewTLSServer(withHeaders(nil))
+	defer server.Close()
+
+	prober := NewProber(nil, 2*time.Second, zerolog.Nop())
+	port := serverPort(t, server)
+	prober.SetPorts(port, port)
+
+	resp, err := prober.Fetch(context.Background(), Request{IP: "127.0.0.1", Host: testHost, Scheme: "https"})
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if got := resp.Header.Get("X-Seen-Host"); got != testHost {
+		t.Errorf("Host = %q, esperado %q", got, testHost)
+	}
+	if resp.TLS == nil {
+		t.Error("se esperaba estado TLS")
+	}
+}
+
+func TestProbeUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	port := serverPort(t, server)
+	server.Close()
+
+	prober := NewProber(nil, time.Second, zerolog.Nop())
+	prober.SetPorts(port, port)
+
+	if _, err := prober.Probe(context.Background(), "127.0.0.1", testHost); err == nil {
+		t.Error("se esperaba error con el servidor cerrado")
+	}
+}
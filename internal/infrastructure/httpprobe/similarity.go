@@ -0,0 +1,195 @@
+package httpprobe
+
+import (
+	"context"
+	"hash/fnv"
+	"html"
+	"math/bits"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// volatileHeaders cambian entre respuestas o los agrega el CDN; no sirven
+// para comparar el sitio servido por el CDN con el del origen
+var volatileHeaders = map[string]bool{
+	"age": true, "alt-svc": true, "connection": true, "content-length": true,
+	"date": true, "expect-ct": true, "keep-alive": true, "nel": true,
+	"report-to": true, "server": true, "server-timing": true, "set-cookie": true,
+	"transfer-encoding": true, "via": true, "x-cache": true, "x-cache-hits": true,
+	"x-served-by": true, "x-timer": true, "speculation-rules": true,
+}
+
+// volatilePrefixes son prefijos de cabeceras propias de CDNs
+var volatilePrefixes = []string{"cf-", "x-amz-cf-", "akamai-", "x-akamai-", "fastly-", "x-fastly-", "x-iinfo", "x-sucuri-"}
+
+// Pesos de cada componente de la similitud
+const (
+	weightStatus  = 0.2
+	weightTitle   = 0.3
+	weightHeaders = 0.15
+	weightBody    = 0.35
+)
+
+// Snapshot resume una respuesta para compararla con otra
+func Snapshot(resp *Response) domain.PageSnapshot {
+	snapshot := domain.PageSnapshot{
+		Scheme: resp.Scheme,
+		Status: resp.StatusCode,
+		Length: len(resp.Body),
+	}
+
+	snapshot.Title = pageTitle(resp.Body)
+
+	for name := range resp.Header {
+		name = strings.ToLower(name)
+		if !isVolatile(name) {
+			snapshot.Headers = append(snapshot.Headers, name)
+		}
+	}
+	sort.Strings(snapshot.Headers)
+
+	snapshot.Simhash = simhash(resp.Body)
+	return snapshot
+}
+
+// pageTitle retorna el <title> del cuerpo con los espacios normalizados
+func pageTitle(body []byte) string {
+	m := titlePattern.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
+}
+
+func isVolatile(name string) bool {
+	if volatileHeaders[name] {
+		return true
+	}
+	for _, prefix := range volatilePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Similarity compara dos respuestas y retorna un puntaje entre 0 y 1. Los
+// componentes sin datos en ambas (ej: sin título ni cuerpo) no cuentan.
+func Similarity(a, b domain.PageSnapshot) float64 {
+	var score, total float64
+
+	add := func(weight, value float64) {
+		score += weight * value
+		total += weight
+	}
+
+	switch {
+	case a.Status == b.Status:
+		add(weightStatus, 1)
+	case a.Status/100 == b.Status/100:
+		add(weightStatus, 0.5)
+	default:
+		add(weightStatus, 0)
+	}
+
+	if a.Title != "" || b.Title != "" {
+		add(weightTitle, boolScore(strings.EqualFold(a.Title, b.Title)))
+	}
+
+	if len(a.Headers) > 0 || len(b.Headers) > 0 {
+		add(weightHeaders, jaccard(a.Headers, b.Headers))
+	}
+
+	if a.Length > 0 || b.Length > 0 {
+		body := 0.0
+		if a.Length > 0 && b.Length > 0 {
+			body = 1 - float64(bits.OnesCount64(a.Simhash^b.Simhash))/64
+		}
+		add(weightBody, body)
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return score / total
+}
+
+// Snapshot consulta la IP con host como Host/SNI y resume la respuesta; con
+// port 0 usa los puertos estándar
+func (p *Prober) Snapshot(ctx context.Context, ip string, port int, host string) (domain.PageSnapshot, error) {
+	resp, err := p.FetchPort(ctx, ip, port, host, "/")
+	if err != nil {
+		return domain.PageSnapshot{}, err
+	}
+	return Snapshot(resp), nil
+}
+
+// Compare implementa ports.OriginVerifier con Similarity
+func (p *Prober) Compare(baseline, candidate domain.PageSnapshot) float64 {
+	return Similarity(baseline, candidate)
+}
+
+// simhash calcula un simhash de 64 bits sobre las palabras del cuerpo
+func simhash(body []byte) uint64 {
+	var weights [64]int
+	tokens := strings.FieldsFunc(strings.ToLower(string(body)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(tokens) == 0 {
+		return 0
+	}
+
+	for _, token := range tokens {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(token))
+		sum := h.Sum64()
+		for i := range weights {
+			if sum&(1<<i) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+
+	var hash uint64
+	for i, w := range weights {
+		if w > 0 {
+			hash |= 1 << i
+		}
+	}
+	return hash
+}
+
+func jaccard(a, b []string) float64 {
+	set := make(map[string]int, len(a)+len(b))
+	for _, v := range a {
+		set[v] |= 1
+	}
+	for _, v := range b {
+		set[v] |= 2
+	}
+	if len(set) == 0 {
+		return 1
+	}
+	both := 0
+	for _, mask := range set {
+		if mask == 3 {
+			both++
+		}
+	}
+	return float64(both) / float64(len(set))
+}
+
+func boolScore(ok bool) float64 {
+	if ok {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,77 @@
+package httpprobe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/rs/zerolog"
+)
+
+const shopPage = `<!doctype html><html><head><title>Example Shop &amp; Co</title></head>
+<body><h1>Welcome to the shop</h1><ul>%s</ul><footer>Example Shop, all rights reserved</footer></body></html>`
+
+const shopItems = `<li>Red shoes</li><li>Blue shirt</li><li>Green hat</li><li>Black socks</li>`
+
+func shopHandler(cdn bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+		if cdn {
+			w.Header().Set("Server", "cloudflare")
+			w.Header().Set("CF-RAY", "8a1b2c3d4e5f6a7b-SCL")
+			w.Header().Set("CF-Cache-Status", "DYNAMIC")
+		} else {
+			w.Header().Set("Server", "nginx")
+		}
+		fmt.Fprintf(w, shopPage, shopItems)
+	}
+}
+
+func defaultPage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html")
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprint(w, "<html><head><title>404 Not Found</title></head><body><center><h1>404 Not Found</h1></center><hr><center>nginx</center></body></html>")
+}
+
+func snapshotOf(t *testing.T, handler http.Handler) domain.PageSnapshot {
+	t.Helper()
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+
+	prober := NewProber(nil, 2*time.Second, zerolog.Nop())
+	port := serverPort(t, server)
+	prober.SetPorts(port, port)
+
+	snapshot, err := prober.Snapshot(context.Background(), "127.0.0.1", 0, "shop.example.com")
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	return snapshot
+}
+
+func TestSimilarity(t *testing.T) {
+	front := snapshotOf(t, shopHandler(true))
+	if front.Title != "Example Shop & Co" {
+		t.Errorf("title = %q", front.Title)
+	}
+	for _, h := range front.Headers {
+		if h == "cf-ray" || h == "server" {
+			t.Errorf("cabecera volátil %q en el resumen", h)
+		}
+	}
+
+	origin := snapshotOf(t, shopHandler(false))
+	if score := Similarity(front, origin); score < 0.85 {
+		t.Errorf("mismo sitio: score = %.3f, esperado >= 0.85", score)
+	}
+
+	unrelated := snapshotOf(t, http.HandlerFunc(defaultPage))
+	if score := Similarity(front, unrelated); score >= 0.6 {
+		t.Errorf("sitio distinto: score = %.3f, esperado < 0.6", score)
+	}
+}
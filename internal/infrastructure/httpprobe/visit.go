@@ -0,0 +1,125 @@
+package httpprobe
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// maxRedirects limita los saltos seguidos por Visit
+const maxRedirects = 10
+
+// Visit consulta host en ip por scheme y sigue las redirecciones. Los saltos
+// al mismo host siguen usando ip; los saltos a otros hosts se resuelven por
+// DNS. Un salto fallido deja como final la última respuesta obtenida.
+func (p *Prober) Visit(ctx context.Context, ip, host, scheme string) (domain.WebProbe, error) {
+	start := time.Now()
+	req := Request{IP: ip, Host: host, Scheme: scheme, Path: "/"}
+
+	resp, err := p.Fetch(ctx, req)
+	if err != nil {
+		return domain.WebProbe{}, err
+	}
+	// Las cookies de los saltos (ej: sesión antes de ir al login) también
+	// identifican tecnologías
+	cookies := resp.Header.Values("Set-Cookie")
+
+	visit := domain.WebProbe{Scheme: scheme}
+	for range maxRedirects {
+		next, ok := redirectTarget(req, resp)
+		if !ok {
+			break
+		}
+		next.IP = hopIP(next.Host, host, ip)
+		visit.Redirects = append(visit.Redirects, requestURL(next))
+
+		nextResp, err := p.Fetch(ctx, next)
+		if err != nil {
+			p.logger.Debug().Err(err).Str("host", host).Str("url", requestURL(next)).Msg("Redirección sin respuesta")
+			visit.Redirects = visit.Redirects[:len(visit.Redirects)-1]
+			break
+		}
+		req, resp = next, nextResp
+		cookies = append(cookies, resp.Header.Values("Set-Cookie")...)
+	}
+
+	visit.Status = resp.StatusCode
+	visit.FinalURL = requestURL(req)
+	visit.Title = pageTitle(resp.Body)
+	visit.Server = resp.Header.Get("Server")
+	visit.ContentLength = resp.ContentLength
+	if visit.ContentLength < 0 {
+		visit.ContentLength = int64(len(resp.Body))
+	}
+	visit.ResponseMS = time.Since(start).Milliseconds()
+
+	if p.detector != nil {
+		header := resp.Header.Clone()
+		header["Set-Cookie"] = cookies
+		visit.Tech = p.detector.Detect(header, resp.Body)
+	}
+	return visit, nil
+}
+
+// redirectTarget retorna la petición a la que redirige resp, si es una
+// redirección HTTP(S) con Location válido
+func redirectTarget(req Request, resp *Response) (Request, bool) {
+	if resp.StatusCode < 300 || resp.StatusCode > 399 {
+		return Request{}, false
+	}
+	location := resp.Header.Get("Location")
+	if location == "" {
+		return Request{}, false
+	}
+	base, err := url.Parse(requestURL(req))
+	if err != nil {
+		return Request{}, false
+	}
+	target, err := base.Parse(location)
+	if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Hostname() == "" {
+		return Request{}, false
+	}
+
+	next := Request{Host: target.Hostname(), Scheme: target.Scheme, Path: target.RequestURI()}
+	if port := target.Port(); port != "" {
+		next.Port, err = strconv.Atoi(port)
+		if err != nil {
+			return Request{}, false
+		}
+	}
+	return next, true
+}
+
+// hopIP retorna la IP a usar en un salto: la original si el salto es al mismo
+// host, la del URL si es una IP literal y vacía (DNS) en otro caso
+func hopIP(hopHost, host, ip string) string {
+	if strings.EqualFold(hopHost, host) {
+		return ip
+	}
+	if addr, err := netip.ParseAddr(hopHost); err == nil {
+		return addr.String()
+	}
+	return ""
+}
+
+// requestURL arma el URL de una petición; el puerto solo aparece si no es el
+// estándar del esquema
+func requestURL(r Request) string {
+	host := r.Host
+	if r.Port != 0 {
+		host = net.JoinHostPort(r.Host, strconv.Itoa(r.Port))
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	path := r.Path
+	if path == "" {
+		path = "/"
+	}
+	return r.Scheme + "://" + host + path
+}
@@ -0,0 +1,121 @@
+package httpprobe
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/rs/zerolog"
+)
+
+func TestVisitFollowsRedirects(t *testing.T) {
+	body := "<html><head><title>\n  Bienvenido  </title></head></html>"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/home", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/home", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://"+testHost+"/final?lang=es", http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != testHost {
+			t.Errorf("Host = %q, esperado %q", r.Host, testHost)
+		}
+		w.Header().Set("Server", "nginx/1.25.3")
+		_, _ = w.Write([]byte(body))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	prober := NewProber(nil, 2*time.Second, zerolog.Nop())
+	port := serverPort(t, server)
+	prober.SetPorts(port, port)
+
+	visit, err := prober.Visit(context.Background(), "127.0.0.1", testHost, "http")
+	if err != nil {
+		t.Fatalf("Visit: %v", err)
+	}
+	wantRedirects := []string{"http://" + testHost + "/home", "http://" + testHost + "/final?lang=es"}
+	if !slices.Equal(visit.Redirects, wantRedirects) {
+		t.Errorf("redirects = %v, esperado %v", visit.Redirects, wantRedirects)
+	}
+	if visit.FinalURL != wantRedirects[1] {
+		t.Errorf("final_url = %q, esperado %q", visit.FinalURL, wantRedirects[1])
+	}
+	if visit.Status != http.StatusOK || visit.Title != "Bienvenido" || visit.Server != "nginx/1.25.3" {
+		t.Errorf("visita inesperada: %+v", visit)
+	}
+	if visit.ContentLength != int64(len(body)) {
+		t.Errorf("content_length = %d, esperado %d", visit.ContentLength, len(body))
+	}
+}
+
+func TestVisitStopsAtFailedHop(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedPort := serverPort(t, closed)
+	closed.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://127.0.0.1:"+strconv.Itoa(closedPort)+"/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	prober := NewProber(nil, time.Second, zerolog.Nop())
+	port := serverPort(t, server)
+	prober.SetPorts(port, port)
+
+	visit, err := prober.Visit(context.Background(), "127.0.0.1", testHost, "http")
+	if err != nil {
+		t.Fatalf("Visit: %v", err)
+	}
+	if visit.Status != http.StatusFound || len(visit.Redirects) != 0 {
+		t.Errorf("se esperaba la redirección como respuesta final: %+v", visit)
+	}
+	if visit.FinalURL != "http://"+testHost+"/" {
+		t.Errorf("final_url = %q", visit.FinalURL)
+	}
+}
+
+// cookieDetector reporta como tecnologías los nombres de las cookies recibidas
+type cookieDetector struct{}
+
+func (cookieDetector) Detect(header http.Header, _ []byte) []domain.Technology {
+	var techs []domain.Technology
+	for _, cookie := range (&http.Response{Header: header}).Cookies() {
+		techs = append(techs, domain.Technology{Name: cookie.Name})
+	}
+	return techs
+}
+
+func TestVisitDetectsWithRedirectCookies(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "PHPSESSID", Value: "abc"})
+		http.Redirect(w, r, "/login", http.StatusFound)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "csrf", Value: "xyz"})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	prober := NewProber(nil, 2*time.Second, zerolog.Nop())
+	port := serverPort(t, server)
+	prober.SetPorts(port, port)
+	prober.SetTechDetector(cookieDetector{})
+
+	visit, err := prober.Visit(context.Background(), "127.0.0.1", testHost, "http")
+	if err != nil {
+		t.Fatalf("Visit: %v", err)
+	}
+	want := []domain.Technology{{Name: "PHPSESSID"}, {Name: "csrf"}}
+	if !slices.Equal(visit.Tech, want) {
+		t.Errorf("tech = %v, esperado %v", visit.Tech, want)
+	}
+}
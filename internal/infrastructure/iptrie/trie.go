@@ -0,0 +1,139 @@
+package iptrie
+
+import (
+	"net/netip"
+)
+
+// Trie es un árbol binario de prefijos (IPv4 e IPv6) inmutable que resuelve
+// el prefijo más específico que contiene una dirección. Se construye con un
+// Builder y, una vez compilado, es seguro para uso concurrente sin locks.
+type Trie[V any] struct {
+	v4      []node
+	v6      []node
+	entries []entry[V]
+}
+
+type node struct {
+	child [2]int32 // índice del hijo (0 = sin hijo; la raíz nunca es hija)
+	entry int32    // índice en entries, -1 si el nodo no termina un prefijo
+}
+
+type entry[V any] struct {
+	prefix netip.Prefix
+	value  V
+}
+
+// Builder acumula prefijos para compilar un Trie
+type Builder[V any] struct {
+	trie *Trie[V]
+}
+
+func NewBuilder[V any]() *Builder[V] {
+	return &Builder[V]{
+		trie: &Trie[V]{
+			v4: []node{{entry: -1}},
+			v6: []node{{entry: -1}},
+		},
+	}
+}
+
+// Insert agrega un prefijo. Si el prefijo ya existe, su valor se reemplaza.
+func (b *Builder[V]) Insert(prefix netip.Prefix, value V) {
+	prefix = prefix.Masked()
+	addr := prefix.Addr()
+
+	nodes := &b.trie.v6
+	if addr.Is4() {
+		nodes = &b.trie.v4
+	}
+
+	bytes := addr.AsSlice()
+	cur := int32(0)
+	for i := 0; i < prefix.Bits(); i++ {
+		bit := bitAt(bytes, i)
+		next := (*nodes)[cur].child[bit]
+		if next == 0 {
+			*nodes = append(*nodes, node{entry: -1})
+			next = int32(len(*nodes) - 1)
+			(*nodes)[cur].child[bit] = next
+		}
+		cur = next
+	}
+
+	if idx := (*nodes)[cur].entry; idx >= 0 {
+		b.trie.entries[idx].value = value
+		return
+	}
+	b.trie.entries = append(b.trie.entries, entry[V]{prefix: prefix, value: value})
+	(*nodes)[cur].entry = int32(len(b.trie.entries) - 1)
+}
+
+// Build retorna el Trie compilado. El Builder no debe usarse después.
+func (b *Builder[V]) Build() *Trie[V] {
+	t := b.trie
+	b.trie = nil
+	return t
+}
+
+// Lookup retorna el prefijo más específico que contiene addr y su valor
+func (t *Trie[V]) Lookup(addr netip.Addr) (netip.Prefix, V, bool) {
+	var zero V
+	if t == nil || !addr.IsValid() {
+		return netip.Prefix{}, zero, false
+	}
+
+	addr = addr.Unmap()
+	nodes := t.v6
+	bits := 128
+	if addr.Is4() {
+		nodes = t.v4
+		bits = 32
+	}
+
+	// As16 evita asignaciones; en IPv4 los 4 bytes útiles están al final
+	raw := addr.As16()
+	bytes := raw[:]
+	if bits == 32 {
+		bytes = raw[12:]
+	}
+
+	best := nodes[0].entry
+	cur := int32(0)
+	for i := 0; i < bits; i++ {
+		cur = nodes[cur].child[bitAt(bytes, i)]
+		if cur == 0 {
+			break
+		}
+		if e := nodes[cur].entry; e >= 0 {
+			best = e
+		}
+	}
+
+	if best < 0 {
+		return netip.Prefix{}, zero, false
+	}
+	e := t.entries[best]
+	return e.prefix, e.value, true
+}
+
+// Len retorna la cantidad de prefijos del Trie
+func (t *Trie[V]) Len() int {
+	if t == nil {
+		return 0
+	}
+	return len(t.entries)
+}
+
+// Walk recorre los prefijos en orden de inserción
+func (t *Trie[V]) Walk(fn func(prefix netip.Prefix, value V)) {
+	if t == nil {
+		return
+	}
+	for _, e := range t.entries {
+		fn(e.prefix, e.value)
+	}
+}
+
+func bitAt(bytes []byte, i int) int {
+	return int(bytes[i/8]>>(7-uint(i%8))) & 1
+}
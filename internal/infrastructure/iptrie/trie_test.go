@@ -0,0 +1,119 @@
+package iptrie
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func build(prefixes map[string]string) *Trie[string] {
+	builder := NewBuilder[string]()
+	for cidr, value := range prefixes {
+		builder.Insert(netip.MustParsePrefix(cidr), value)
+	}
+	return builder.Build()
+}
+
+func TestLookup(t *testing.T) {
+	trie := build(map[string]string{
+		"104.16.0.0/13":     "cloudflare",
+		"104.16.132.0/24":   "zona",
+		"104.16.132.229/32": "host",
+		"2606:4700::/32":    "cloudflare6",
+		"2606:4700::1/128":  "host6",
+		"10.1.2.3/8":        "privada", // se enmascara a 10.0.0.0/8
+	})
+
+	tests := []struct {
+		addr   string
+		prefix string
+		value  string
+		ok     bool
+	}{
+		// Gana el prefijo más largo
+		{"104.16.132.229", "104.16.132.229/32", "host", true},
+		{"104.16.132.1", "104.16.132.0/24", "zona", true},
+		{"104.17.0.1", "104.16.0.0/13", "cloudflare", true},
+		{"104.24.0.1", "", "", false},
+		{"2606:4700::1", "2606:4700::1/128", "host6", true},
+		{"2606:4700::2", "2606:4700::/32", "cloudflare6", true},
+		{"2606:4701::1", "", "", false},
+		// IPv4 mapeada en IPv6 se busca como IPv4
+		{"::ffff:104.16.132.229", "104.16.132.229/32", "host", true},
+		{"::ffff:104.24.0.1", "", "", false},
+		{"10.200.0.1", "10.0.0.0/8", "privada", true},
+		// Las familias no se mezclan
+		{"::104.16.132.229", "", "", false},
+	}
+	for _, tt := range tests {
+		prefix, value, ok := trie.Lookup(netip.MustParseAddr(tt.addr))
+		if ok != tt.ok || value != tt.value || (ok && prefix.String() != tt.prefix) {
+			t.Errorf("Lookup(%s) = %s %q %v, esperado %s %q %v", tt.addr, prefix, value, ok, tt.prefix, tt.value, tt.ok)
+		}
+	}
+}
+
+func TestLookupDefaultRoutes(t *testing.T) {
+	trie := build(map[string]string{
+		"0.0.0.0/0":     "todo4",
+		"192.0.2.0/24":  "doc",
+		"::/0":          "todo6",
+		"2001:db8::/32": "doc6",
+	})
+
+	tests := []struct {
+		addr, value string
+	}{
+		{"192.0.2.7", "doc"},
+		{"198.51.100.1", "todo4"},
+		{"255.255.255.255", "todo4"},
+		{"0.0.0.0", "todo4"},
+		{"2001:db8::7", "doc6"},
+		{"2001:db9::1", "todo6"},
+		{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "todo6"},
+	}
+	for _, tt := range tests {
+		if _, value, ok := trie.Lookup(netip.MustParseAddr(tt.addr)); !ok || value != tt.value {
+			t.Errorf("Lookup(%s) = %q %v, esperado %q", tt.addr, value, ok, tt.value)
+		}
+	}
+}
+
+func TestInsertReplacesValue(t *testing.T) {
+	builder := NewBuilder[int]()
+	builder.Insert(netip.MustParsePrefix("192.0.2.0/24"), 1)
+	builder.Insert(netip.MustParsePrefix("192.0.2.128/24"), 2)
+	trie := builder.Build()
+
+	if trie.Len() != 1 {
+		t.Errorf("Len() = %d, esperado 1", trie.Len())
+	}
+	if _, value, ok := trie.Lookup(netip.MustParseAddr("192.0.2.1")); !ok || value != 2 {
+		t.Errorf("Lookup = %d %v, esperado 2", value, ok)
+	}
+
+	var walked []string
+	trie.Walk(func(prefix netip.Prefix, _ int) {
+		walked = append(walked, prefix.String())
+	})
+	if len(walked) != 1 || walked[0] != "192.0.2.0/24" {
+		t.Errorf("Walk = %v", walked)
+	}
+}
+
+func TestLookupEmpty(t *testing.T) {
+	var nilTrie *Trie[string]
+	if _, _, ok := nilTrie.Lookup(netip.MustParseAddr("192.0.2.1")); ok {
+		t.Error("un trie nil no debería coincidir")
+	}
+	if nilTrie.Len() != 0 {
+		t.Error("un trie nil debería tener largo 0")
+	}
+
+	trie := NewBuilder[string]().Build()
+	if _, _, ok := trie.Lookup(netip.MustParseAddr("2001:db8::1")); ok {
+		t.Error("un trie vacío no debería coincidir")
+	}
+	if _, _, ok := trie.Lookup(netip.Addr{}); ok {
+		t.Error("una dirección inválida no debería coincidir")
+	}
+}
@@ -0,0 +1,229 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// Binder aplica la IP/interfaz de origen y la familia de direcciones
+// configuradas a todas las conexiones salientes (DNS y HTTP)
+type Binder struct {
+	family   string
+	bound    bool // se pidió -source-ip o -interface
+	localV4  netip.Addr
+	localV6  netip.Addr
+	timeout  time.Duration
+	resolver *net.Resolver
+}
+
+func NewBinder(config domain.ScannerConfig) (*Binder, error) {
+	b := &Binder{
+		family:  config.IPFamily,
+		bound:   config.SourceIP != "" || config.Interface != "",
+		timeout: config.Timeout,
+	}
+	if b.family == "" {
+		b.family = domain.IPFamilyAny
+	}
+	// Los hostnames (APIs de rangos, redirecciones) se resuelven saliendo
+	// también por la dirección de origen
+	b.resolver = &net.Resolver{PreferGo: true, Dial: b.DialResolver}
+
+	if config.SourceIP != "" {
+		addr, err := netip.ParseAddr(config.SourceIP)
+		if err != nil {
+			return nil, fmt.Errorf("IP de origen inválida %q: %w", config.SourceIP, err)
+		}
+		b.setLocal(addr.Unmap())
+	}
+
+	if config.Interface != "" {
+		if err := b.loadInterface(config.Interface); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.bound && !b.localV4.IsValid() && !b.localV6.IsValid() {
+		return nil, fmt.Errorf("no hay direcciones de origen compatibles con la familia %s", b.family)
+	}
+
+	return b, nil
+}
+
+func (b *Binder) setLocal(addr netip.Addr) {
+	if addr.Is4() && b.family != domain.IPFamilyIPv6 && !b.localV4.IsValid() {
+		b.localV4 = addr
+	}
+	if addr.Is6() && b.family != domain.IPFamilyIPv4 && !b.localV6.IsValid() {
+		b.localV6 = addr
+	}
+}
+
+func (b *Binder) loadInterface(name string) error {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return fmt.Errorf("interfaz %q no encontrada: %w", name, err)
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return fmt.Errorf("leyendo direcciones de %q: %w", name, err)
+	}
+
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		addr, ok := netip.AddrFromSlice(ipNet.IP)
+		if !ok {
+			continue
+		}
+		addr = addr.Unmap()
+		// Las link-local IPv6 requieren zona y no sirven para tráfico a Internet
+		if addr.IsLinkLocalUnicast() {
+			continue
+		}
+		b.setLocal(addr)
+	}
+
+	return nil
+}
+
+// Family retorna la familia de direcciones efectiva (any|ipv4|ipv6)
+func (b *Binder) Family() string {
+	return b.family
+}
+
+// IPNetwork retorna la red a usar en net.Resolver.LookupIP ("ip", "ip4" o "ip6")
+func (b *Binder) IPNetwork() string {
+	switch b.family {
+	case domain.IPFamilyIPv4:
+		return "ip4"
+	case domain.IPFamilyIPv6:
+		return "ip6"
+	default:
+		return "ip"
+	}
+}
+
+// Allows indica si una dirección pertenece a la familia configurada
+func (b *Binder) Allows(addr netip.Addr) bool {
+	return domain.AllowsFamily(b.family, addr)
+}
+
+// DialContext abre conexiones respetando la familia y la dirección de origen.
+// Un hostname se resuelve con la familia configurada y se prueban sus
+// direcciones en orden. Es compatible con http.Transport.DialContext.
+func (b *Binder) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return b.dialAddress(ctx, network, address, b.family)
+}
+
+// DialResolver abre la conexión al servidor DNS. La familia configurada no
+// aplica: limita los registros A/AAAA consultados, no cómo se llega al
+// servidor. La dirección de origen se usa si es de la familia del servidor.
+func (b *Binder) DialResolver(ctx context.Context, network, address string) (net.Conn, error) {
+	return b.dialAddress(ctx, network, address, domain.IPFamilyAny)
+}
+
+// dialAddress conecta a host:port limitando las direcciones a family
+func (b *Binder) dialAddress(ctx context.Context, network, address, family string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if remote, err := netip.ParseAddr(host); err == nil {
+		remote = remote.Unmap()
+		if !domain.AllowsFamily(family, remote) {
+			return nil, fmt.Errorf("la dirección %s no pertenece a la familia %s", remote, family)
+		}
+		return b.dial(ctx, network, remote, port)
+	}
+
+	ipNetwork := "ip"
+	if family == b.family {
+		ipNetwork = b.IPNetwork()
+	}
+	ips, err := b.resolver.LookupNetIP(ctx, ipNetwork, host)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, remote := range ips {
+		remote = remote.Unmap()
+		if !domain.AllowsFamily(family, remote) {
+			continue
+		}
+		conn, err := b.dial(ctx, network, remote, port)
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("sin direcciones %s para %s", family, host)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// dial conecta desde la dirección de origen de la familia de remote. Con
+// -source-ip o -interface, una familia sin dirección de origen no se conecta:
+// saldría por la ruta por defecto y se perdería el binding.
+func (b *Binder) dial(ctx context.Context, network string, remote netip.Addr, port string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: b.timeout}
+	local, suffix := b.localV4, "4"
+	if !remote.Is4() {
+		local, suffix = b.localV6, "6"
+	}
+	network = withFamily(network, suffix)
+	if local.IsValid() {
+		dialer.LocalAddr = localAddr(network, local)
+	} else if b.bound {
+		return nil, fmt.Errorf("sin dirección de origen IPv%s para conectar a %s; restringir la familia con -ip-family", suffix, remote)
+	}
+
+	return dialer.DialContext(ctx, network, net.JoinHostPort(remote.String(), port))
+}
+
+// HTTPClient crea un cliente HTTP cuyas conexiones pasan por el binder
+func (b *Binder) HTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			DialContext:         b.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+func withFamily(network, suffix string) string {
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		return "tcp" + suffix
+	case "udp", "udp4", "udp6":
+		return "udp" + suffix
+	default:
+		return network
+	}
+}
+
+func localAddr(network string, addr netip.Addr) net.Addr {
+	if network == "udp4" || network == "udp6" {
+		return net.UDPAddrFromAddrPort(netip.AddrPortFrom(addr, 0))
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, 0))
+}
@@ -0,0 +1,113 @@
+package network
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		family string
+		addr   string
+		want   bool
+	}{
+		{domain.IPFamilyAny, "192.0.2.1", true},
+		{domain.IPFamilyAny, "2001:db8::1", true},
+		{"", "2001:db8::1", true},
+		{domain.IPFamilyIPv4, "192.0.2.1", true},
+		{domain.IPFamilyIPv4, "2001:db8::1", false},
+		{domain.IPFamilyIPv4, "::ffff:192.0.2.1", true},
+		{domain.IPFamilyIPv6, "2001:db8::1", true},
+		{domain.IPFamilyIPv6, "192.0.2.1", false},
+		{domain.IPFamilyIPv6, "::ffff:192.0.2.1", false},
+	}
+	for _, tt := range tests {
+		binder, err := NewBinder(domain.ScannerConfig{IPFamily: tt.family})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := binder.Allows(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("family=%q Allows(%s) = %v, esperado %v", tt.family, tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestDialResolverIgnoresFamily(t *testing.T) {
+	// Servidor DNS IPv4 con un escaneo solo IPv6
+	server, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	address := server.LocalAddr().String()
+
+	binder, err := NewBinder(domain.ScannerConfig{IPFamily: domain.IPFamilyIPv6})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := binder.DialResolver(context.Background(), "udp", address)
+	if err != nil {
+		t.Fatalf("DialResolver: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if n, _, err := server.ReadFrom(buf); err != nil || string(buf[:n]) != "ping" {
+		t.Errorf("el servidor no recibió el paquete: %q %v", buf[:n], err)
+	}
+
+	// Las conexiones que no son al servidor DNS siguen respetando la familia
+	if _, err := binder.DialContext(context.Background(), "udp", address); err == nil {
+		t.Error("DialContext debería rechazar una IPv4 con -ip-family ipv6")
+	}
+}
+
+func TestDialUnboundFamily(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Solo hay dirección de origen IPv4: IPv6 no debe salir sin binding
+	binder, err := NewBinder(domain.ScannerConfig{SourceIP: "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := binder.DialContext(context.Background(), "tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("DialContext IPv4: %v", err)
+	}
+	if local := conn.LocalAddr().(*net.TCPAddr).IP.String(); local != "127.0.0.1" {
+		t.Errorf("dirección local = %s, esperado 127.0.0.1", local)
+	}
+	conn.Close()
+
+	for _, dial := range []func(context.Context, string, string) (net.Conn, error){binder.DialContext, binder.DialResolver} {
+		if conn, err := dial(context.Background(), "tcp", net.JoinHostPort("::1", port)); err == nil {
+			conn.Close()
+			t.Error("se esperaba error al conectar a IPv6 sin dirección de origen IPv6")
+		}
+	}
+
+	// Sin -source-ip ni -interface no hay restricción
+	unbound, err := NewBinder(domain.ScannerConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := unbound.dial(context.Background(), "tcp", netip.MustParseAddr("::1"), port); err != nil && strings.Contains(err.Error(), "origen") {
+		t.Errorf("sin binding no debería exigir dirección de origen: %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+)
+
+// Tipos de bloque y constantes pcapng (draft-ietf-opsawg-pcapng)
+const (
+	blockSectionHeader      = 0x0A0D0D0A
+	blockInterfaceDesc      = 0x00000001
+	blockEnhancedPacket     = 0x00000006
+	byteOrderMagic          = 0x1A2B3C4D
+	linkTypeRaw             = 101 // LINKTYPE_RAW: el paquete empieza en la cabecera IP
+	optEndOfOpt             = 0
+	optComment              = 1
+	optIfName               = 2
+	protoUDP                = 17
+	ipv4HeaderLen           = 20
+	ipv6HeaderLen           = 40
+	udpHeaderLen            = 8
+	defaultTTL              = 64
+	sectionLengthUnspecific = 0xFFFFFFFFFFFFFFFF // -1: longitud de sección no especificada
+)
+
+// Writer escribe mensajes DNS en un archivo pcapng sintetizando las cabeceras
+// IP/UDP. Es seguro para uso concurrente.
+type Writer struct {
+	mu      sync.Mutex
+	file    *os.File
+	w       *bufio.Writer
+	ipID    uint16
+	packets int
+}
+
+// Create crea el archivo pcapng y escribe la cabecera de sección e interfaz
+func Create(path string) (*Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("creando pcap: %w", err)
+	}
+
+	pw := &Writer{
+		file: file,
+		w:    bufio.NewWriter(file),
+	}
+
+	if err := pw.writeHeader(); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return pw, nil
+}
+
+func (pw *Writer) writeHeader() error {
+	// Section Header Block
+	shb := make([]byte, 0, 28)
+	shb = binary.LittleEndian.AppendUint32(shb, byteOrderMagic)
+	shb = binary.LittleEndian.AppendUint16(shb, 1) // major
+	shb = binary.LittleEndian.AppendUint16(shb, 0) // minor
+	shb = binary.LittleEndian.AppendUint64(shb, sectionLengthUnspecific)
+	shb = appendOption(shb, optComment, []byte("cloudrip DNS capture (cabeceras IP/UDP sintetizadas)"))
+	shb = appendOption(shb, optEndOfOpt, nil)
+	if err := pw.writeBlock(blockSectionHeader, shb); err != nil {
+		return err
+	}
+
+	// Interface Description Block
+	idb := make([]byte, 0, 32)
+	idb = binary.LittleEndian.AppendUint16(idb, linkTypeRaw)
+	idb = binary.LittleEndian.AppendUint16(idb, 0) // reservado
+	idb = binary.LittleEndian.AppendUint32(idb, 0) // snaplen sin límite
+	idb = appendOption(idb, optIfName, []byte("cloudrip-dns"))
+	idb = appendOption(idb, optEndOfOpt, nil)
+	return pw.writeBlock(blockInterfaceDesc, idb)
+}
+
+// WritePacket registra un mensaje DNS enviado de src a dst en el instante ts
+func (pw *Writer) WritePacket(ts time.Time, src, dst netip.AddrPort, payload []byte) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	pw.ipID++
+	packet, err := buildUDPPacket(src, dst, payload, pw.ipID)
+	if err != nil {
+		return err
+	}
+
+	// Resolución de timestamp por defecto: microsegundos
+	micros := uint64(ts.UnixMicro())
+
+	epb := make([]byte, 0, 20+len(packet)+3)
+	epb = binary.LittleEndian.AppendUint32(epb, 0) // interface id
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(micros>>32))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(micros))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(len(packet)))
+	epb = binary.LittleEndian.AppendUint32(epb, uint32(len(packet)))
+	epb = append(epb, packet...)
+	epb = pad32(epb)
+
+	if err := pw.writeBlock(blockEnhancedPacket, epb); err != nil {
+		return err
+	}
+	pw.packets++
+	return nil
+}
+
+// Packets retorna la cantidad de paquetes escritos
+func (pw *Writer) Packets() int {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	return pw.packets
+}
+
+// Close vuelca el buffer y cierra el archivo
+func (pw *Writer) Close() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if err := pw.w.Flush(); err != nil {
+		_ = pw.file.Close()
+		return fmt.Errorf("escribiendo pcap: %w", err)
+	}
+	if err := pw.file.Close(); err != nil {
+		return fmt.Errorf("cerrando pcap: %w", err)
+	}
+	return nil
+}
+
+func (pw *Writer) writeBlock(blockType uint32, body []byte) error {
+	total := uint32(12 + len(body))
+	block := make([]byte, 0, total)
+	block = binary.LittleEndian.AppendUint32(block, blockType)
+	block = binary.LittleEndian.AppendUint32(block, total)
+	block = append(block, body...)
+	block = binary.LittleEndian.AppendUint32(block, total)
+
+	if _, err := pw.w.Write(block); err != nil {
+		return fmt.Errorf("escribiendo bloque pcapng: %w", err)
+	}
+	return nil
+}
+
+func appendOption(buf []byte, code uint16, value []byte) []byte {
+	buf = binary.LittleEndian.AppendUint16(buf, code)
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(value)))
+	buf = append(buf, value...)
+	return pad32(buf)
+}
+
+func pad32(buf []byte) []byte {
+	for len(buf)%4 != 0 {
+		buf = append(buf, 0)
+	}
+	return buf
+}
+
+func buildUDPPacket(src, dst netip.AddrPort, payload []byte, id uint16) ([]byte, error) {
+	srcIP, dstIP := src.Addr().Unmap(), dst.Addr().Unmap()
+	if srcIP.Is4() != dstIP.Is4() {
+		return nil, fmt.Errorf("familias distintas en origen (%s) y destino (%s)", srcIP, dstIP)
+	}
+
+	udpLen := udpHeaderLen + len(payload)
+	udp := make([]byte, 0, udpLen)
+	udp = binary.BigEndian.AppendUint16(udp, src.Port())
+	udp = binary.BigEndian.AppendUint16(udp, dst.Port())
+	udp = binary.BigEndian.AppendUint16(udp, uint16(udpLen))
+	udp = binary.BigEndian.AppendUint16(udp, 0) // checksum, se calcula abajo
+	udp = append(udp, payload...)
+
+	var packet []byte
+	if srcIP.Is4() {
+		s, d := srcIP.As4(), dstIP.As4()
+		ip := make([]byte, 0, ipv4HeaderLen+udpLen)
+		ip = append(ip, 0x45, 0)
+		ip = binary.BigEndian.AppendUint16(ip, uint16(ipv4HeaderLen+udpLen))
+		ip = binary.BigEndian.AppendUint16(ip, id)
+		ip = binary.BigEndian.AppendUint16(ip, 0x4000) // Don't Fragment
+		ip = append(ip, defaultTTL, protoUDP, 0, 0)
+		ip = append(ip, s[:]...)
+		ip = append(ip, d[:]...)
+		binary.BigEndian.PutUint16(ip[10:], checksum(ip, 0))
+
+		pseudo := pseudoHeaderSum(s[:], d[:], udpLen)
+		binary.BigEndian.PutUint16(udp[6:], udpChecksum(udp, pseudo))
+		packet = append(ip, udp...)
+	} else {
+		s, d := srcIP.As16(), dstIP.As16()
+		ip := make([]byte, 0, ipv6HeaderLen+udpLen)
+		ip = binary.BigEndian.AppendUint32(ip, 0x60000000)
+		ip = binary.BigEndian.AppendUint16(ip, uint16(udpLen))
+		ip = append(ip, protoUDP, defaultTTL)
+		ip = append(ip, s[:]...)
+		ip = append(ip, d[:]...)
+
+		pseudo := pseudoHeaderSum(s[:], d[:], udpLen)
+		binary.BigEndian.PutUint16(udp[6:], udpChecksum(udp, pseudo))
+		packet = append(ip, udp...)
+	}
+
+	return packet, nil
+}
+
+func pseudoHeaderSum(src, dst []byte, udpLen int) uint32 {
+	var sum uint32
+	sum = sumWords(src, sum)
+	sum = sumWords(dst, sum)
+	sum += protoUDP
+	sum += uint32(udpLen)
+	return sum
+}
+
+func udpChecksum(udp []byte, pseudo uint32) uint16 {
+	cs := checksum(udp, pseudo)
+	if cs == 0 {
+		// En UDP un checksum 0 significa "sin checksum"
+		return 0xffff
+	}
+	return cs
+}
+
+func checksum(data []byte, initial uint32) uint16 {
+	sum := sumWords(data, initial)
+	for sum>>16 != 0 {
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	return ^uint16(sum)
+}
+
+func sumWords(data []byte, sum uint32) uint32 {
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(data[i])<<8 | uint32(data[i+1])
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	return sum
+}
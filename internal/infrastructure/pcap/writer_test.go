@@ -0,0 +1,162 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// block es un bloque pcapng leído del archivo
+type block struct {
+	kind uint32
+	body []byte
+}
+
+// readBlocks recorre el archivo validando que cada bloque repita su longitud
+// al final y esté alineado a 32 bits
+func readBlocks(t *testing.T, data []byte) []block {
+	t.Helper()
+	var blocks []block
+	for len(data) > 0 {
+		if len(data) < 12 {
+			t.Fatalf("bloque truncado: %d bytes", len(data))
+		}
+		kind := binary.LittleEndian.Uint32(data)
+		total := int(binary.LittleEndian.Uint32(data[4:]))
+		if total%4 != 0 || total < 12 || total > len(data) {
+			t.Fatalf("longitud de bloque inválida: %d (quedan %d)", total, len(data))
+		}
+		if trailer := int(binary.LittleEndian.Uint32(data[total-4:])); trailer != total {
+			t.Fatalf("longitud final %d distinta de la inicial %d", trailer, total)
+		}
+		blocks = append(blocks, block{kind: kind, body: data[8 : total-4]})
+		data = data[total:]
+	}
+	return blocks
+}
+
+func TestWriterBlockLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dns.pcapng")
+	pw, err := Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := time.Unix(1700000000, 123456000)
+	payloads := [][]byte{[]byte("query"), []byte("respuesta!")}
+	v4 := [2]netip.AddrPort{netip.MustParseAddrPort("192.0.2.10:53000"), netip.MustParseAddrPort("198.51.100.1:53")}
+	v6 := [2]netip.AddrPort{netip.MustParseAddrPort("[2001:db8::10]:53000"), netip.MustParseAddrPort("[2001:db8::53]:53")}
+	if err := pw.WritePacket(ts, v4[0], v4[1], payloads[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.WritePacket(ts, v6[1], v6[0], payloads[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := pw.WritePacket(ts, v4[0], v6[1], payloads[0]); err == nil {
+		t.Error("se esperaba error con familias distintas")
+	}
+	if pw.Packets() != 2 {
+		t.Errorf("Packets() = %d, esperado 2", pw.Packets())
+	}
+	if err := pw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := readBlocks(t, data)
+	wantKinds := []uint32{blockSectionHeader, blockInterfaceDesc, blockEnhancedPacket, blockEnhancedPacket}
+	if len(blocks) != len(wantKinds) {
+		t.Fatalf("%d bloques, esperados %d", len(blocks), len(wantKinds))
+	}
+	for i, kind := range wantKinds {
+		if blocks[i].kind != kind {
+			t.Errorf("bloque %d tipo %#x, esperado %#x", i, blocks[i].kind, kind)
+		}
+	}
+
+	shb := blocks[0].body
+	if magic := binary.LittleEndian.Uint32(shb); magic != byteOrderMagic {
+		t.Errorf("byte-order magic = %#x", magic)
+	}
+	if major, minor := binary.LittleEndian.Uint16(shb[4:]), binary.LittleEndian.Uint16(shb[6:]); major != 1 || minor != 0 {
+		t.Errorf("versión %d.%d, esperada 1.0", major, minor)
+	}
+	if link := binary.LittleEndian.Uint16(blocks[1].body); link != linkTypeRaw {
+		t.Errorf("link type = %d, esperado %d", link, linkTypeRaw)
+	}
+
+	tests := []struct {
+		name     string
+		epb      []byte
+		headerIP int
+		payload  []byte
+		src, dst netip.AddrPort
+	}{
+		{"ipv4", blocks[2].body, ipv4HeaderLen, payloads[0], v4[0], v4[1]},
+		{"ipv6", blocks[3].body, ipv6HeaderLen, payloads[1], v6[1], v6[0]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			epb := tt.epb
+			micros := uint64(binary.LittleEndian.Uint32(epb[4:]))<<32 | uint64(binary.LittleEndian.Uint32(epb[8:]))
+			if micros != uint64(ts.UnixMicro()) {
+				t.Errorf("timestamp = %d, esperado %d", micros, ts.UnixMicro())
+			}
+			captured := int(binary.LittleEndian.Uint32(epb[12:]))
+			original := int(binary.LittleEndian.Uint32(epb[16:]))
+			wantLen := tt.headerIP + udpHeaderLen + len(tt.payload)
+			if captured != wantLen || original != wantLen {
+				t.Fatalf("longitudes captured=%d original=%d, esperado %d", captured, original, wantLen)
+			}
+			if padded := 20 + (wantLen+3)/4*4; len(epb) != padded {
+				t.Errorf("cuerpo del EPB %d bytes, esperado %d", len(epb), padded)
+			}
+
+			packet := epb[20 : 20+captured]
+			var src, dst netip.Addr
+			if tt.headerIP == ipv4HeaderLen {
+				if packet[0] != 0x45 || packet[9] != protoUDP {
+					t.Errorf("cabecera IPv4 inesperada: % x", packet[:ipv4HeaderLen])
+				}
+				if total := int(binary.BigEndian.Uint16(packet[2:])); total != wantLen {
+					t.Errorf("longitud IPv4 = %d, esperado %d", total, wantLen)
+				}
+				if checksum(packet[:ipv4HeaderLen], 0) != 0 {
+					t.Error("checksum IPv4 inválido")
+				}
+				src, dst = netip.AddrFrom4([4]byte(packet[12:16])), netip.AddrFrom4([4]byte(packet[16:20]))
+			} else {
+				if packet[0]>>4 != 6 || packet[6] != protoUDP {
+					t.Errorf("cabecera IPv6 inesperada: % x", packet[:ipv6HeaderLen])
+				}
+				if payloadLen := int(binary.BigEndian.Uint16(packet[4:])); payloadLen != udpHeaderLen+len(tt.payload) {
+					t.Errorf("payload length IPv6 = %d", payloadLen)
+				}
+				src, dst = netip.AddrFrom16([16]byte(packet[8:24])), netip.AddrFrom16([16]byte(packet[24:40]))
+			}
+			if src != tt.src.Addr() || dst != tt.dst.Addr() {
+				t.Errorf("direcciones %s → %s, esperado %s → %s", src, dst, tt.src.Addr(), tt.dst.Addr())
+			}
+
+			udp := packet[tt.headerIP:]
+			if sport, dport := binary.BigEndian.Uint16(udp), binary.BigEndian.Uint16(udp[2:]); sport != tt.src.Port() || dport != tt.dst.Port() {
+				t.Errorf("puertos %d → %d", sport, dport)
+			}
+			if udpLen := int(binary.BigEndian.Uint16(udp[4:])); udpLen != len(udp) {
+				t.Errorf("longitud UDP = %d, esperado %d", udpLen, len(udp))
+			}
+			if checksum(udp, pseudoHeaderSum(src.AsSlice(), dst.AsSlice(), len(udp))) != 0 {
+				t.Error("checksum UDP inválido")
+			}
+			if string(udp[udpHeaderLen:]) != string(tt.payload) {
+				t.Errorf("payload = %q, esperado %q", udp[udpHeaderLen:], tt.payload)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package portscan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// DialFunc abre conexiones salientes (ej: network.Binder.DialContext)
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// Conjuntos de puertos con nombre para ParsePorts
+var portSets = map[string][]int{
+	// Puertos HTTP/HTTPS que Cloudflare acepta con proxy
+	"cloudflare": {80, 443, 2052, 2053, 2082, 2083, 2086, 2087, 2095, 2096, 8080, 8443, 8880},
+	"web":        {80, 443, 8000, 8008, 8080, 8081, 8443, 8888, 9443},
+	// Paneles de administración y servicios que suelen quedar expuestos
+	"admin": {2082, 2083, 2086, 2087, 2095, 2096, 3000, 5000, 7001, 8006, 8069, 8090, 8443, 8834, 9000, 9090, 9200, 10000},
+}
+
+// DefaultPorts es el conjunto por defecto: todos los conjuntos con nombre
+const DefaultPorts = "top"
+
+// ParsePorts interpreta una lista separada por comas de puertos, rangos
+// (9000-9010) y conjuntos con nombre (cloudflare, web, admin, top)
+func ParsePorts(spec string) ([]int, error) {
+	seen := make(map[int]bool)
+	var ports []int
+	add := func(port int) {
+		if !seen[port] {
+			seen[port] = true
+			ports = append(ports, port)
+		}
+	}
+
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if field == "" {
+			continue
+		}
+		if field == "top" {
+			for _, set := range portSets {
+				for _, port := range set {
+					add(port)
+				}
+			}
+			continue
+		}
+		if set, ok := portSets[field]; ok {
+			for _, port := range set {
+				add(port)
+			}
+			continue
+		}
+
+		low, high, isRange := strings.Cut(field, "-")
+		first, err := parsePort(low)
+		if err != nil {
+			return nil, err
+		}
+		last := first
+		if isRange {
+			if last, err = parsePort(high); err != nil {
+				return nil, err
+			}
+			if last < first {
+				return nil, fmt.Errorf("rango de puertos inválido: %q", field)
+			}
+		}
+		for port := first; port <= last; port++ {
+			add(port)
+		}
+	}
+
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("lista de puertos vacía")
+	}
+	slices.Sort(ports)
+	return ports, nil
+}
+
+func parsePort(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("puerto inválido: %q", value)
+	}
+	return port, nil
+}
+
+// Prober detecta puertos TCP abiertos con connect(). Las conexiones
+// simultáneas se limitan entre todas las IPs, no por IP.
+type Prober struct {
+	dial    DialFunc
+	ports   []int
+	timeout time.Duration
+	sem     chan struct{}
+	logger  zerolog.Logger
+}
+
+func NewProber(dial DialFunc, ports []int, concurrency int, timeout time.Duration, logger zerolog.Logger) *Prober {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	return &Prober{
+		dial:    dial,
+		ports:   ports,
+		timeout: timeout,
+		sem:     make(chan struct{}, max(concurrency, 1)),
+		logger:  logger.With().Str("component", "portscan").Logger(),
+	}
+}
+
+// OpenPorts retorna, ordenados, los puertos de ip que aceptan la conexión
+func (p *Prober) OpenPorts(ctx context.Context, ip string) ([]int, error) {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		open []int
+	)
+
+	for _, port := range p.ports {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		case p.sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-p.sem }()
+
+			if p.connect(ctx, ip, port) {
+				mu.Lock()
+				open = append(open, port)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	slices.Sort(open)
+	return open, nil
+}
+
+// connect intenta una conexión TCP; un timeout 0 deja el límite al contexto
+func (p *Prober) connect(ctx context.Context, ip string, port int) bool {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+	conn, err := p.dial(ctx, "tcp", address)
+	if err != nil {
+		return false
+	}
+	if err := conn.Close(); err != nil {
+		p.logger.Debug().Err(err).Str("address", address).Msg("Error cerrando conexión")
+	}
+	return true
+}
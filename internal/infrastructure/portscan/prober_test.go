@@ -0,0 +1,83 @@
+package portscan
+
+import (
+	"context"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParsePorts(t *testing.T) {
+	ports, err := ParsePorts("8443, 9000-9002,cloudflare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []int{443, 2053, 8443, 8880, 9000, 9001, 9002} {
+		if !slices.Contains(ports, want) {
+			t.Errorf("falta el puerto %d en %v", want, ports)
+		}
+	}
+	if !slices.IsSorted(ports) {
+		t.Errorf("puertos sin ordenar: %v", ports)
+	}
+	if countOf(ports, 8443) != 1 {
+		t.Errorf("8443 repetido: %v", ports)
+	}
+
+	for _, spec := range []string{"", "0", "70000", "9010-9000", "http"} {
+		if _, err := ParsePorts(spec); err == nil {
+			t.Errorf("ParsePorts(%q) debería fallar", spec)
+		}
+	}
+}
+
+func TestOpenPorts(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	open := listener.Addr().(*net.TCPAddr).Port
+
+	// Un puerto recién liberado queda cerrado
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := closedListener.Addr().(*net.TCPAddr).Port
+	closedListener.Close()
+
+	// Un timeout 0 no limita la conexión
+	for _, timeout := range []time.Duration{time.Second, 0} {
+		prober := NewProber(nil, []int{closed, open}, 2, timeout, zerolog.Nop())
+		ports, err := prober.OpenPorts(context.Background(), "127.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(ports, []int{open}) {
+			t.Errorf("timeout %v: puertos abiertos = %v, se esperaba [%d]", timeout, ports, open)
+		}
+	}
+}
+
+func countOf(ports []int, port int) int {
+	n := 0
+	for _, p := range ports {
+		if p == port {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,285 @@
+package remediate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+// Formatos de salida soportados
+const (
+	FormatNftables = "nftables"
+	FormatIptables = "iptables"
+	FormatUFW      = "ufw"
+	FormatNginx    = "nginx"
+	FormatApache   = "apache"
+	FormatAWSSG    = "aws-sg"
+)
+
+// Formats lista los formatos en el orden en que se generan con "all"
+var Formats = []string{FormatNftables, FormatIptables, FormatUFW, FormatNginx, FormatApache, FormatAWSSG}
+
+// fileNames son los nombres de archivo usados al generar todos los formatos
+var fileNames = map[string]string{
+	FormatNftables: "lockdown.nft",
+	FormatIptables: "lockdown-iptables.sh",
+	FormatUFW:      "lockdown-ufw.sh",
+	FormatNginx:    "lockdown-nginx.conf",
+	FormatApache:   "lockdown-apache.conf",
+	FormatAWSSG:    "lockdown-aws-sg.json",
+}
+
+// Origin es una IP expuesta y los hosts que resuelven a ella
+type Origin struct {
+	IP    string   `json:"ip"`
+	Hosts []string `json:"hosts"`
+}
+
+// Plan es lo necesario para generar las reglas: los orígenes a proteger, los
+// rangos de Cloudflare permitidos y los puertos a restringir
+type Plan struct {
+	Domain        string
+	Ranges        domain.CFRanges
+	Ports         []int
+	Origins       []Origin
+	SecurityGroup string
+	GeneratedAt   time.Time
+}
+
+// Origins agrupa por IP las respuestas que no están detrás de un CDN/WAF
+func Origins(result *domain.ScanResult) []Origin {
+	hosts := make(map[string]map[string]bool)
+	for fqdn, entries := range result.Results {
+		for _, entry := range entries {
+			if entry.IP == "" || entry.Provider != "" {
+				continue
+			}
+			if hosts[entry.IP] == nil {
+				hosts[entry.IP] = make(map[string]bool)
+			}
+			hosts[entry.IP][fqdn] = true
+		}
+	}
+
+	origins := make([]Origin, 0, len(hosts))
+	for ip, names := range hosts {
+		origin := Origin{IP: ip}
+		for name := range names {
+			origin.Hosts = append(origin.Hosts, name)
+		}
+		sort.Strings(origin.Hosts)
+		origins = append(origins, origin)
+	}
+	sort.Slice(origins, func(i, j int) bool {
+		a, errA := netip.ParseAddr(origins[i].IP)
+		b, errB := netip.ParseAddr(origins[j].IP)
+		if errA != nil || errB != nil {
+			return origins[i].IP < origins[j].IP
+		}
+		return a.Less(b)
+	})
+	return origins
+}
+
+// FileName retorna el nombre de archivo por defecto de un formato
+func FileName(format string) string {
+	return fileNames[format]
+}
+
+// Render escribe las reglas del formato pedido
+func Render(w io.Writer, format string, plan Plan) error {
+	if len(plan.Ranges.IPv4) == 0 && len(plan.Ranges.IPv6) == 0 {
+		return fmt.Errorf("no hay rangos de Cloudflare para permitir")
+	}
+	if len(plan.Ports) == 0 {
+		return fmt.Errorf("no hay puertos a restringir")
+	}
+
+	switch format {
+	case FormatNftables:
+		return renderNftables(w, plan)
+	case FormatIptables:
+		return renderIptables(w, plan)
+	case FormatUFW:
+		return renderUFW(w, plan)
+	case FormatNginx:
+		return renderNginx(w, plan)
+	case FormatApache:
+		return renderApache(w, plan)
+	case FormatAWSSG:
+		return renderAWSSG(w, plan)
+	default:
+		return fmt.Errorf("formato desconocido: %s", format)
+	}
+}
+
+// header describe el origen de las reglas como comentarios con el prefijo dado
+func header(w io.Writer, prefix string, plan Plan) {
+	source := plan.Ranges.Source
+	fmt.Fprintf(w, "%s Generado por cloudrip remediate para %s (%s)\n", prefix, plan.Domain, plan.GeneratedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(w, "%s Permite solo Cloudflare en los puertos %s\n", prefix, joinPorts(plan.Ports, ","))
+	fmt.Fprintf(w, "%s Rangos: %s source=%s version=%s\n", prefix, source.Provider, source.Source, source.Version)
+	fmt.Fprintf(w, "%s Aplicar en los orígenes expuestos:\n", prefix)
+	for _, origin := range plan.Origins {
+		fmt.Fprintf(w, "%s   %s (%s)\n", prefix, origin.IP, strings.Join(origin.Hosts, ", "))
+	}
+	fmt.Fprintln(w)
+}
+
+func renderNftables(w io.Writer, plan Plan) error {
+	header(w, "#", plan)
+
+	ports := joinPorts(plan.Ports, ", ")
+	fmt.Fprintln(w, "table inet cloudrip_lockdown {")
+	if len(plan.Ranges.IPv4) > 0 {
+		fmt.Fprintf(w, "\tset cloudflare_v4 {\n\t\ttype ipv4_addr\n\t\tflags interval\n\t\telements = { %s }\n\t}\n\n", strings.Join(plan.Ranges.IPv4, ", "))
+	}
+	if len(plan.Ranges.IPv6) > 0 {
+		fmt.Fprintf(w, "\tset cloudflare_v6 {\n\t\ttype ipv6_addr\n\t\tflags interval\n\t\telements = { %s }\n\t}\n\n", strings.Join(plan.Ranges.IPv6, ", "))
+	}
+	fmt.Fprintln(w, "\tchain input {")
+	fmt.Fprintln(w, "\t\ttype filter hook input priority -10; policy accept;")
+	if len(plan.Ranges.IPv4) > 0 {
+		fmt.Fprintf(w, "\t\tip saddr @cloudflare_v4 tcp dport { %s } accept\n", ports)
+	}
+	if len(plan.Ranges.IPv6) > 0 {
+		fmt.Fprintf(w, "\t\tip6 saddr @cloudflare_v6 tcp dport { %s } accept\n", ports)
+	}
+	fmt.Fprintf(w, "\t\ttcp dport { %s } drop\n", ports)
+	fmt.Fprintln(w, "\t}")
+	fmt.Fprintln(w, "}")
+	return nil
+}
+
+func renderIptables(w io.Writer, plan Plan) error {
+	fmt.Fprintln(w, "#!/bin/sh")
+	header(w, "#", plan)
+	fmt.Fprintln(w, "set -e")
+
+	ports := joinPorts(plan.Ports, ",")
+	for _, family := range []struct {
+		cmd   string
+		cidrs []string
+	}{
+		{"iptables", plan.Ranges.IPv4},
+		{"ip6tables", plan.Ranges.IPv6},
+	} {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "%s -N CLOUDRIP_LOCKDOWN 2>/dev/null || %s -F CLOUDRIP_LOCKDOWN\n", family.cmd, family.cmd)
+		for _, cidr := range family.cidrs {
+			fmt.Fprintf(w, "%s -A CLOUDRIP_LOCKDOWN -s %s -j RETURN\n", family.cmd, cidr)
+		}
+		fmt.Fprintf(w, "%s -A CLOUDRIP_LOCKDOWN -j DROP\n", family.cmd)
+		fmt.Fprintf(w, "%s -C INPUT -p tcp -m multiport --dports %s -j CLOUDRIP_LOCKDOWN 2>/dev/null || \\\n", family.cmd, ports)
+		fmt.Fprintf(w, "\t%s -I INPUT -p tcp -m multiport --dports %s -j CLOUDRIP_LOCKDOWN\n", family.cmd, ports)
+	}
+	return nil
+}
+
+func renderUFW(w io.Writer, plan Plan) error {
+	fmt.Fprintln(w, "#!/bin/sh")
+	header(w, "#", plan)
+	fmt.Fprintln(w, "set -e")
+	fmt.Fprintln(w)
+
+	// ufw evalúa en orden: primero los allow de Cloudflare, luego el deny
+	ports := joinPorts(plan.Ports, ",")
+	for _, cidr := range allCIDRs(plan.Ranges) {
+		fmt.Fprintf(w, "ufw allow proto tcp from %s to any port %s comment 'cloudflare'\n", cidr, ports)
+	}
+	fmt.Fprintf(w, "ufw deny proto tcp from any to any port %s comment 'cloudrip lockdown'\n", ports)
+	return nil
+}
+
+func renderNginx(w io.Writer, plan Plan) error {
+	header(w, "#", plan)
+	fmt.Fprintf(w, "# Incluir en los bloques server que escuchan en %s\n", joinPorts(plan.Ports, ", "))
+	for _, cidr := range allCIDRs(plan.Ranges) {
+		fmt.Fprintf(w, "allow %s;\n", cidr)
+	}
+	fmt.Fprintln(w, "deny all;")
+	return nil
+}
+
+func renderApache(w io.Writer, plan Plan) error {
+	header(w, "#", plan)
+	fmt.Fprintf(w, "# Incluir en los VirtualHost de %s (Apache 2.4, mod_authz_host)\n", joinPorts(plan.Ports, ", "))
+	fmt.Fprintln(w, "<RequireAny>")
+	for _, cidr := range allCIDRs(plan.Ranges) {
+		fmt.Fprintf(w, "    Require ip %s\n", cidr)
+	}
+	fmt.Fprintln(w, "</RequireAny>")
+	return nil
+}
+
+// awsIngress sigue el formato de aws ec2 authorize-security-group-ingress --cli-input-json
+type awsIngress struct {
+	GroupID       string          `json:"GroupId"`
+	IPPermissions []awsPermission `json:"IpPermissions"`
+}
+
+type awsPermission struct {
+	IPProtocol string         `json:"IpProtocol"`
+	FromPort   int            `json:"FromPort"`
+	ToPort     int            `json:"ToPort"`
+	IPRanges   []awsIPRange   `json:"IpRanges,omitempty"`
+	IPv6Ranges []awsIPv6Range `json:"Ipv6Ranges,omitempty"`
+}
+
+type awsIPRange struct {
+	CidrIP      string `json:"CidrIp"`
+	Description string `json:"Description"`
+}
+
+type awsIPv6Range struct {
+	CidrIPv6    string `json:"CidrIpv6"`
+	Description string `json:"Description"`
+}
+
+// renderAWSSG genera el ingreso de un security group solo desde Cloudflare;
+// el grupo debe asociarse a las instancias de los orígenes listados en el
+// resto de formatos
+func renderAWSSG(w io.Writer, plan Plan) error {
+	groupID := plan.SecurityGroup
+	if groupID == "" {
+		groupID = "sg-REPLACE_ME"
+	}
+
+	ingress := awsIngress{GroupID: groupID}
+	for _, port := range plan.Ports {
+		permission := awsPermission{IPProtocol: "tcp", FromPort: port, ToPort: port}
+		for _, cidr := range plan.Ranges.IPv4 {
+			permission.IPRanges = append(permission.IPRanges, awsIPRange{CidrIP: cidr, Description: "Cloudflare " + plan.Domain})
+		}
+		for _, cidr := range plan.Ranges.IPv6 {
+			permission.IPv6Ranges = append(permission.IPv6Ranges, awsIPv6Range{CidrIPv6: cidr, Description: "Cloudflare " + plan.Domain})
+		}
+		ingress.IPPermissions = append(ingress.IPPermissions, permission)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(ingress); err != nil {
+		return fmt.Errorf("escribiendo JSON: %w", err)
+	}
+	return nil
+}
+
+func allCIDRs(ranges domain.CFRanges) []string {
+	return append(append([]string(nil), ranges.IPv4...), ranges.IPv6...)
+}
+
+func joinPorts(ports []int, sep string) string {
+	parts := make([]string, len(ports))
+	for i, port := range ports {
+		parts[i] = strconv.Itoa(port)
+	}
+	return strings.Join(parts, sep)
+}
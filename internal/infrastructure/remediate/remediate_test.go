@@ -0,0 +1,114 @@
+package remediate
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+)
+
+var update = flag.Bool("update", false, "reescribe los archivos golden de testdata")
+
+func testPlan(ipv6 bool) Plan {
+	ranges := domain.CFRanges{
+		IPv4:   []string{"173.245.48.0/20", "104.16.0.0/13"},
+		Source: domain.RangeSource{Provider: "cloudflare", Source: domain.RangesSourceFile, Version: "test"},
+	}
+	if ipv6 {
+		ranges.IPv6 = []string{"2400:cb00::/32", "2606:4700::/32"}
+	}
+	return Plan{
+		Domain: "example.com",
+		Ranges: ranges,
+		Ports:  []int{80, 443},
+		Origins: []Origin{
+			{IP: "192.0.2.10", Hosts: []string{"api.example.com", "www.example.com"}},
+			{IP: "2001:db8::10", Hosts: []string{"www.example.com"}},
+		},
+		SecurityGroup: "sg-0123456789abcdef0",
+		GeneratedAt:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func TestRenderGolden(t *testing.T) {
+	variants := []struct {
+		name string
+		ipv6 bool
+	}{
+		{"ipv4", false},
+		{"dual", true},
+	}
+	for _, format := range Formats {
+		for _, variant := range variants {
+			t.Run(format+"/"+variant.name, func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := Render(&buf, format, testPlan(variant.ipv6)); err != nil {
+					t.Fatal(err)
+				}
+
+				golden := filepath.Join("testdata", format+"-"+variant.name+".golden")
+				if *update {
+					if err := os.WriteFile(golden, buf.Bytes(), 0o644); err != nil {
+						t.Fatal(err)
+					}
+				}
+				want, err := os.ReadFile(golden)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(buf.Bytes(), want) {
+					t.Errorf("%s difiere del golden (go test -update para regenerar):\n%s", golden, buf.String())
+				}
+			})
+		}
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	plan := testPlan(true)
+	if err := Render(&bytes.Buffer{}, "pf", plan); err == nil {
+		t.Error("se esperaba error con un formato desconocido")
+	}
+
+	noPorts := plan
+	noPorts.Ports = nil
+	if err := Render(&bytes.Buffer{}, FormatNginx, noPorts); err == nil {
+		t.Error("se esperaba error sin puertos")
+	}
+
+	noRanges := plan
+	noRanges.Ranges = domain.CFRanges{}
+	if err := Render(&bytes.Buffer{}, FormatNginx, noRanges); err == nil {
+		t.Error("se esperaba error sin rangos")
+	}
+}
+
+func TestOrigins(t *testing.T) {
+	result := &domain.ScanResult{Results: map[string][]domain.ResultEntry{
+		"www.example.com": {
+			{IP: "192.0.2.10"}, {IP: "2001:db8::10"},
+			{IP: "104.16.1.1", Provider: "cloudflare", Proxied: true},
+		},
+		"api.example.com":    {{IP: "192.0.2.10"}, {IP: "192.0.2.9"}},
+		"tunnel.example.com": {{Type: "CNAME", Provider: "cloudflare", Product: domain.CFProductTunnel}},
+	}}
+
+	origins := Origins(result)
+	want := []Origin{
+		{IP: "192.0.2.9", Hosts: []string{"api.example.com"}},
+		{IP: "192.0.2.10", Hosts: []string{"api.example.com", "www.example.com"}},
+		{IP: "2001:db8::10", Hosts: []string{"www.example.com"}},
+	}
+	if len(origins) != len(want) {
+		t.Fatalf("Origins = %+v", origins)
+	}
+	for i := range want {
+		if origins[i].IP != want[i].IP || len(origins[i].Hosts) != len(want[i].Hosts) || origins[i].Hosts[0] != want[i].Hosts[0] {
+			t.Errorf("Origins[%d] = %+v, esperado %+v", i, origins[i], want[i])
+		}
+	}
+}
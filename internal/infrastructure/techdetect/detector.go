@@ -0,0 +1,372 @@
+package techdetect
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/rs/zerolog"
+)
+
+//go:embed data/technologies.json
+var bundledRules []byte
+
+var (
+	metaTagPattern   = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+	scriptTagPattern = regexp.MustCompile(`(?is)<script\s[^>]*>`)
+	// Referencia a un grupo en la plantilla de versión: \1 o \1?si:no
+	versionGroupPattern = regexp.MustCompile(`\\(\d)(?:\?([^:]*):(.*))?`)
+	groupRefPattern     = regexp.MustCompile(`\\\d`)
+	// Atributos leídos de meta y script
+	attrPatterns = map[string]*regexp.Regexp{
+		"name":     attrPattern("name"),
+		"property": attrPattern("property"),
+		"content":  attrPattern("content"),
+		"src":      attrPattern("src"),
+	}
+)
+
+func attrPattern(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)\s` + name + `\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+))`)
+}
+
+// stringList acepta un string o una lista de strings, como las reglas de
+// Wappalyzer
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = stringList{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// ruleFile es una tecnología en el formato de Wappalyzer; los campos no
+// soportados (cats, js, dom, website...) se ignoran
+type ruleFile struct {
+	Headers   map[string]stringList `json:"headers"`
+	Cookies   map[string]stringList `json:"cookies"`
+	Meta      map[string]stringList `json:"meta"`
+	ScriptSrc stringList            `json:"scriptSrc"`
+	HTML      stringList            `json:"html"`
+	Implies   stringList            `json:"implies"`
+}
+
+// pattern es una expresión de una regla con su plantilla de versión
+// (\;version:\1)
+type pattern struct {
+	re      *regexp.Regexp
+	version string
+}
+
+type technology struct {
+	name      string
+	headers   map[string][]pattern
+	cookies   map[string][]pattern
+	meta      map[string][]pattern
+	scriptSrc []pattern
+	html      []pattern
+	implies   []string
+}
+
+// Detector reconoce tecnologías en respuestas HTTP con reglas estilo
+// Wappalyzer: cabeceras, cookies, meta tags, src de scripts y regex sobre el
+// HTML. Las reglas se cargan antes de detectar; Detect no toma locks.
+type Detector struct {
+	techs  map[string]*technology
+	logger zerolog.Logger
+}
+
+// NewDetector crea un detector con las reglas incluidas en el binario
+func NewDetector(logger zerolog.Logger) (*Detector, error) {
+	d := &Detector{
+		techs:  make(map[string]*technology),
+		logger: logger.With().Str("component", "techdetect").Logger(),
+	}
+	if err := d.load(bundledRules, "bundled"); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// LoadFile agrega las reglas de un archivo JSON. Una tecnología con el mismo
+// nombre que una existente la reemplaza.
+func (d *Detector) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("leyendo archivo de reglas: %w", err)
+	}
+	return d.load(data, path)
+}
+
+// load acepta un objeto nombre → tecnología o uno con la clave
+// "technologies" (o "apps", el formato antiguo de Wappalyzer)
+func (d *Detector) load(data []byte, source string) error {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return fmt.Errorf("parseando reglas (%s): %w", source, err)
+	}
+	for _, key := range []string{"technologies", "apps"} {
+		if nested, ok := top[key]; ok {
+			top = nil
+			if err := json.Unmarshal(nested, &top); err != nil {
+				return fmt.Errorf("parseando reglas (%s): %w", source, err)
+			}
+			break
+		}
+	}
+
+	skipped := 0
+	for name, raw := range top {
+		var rule ruleFile
+		if err := json.Unmarshal(raw, &rule); err != nil {
+			return fmt.Errorf("parseando regla %q (%s): %w", name, source, err)
+		}
+		tech, invalid := compile(name, rule)
+		skipped += invalid
+		d.techs[name] = tech
+	}
+
+	// Expresiones de JavaScript que RE2 no soporta (lookahead, backreferences)
+	if skipped > 0 {
+		d.logger.Debug().Str("source", source).Int("skipped", skipped).Msg("Expresiones de reglas no soportadas")
+	}
+	d.logger.Debug().Str("source", source).Int("technologies", len(top)).Msg("Reglas de tecnologías cargadas")
+	return nil
+}
+
+// compile compila las expresiones de una regla y retorna cuántas se
+// descartaron por inválidas
+func compile(name string, rule ruleFile) (*technology, int) {
+	invalid := 0
+	compileList := func(values []string) []pattern {
+		var patterns []pattern
+		for _, value := range values {
+			p, err := parsePattern(value)
+			if err != nil {
+				invalid++
+				continue
+			}
+			patterns = append(patterns, p)
+		}
+		return patterns
+	}
+	compileMap := func(values map[string]stringList) map[string][]pattern {
+		compiled := make(map[string][]pattern, len(values))
+		for key, list := range values {
+			if patterns := compileList(list); len(patterns) > 0 {
+				compiled[strings.ToLower(key)] = patterns
+			}
+		}
+		return compiled
+	}
+
+	tech := &technology{
+		name:      name,
+		headers:   compileMap(rule.Headers),
+		cookies:   compileMap(rule.Cookies),
+		meta:      compileMap(rule.Meta),
+		scriptSrc: compileList(rule.ScriptSrc),
+		html:      compileList(rule.HTML),
+	}
+	for _, implied := range rule.Implies {
+		// Las implicaciones también admiten \;confidence:N
+		implied, _, _ = strings.Cut(implied, `\;`)
+		tech.implies = append(tech.implies, implied)
+	}
+	return tech, invalid
+}
+
+// parsePattern separa la expresión de sus etiquetas (\;version:\1,
+// \;confidence:50); las expresiones no distinguen mayúsculas
+func parsePattern(value string) (pattern, error) {
+	parts := strings.Split(value, `\;`)
+	re, err := regexp.Compile("(?i)" + parts[0])
+	if err != nil {
+		return pattern{}, err
+	}
+	p := pattern{re: re}
+	for _, tag := range parts[1:] {
+		if version, ok := strings.CutPrefix(tag, "version:"); ok {
+			p.version = version
+		}
+	}
+	return p, nil
+}
+
+// match retorna si la expresión coincide con value y la versión extraída
+func (p pattern) match(value string) (bool, string) {
+	groups := p.re.FindStringSubmatch(value)
+	if groups == nil {
+		return false, ""
+	}
+	if p.version == "" {
+		return true, ""
+	}
+	version := versionGroupPattern.ReplaceAllStringFunc(p.version, func(ref string) string {
+		m := versionGroupPattern.FindStringSubmatch(ref)
+		group := submatch(groups, m[1])
+		if !strings.Contains(ref, "?") {
+			return group
+		}
+		// Ternario: la rama elegida puede volver a referir grupos
+		branch := m[3]
+		if group != "" {
+			branch = m[2]
+		}
+		return groupRefPattern.ReplaceAllStringFunc(branch, func(ref string) string {
+			return submatch(groups, ref[1:])
+		})
+	})
+	return true, strings.TrimSpace(version)
+}
+
+// submatch retorna el grupo index (en texto) o vacío si no existe
+func submatch(groups []string, index string) string {
+	i, _ := strconv.Atoi(index)
+	if i < len(groups) {
+		return groups[i]
+	}
+	return ""
+}
+
+// page son las partes de una respuesta que miran las reglas
+type page struct {
+	header  http.Header
+	cookies map[string]string
+	meta    map[string][]string
+	scripts []string
+	html    string
+}
+
+func newPage(header http.Header, body []byte) page {
+	p := page{
+		header:  header,
+		cookies: make(map[string]string),
+		meta:    make(map[string][]string),
+		html:    string(body),
+	}
+	for _, cookie := range (&http.Response{Header: header}).Cookies() {
+		p.cookies[strings.ToLower(cookie.Name)] = cookie.Value
+	}
+	for _, tag := range metaTagPattern.FindAllString(p.html, -1) {
+		name := attr(tag, "name")
+		if name == "" {
+			name = attr(tag, "property")
+		}
+		if name != "" {
+			name = strings.ToLower(name)
+			p.meta[name] = append(p.meta[name], attr(tag, "content"))
+		}
+	}
+	for _, tag := range scriptTagPattern.FindAllString(p.html, -1) {
+		if src := attr(tag, "src"); src != "" {
+			p.scripts = append(p.scripts, src)
+		}
+	}
+	return p
+}
+
+// attr retorna el valor de un atributo de una etiqueta HTML
+func attr(tag, name string) string {
+	m := attrPatterns[name].FindStringSubmatch(tag)
+	if m == nil {
+		return ""
+	}
+	return m[1] + m[2] + m[3]
+}
+
+// Detect retorna las tecnologías de una respuesta, con las implicadas por
+// ellas, ordenadas por nombre
+func (d *Detector) Detect(header http.Header, body []byte) []domain.Technology {
+	p := newPage(header, body)
+
+	found := make(map[string]string)
+	for name, tech := range d.techs {
+		if ok, version := tech.detect(p); ok {
+			found[name] = version
+		}
+	}
+
+	// Las implicadas se agregan sin versión si no se detectaron por sí mismas
+	pending := make([]string, 0, len(found))
+	for name := range found {
+		pending = append(pending, name)
+	}
+	for len(pending) > 0 {
+		name := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		tech, ok := d.techs[name]
+		if !ok {
+			continue
+		}
+		for _, implied := range tech.implies {
+			if _, ok := found[implied]; !ok {
+				found[implied] = ""
+				pending = append(pending, implied)
+			}
+		}
+	}
+
+	techs := make([]domain.Technology, 0, len(found))
+	for name, version := range found {
+		techs = append(techs, domain.Technology{Name: name, Version: version})
+	}
+	sort.Slice(techs, func(i, j int) bool {
+		return techs[i].Name < techs[j].Name
+	})
+	return techs
+}
+
+// detect evalúa todas las expresiones de la tecnología; entre las versiones
+// encontradas gana la más precisa (la más larga)
+func (t *technology) detect(p page) (bool, string) {
+	matched, version := false, ""
+	check := func(patterns []pattern, value string) {
+		for _, pat := range patterns {
+			if ok, v := pat.match(value); ok {
+				matched = true
+				if len(v) > len(version) || (len(v) == len(version) && v > version) {
+					version = v
+				}
+			}
+		}
+	}
+
+	for name, patterns := range t.headers {
+		for _, value := range p.header.Values(name) {
+			check(patterns, value)
+		}
+	}
+	for name, patterns := range t.cookies {
+		if value, ok := p.cookies[name]; ok {
+			check(patterns, value)
+		}
+	}
+	for name, patterns := range t.meta {
+		for _, value := range p.meta[name] {
+			check(patterns, value)
+		}
+	}
+	for _, src := range p.scripts {
+		check(t.scriptSrc, src)
+	}
+	if len(t.html) > 0 {
+		check(t.html, p.html)
+	}
+	return matched, version
+}
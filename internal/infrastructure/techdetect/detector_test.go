@@ -0,0 +1,96 @@
+package techdetect
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/rs/zerolog"
+)
+
+func detected(techs []domain.Technology) map[string]string {
+	found := make(map[string]string, len(techs))
+	for _, tech := range techs {
+		found[tech.Name] = tech.Version
+	}
+	return found
+}
+
+func TestDetect(t *testing.T) {
+	detector, err := NewDetector(zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := http.Header{}
+	header.Set("Server", "nginx/1.18.0")
+	header.Set("X-Powered-By", "PHP/7.4.3")
+	header.Add("Set-Cookie", "laravel_session=abc; Path=/; HttpOnly")
+	body := []byte(`<html><head>
+<meta name="generator" content="WordPress 5.8.1">
+<script src="/wp-includes/js/jquery/jquery.min.js?ver=3.6.0"></script>
+</head><body></body></html>`)
+
+	found := detected(detector.Detect(header, body))
+	want := map[string]string{
+		"Nginx":     "1.18.0",
+		"PHP":       "7.4.3",
+		"Laravel":   "",
+		"WordPress": "5.8.1",
+		"jQuery":    "3.6.0",
+		"MySQL":     "", // implicada por WordPress
+	}
+	for name, version := range want {
+		got, ok := found[name]
+		if !ok {
+			t.Errorf("no se detectó %s (detectadas %v)", name, found)
+			continue
+		}
+		if got != version {
+			t.Errorf("%s versión = %q, esperada %q", name, got, version)
+		}
+	}
+	if _, ok := found["Microsoft IIS"]; ok {
+		t.Errorf("falso positivo: %v", found)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	detector, err := NewDetector(zerolog.Nop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Formato con la clave technologies; la expresión con lookahead no es
+	// válida en RE2 y se descarta sin fallar
+	rules := `{"technologies": {
+		"Acme Panel": {
+			"headers": {"X-Acme": "^v([\\d.]+)\\;version:\\1?\\1:unknown"},
+			"html": ["(?=acme)", "<title>Acme</title>"],
+			"implies": "Nginx\\;confidence:50"
+		}
+	}}`
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := detector.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	header := http.Header{}
+	header.Set("X-Acme", "v2.1")
+	found := detected(detector.Detect(header, []byte("<title>Acme</title>")))
+	if found["Acme Panel"] != "2.1" {
+		t.Errorf("Acme Panel versión = %q, detectadas %v", found["Acme Panel"], found)
+	}
+	if _, ok := found["Nginx"]; !ok {
+		t.Errorf("se esperaba Nginx implicada: %v", found)
+	}
+
+	if err := detector.LoadFile(filepath.Join(t.TempDir(), "no-existe.json")); err == nil {
+		t.Error("se esperaba error con un archivo inexistente")
+	}
+}
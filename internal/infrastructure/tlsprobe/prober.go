@@ -0,0 +1,100 @@
+package tlsprobe
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/rs/zerolog"
+)
+
+// DialFunc abre conexiones salientes (ej: network.Binder.DialContext)
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// Prober obtiene la cadena de certificados que presenta una IP
+type Prober struct {
+	dial    DialFunc
+	timeout time.Duration
+	logger  zerolog.Logger
+}
+
+func NewProber(dial DialFunc, timeout time.Duration, logger zerolog.Logger) *Prober {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	return &Prober{
+		dial:    dial,
+		timeout: timeout,
+		logger:  logger.With().Str("component", "tlsprobe").Logger(),
+	}
+}
+
+// Certificates hace un handshake TLS con ip:port y retorna la cadena
+// presentada. Con serverName vacío no se envía SNI: el servidor responde con
+// su certificado por defecto. Un timeout de 0 no limita el handshake, como
+// en httpprobe con el mismo -probe-timeout.
+func (p *Prober) Certificates(ctx context.Context, ip string, port int, serverName string) ([]domain.CertInfo, error) {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	address := net.JoinHostPort(ip, strconv.Itoa(port))
+	raw, err := p.dial(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("conectando a %s: %w", address, err)
+	}
+
+	conn := tls.Client(raw, &tls.Config{
+		ServerName: serverName,
+		// Se inspecciona el certificado, no se confía en él
+		InsecureSkipVerify: true,
+	})
+	defer func() {
+		if err := conn.Close(); err != nil {
+			p.logger.Debug().Err(err).Str("address", address).Msg("Error cerrando conexión TLS")
+		}
+	}()
+
+	if err := conn.HandshakeContext(ctx); err != nil {
+		return nil, fmt.Errorf("handshake TLS con %s: %w", address, err)
+	}
+
+	peers := conn.ConnectionState().PeerCertificates
+	chain := make([]domain.CertInfo, 0, len(peers))
+	for _, cert := range peers {
+		chain = append(chain, Describe(cert))
+	}
+	return chain, nil
+}
+
+// Describe extrae los campos de un certificado usados para el matching
+func Describe(cert *x509.Certificate) domain.CertInfo {
+	spki := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	info := domain.CertInfo{
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		Serial:    cert.SerialNumber.Text(16),
+		SPKI:      hex.EncodeToString(spki[:]),
+		NotBefore: cert.NotBefore.UTC(),
+		NotAfter:  cert.NotAfter.UTC(),
+	}
+	info.SANs = append(info.SANs, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+	// Sin SANs, algunos clientes aún usan el CN como nombre
+	if len(info.SANs) == 0 && cert.Subject.CommonName != "" {
+		info.SANs = []string{cert.Subject.CommonName}
+	}
+	return info
+}
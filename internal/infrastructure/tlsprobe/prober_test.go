@@ -0,0 +1,110 @@
+package tlsprobe
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func hostPort(t *testing.T, address string) (string, int) {
+	t.Helper()
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return host, port
+}
+
+func TestCertificates(t *testing.T) {
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+	ip, port := hostPort(t, server.Listener.Addr().String())
+
+	// Un timeout de 0 no limita el handshake
+	for _, prober := range []*Prober{NewProber(nil, 5*time.Second, zerolog.Nop()), NewProber(nil, 0, zerolog.Nop())} {
+		for _, sni := range []string{"", "example.com"} {
+			chain, err := prober.Certificates(context.Background(), ip, port, sni)
+			if err != nil {
+				t.Fatalf("sni %q: %v", sni, err)
+			}
+			if len(chain) == 0 {
+				t.Fatalf("sni %q: cadena vacía", sni)
+			}
+			want := Describe(server.Certificate())
+			if chain[0].SPKI != want.SPKI || chain[0].Serial != want.Serial || !slices.Equal(chain[0].SANs, want.SANs) {
+				t.Errorf("sni %q: certificado = %+v, esperado %+v", sni, chain[0], want)
+			}
+			// El certificado de httptest cubre example.com y las direcciones de loopback
+			for _, san := range []string{"example.com", "127.0.0.1", "::1"} {
+				if !slices.Contains(chain[0].SANs, san) {
+					t.Errorf("sni %q: falta SAN %s en %v", sni, san, chain[0].SANs)
+				}
+			}
+		}
+	}
+}
+
+func TestCertificatesErrors(t *testing.T) {
+	prober := NewProber(nil, 2*time.Second, zerolog.Nop())
+
+	// Puerto cerrado: error de conexión (el escaneo no reintenta con SNI)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ip, port := hostPort(t, listener.Addr().String())
+	listener.Close()
+
+	_, err = prober.Certificates(context.Background(), ip, port, "")
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || opErr.Op != "dial" {
+		t.Errorf("puerto cerrado: err = %v, esperado error de dial", err)
+	}
+
+	// Servicio que no habla TLS
+	plain, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	go func() {
+		for {
+			conn, err := plain.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+			conn.Close()
+		}
+	}()
+	ip, port = hostPort(t, plain.Addr().String())
+	if _, err := prober.Certificates(context.Background(), ip, port, ""); err == nil {
+		t.Error("se esperaba error de handshake")
+	}
+}
+
+func TestDescribeCommonNameFallback(t *testing.T) {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "legacy.example.com"}}
+	if info := Describe(cert); !slices.Equal(info.SANs, []string{"legacy.example.com"}) {
+		t.Errorf("SANs = %v", info.SANs)
+	}
+
+	cert.DNSNames = []string{"www.example.com"}
+	cert.IPAddresses = []net.IP{net.ParseIP("192.0.2.1")}
+	if info := Describe(cert); !slices.Equal(info.SANs, []string{"www.example.com", "192.0.2.1"}) {
+		t.Errorf("SANs = %v", info.SANs)
+	}
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+type CloudRangesConfig struct {
+	Dir       string
+	AWSURL    string
+	GCPURL    string
+	AzureURL  string
+	OracleURL string
+	SourceIP  string
+	Interface string
+	Timeout   time.Duration
+}
+
+// ParseCloudRangesFlags parsea los flags del subcomando cloud-ranges
+func ParseCloudRangesFlags(args []string, defaults map[string]string) (*CloudRangesConfig, error) {
+	var cfg CloudRangesConfig
+
+	fs := flag.NewFlagSet("cloud-ranges", flag.ContinueOnError)
+	fs.StringVar(&cfg.Dir, "dir", "", "Directorio donde guardar los archivos de rangos [requerido]")
+	fs.StringVar(&cfg.AWSURL, "aws-url", defaults["aws"], "URL de ip-ranges.json de AWS (vacío para omitir)")
+	fs.StringVar(&cfg.GCPURL, "gcp-url", defaults["gcp"], "URL de cloud.json de GCP (vacío para omitir)")
+	fs.StringVar(&cfg.AzureURL, "azure-url", "", "URL de ServiceTags_Public_AAAAMMDD.json de Azure (no tiene URL estable)")
+	fs.StringVar(&cfg.OracleURL, "oracle-url", defaults["oracle"], "URL de public_ip_ranges.json de Oracle (vacío para omitir)")
+	fs.StringVar(&cfg.SourceIP, "source-ip", "", "IP local de origen para las descargas")
+	fs.StringVar(&cfg.Interface, "interface", "", "Interfaz local de origen para las descargas")
+	fs.DurationVar(&cfg.Timeout, "timeout", 60*time.Second, "Timeout por descarga")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("el flag -dir es requerido")
+	}
+
+	return &cfg, nil
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// stringList es un flag repetible (-fail a -fail b)
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+type FixtureDNSConfig struct {
+	ZoneFile string
+	Origin   string
+	Listen   string
+	Failures []string
+	NoAXFR   bool
+}
+
+// ParseFixtureDNSFlags parsea los flags del subcomando fixture-dns
+func ParseFixtureDNSFlags(args []string) (*FixtureDNSConfig, error) {
+	var cfg FixtureDNSConfig
+	var failures stringList
+
+	fs := flag.NewFlagSet("fixture-dns", flag.ContinueOnError)
+	fs.StringVar(&cfg.ZoneFile, "zone", "", "Archivo de zona en formato maestro RFC 1035 [requerido]")
+	fs.StringVar(&cfg.Origin, "origin", "", "Origen de la zona si el archivo no declara $ORIGIN")
+	fs.StringVar(&cfg.Listen, "listen", "127.0.0.1:5353", "Dirección UDP/TCP de escucha")
+	fs.Var(&failures, "fail", "Regla de falla patrón[/TIPO]=acción (servfail|refused|nxdomain|drop|truncate|delay:200ms), repetible")
+	fs.BoolVar(&cfg.NoAXFR, "no-axfr", false, "Rechazar transferencias de zona (AXFR)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	cfg.Failures = failures
+
+	if cfg.ZoneFile == "" {
+		return nil, fmt.Errorf("el flag -zone es requerido")
+	}
+
+	return &cfg, nil
+}
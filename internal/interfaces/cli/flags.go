@@ -3,9 +3,13 @@ package cli
 import (
 	"flag"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/alexperezortuno/cloudrip/internal/core/domain"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/config"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/portscan"
 )
 
 type CLIConfig struct {
@@ -30,8 +34,68 @@ func ParseFlags() (*CLIConfig, error) {
 	flag.DurationVar(&cliConfig.ScannerConfig.Timeout, "timeout", 5*time.Second, "Timeout por consulta DNS")
 	flag.DurationVar(&cliConfig.ScannerConfig.Delay, "delay", 0, "Delay por job (throttling)")
 	flag.BoolVar(&cliConfig.ScannerConfig.FollowCNAME, "follow-cname", false, "Seguir un nivel de CNAME")
-	flag.BoolVar(&cliConfig.ScannerConfig.IncludeCF, "include-cf", false, "Incluir IPs pertenecientes a Cloudflare en resultados")
+	flag.BoolVar(&cliConfig.ScannerConfig.IncludeCF, "include-cf", false, "Incluir IPs pertenecientes a Cloudflare en resultados (equivale a -include-providers cloudflare)")
+	flag.Func("include-providers", "Proveedores CDN/WAF cuyas IPs se incluyen en resultados, separados por coma (ej: cloudflare,fastly) o 'all'", func(value string) error {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				cliConfig.ScannerConfig.IncludeProviders = append(cliConfig.ScannerConfig.IncludeProviders, strings.ToLower(name))
+			}
+		}
+		return nil
+	})
+	flag.Var((*stringList)(&cliConfig.ScannerConfig.ProvidersFiles), "providers-file", "Archivo YAML/JSON con proveedores CDN/WAF adicionales, repetible")
+	flag.StringVar(&cliConfig.ScannerConfig.CloudRangesDir, "cloud-ranges-dir", "", "Directorio con rangos AWS/GCP/Azure/Oracle para atribuir IPs a clouds (ver cloudrip cloud-ranges)")
+	flag.BoolVar(&cliConfig.ScannerConfig.HTTPProbe, "http-probe", false, "Confirmar el CDN/WAF de cada IP por cabeceras HTTP (cf-ray, x-amz-cf-id...) y marcar discrepancias con los rangos")
+	flag.BoolVar(&cliConfig.ScannerConfig.Enrich, "enrich", false, "Visitar cada host resuelto por HTTPS y HTTP y registrar estado, URL final, redirecciones, título, servidor, tamaño y tiempo")
+	flag.IntVar(&cliConfig.ScannerConfig.EnrichThreads, "enrich-threads", 20, "Visitas HTTP simultáneas con -enrich")
+	flag.DurationVar(&cliConfig.ScannerConfig.EnrichTimeout, "enrich-timeout", 10*time.Second, "Timeout por petición de -enrich")
+	flag.BoolVar(&cliConfig.ScannerConfig.Tech, "tech", false, "Detectar tecnologías y versiones de cada host con reglas estilo Wappalyzer (visita los hosts como -enrich)")
+	flag.StringVar(&cliConfig.ScannerConfig.TechRules, "tech-rules", "", "Archivo JSON de reglas de tecnologías estilo Wappalyzer que se agregan a las incluidas (con -tech)")
+	flag.DurationVar(&cliConfig.ScannerConfig.ProbeTimeout, "probe-timeout", 5*time.Second, "Timeout por sondeo HTTP y handshake TLS (0: sin límite)")
+	flag.BoolVar(&cliConfig.ScannerConfig.VerifyOrigins, "verify-origins", false, "Verificar IPs expuestas como origen de los hosts detrás de un CDN comparando respuestas (confirmed|likely|unrelated)")
+	flag.IntVar(&cliConfig.ScannerConfig.MaxCandidates, "max-candidates", 50, "Máximo de IPs candidatas a verificar")
+	flag.BoolVar(&cliConfig.ScannerConfig.SPF, "spf", false, "Buscar IPs de origen en el SPF (include:, redirect=, a, mx) y los MX del dominio")
+	flag.IntVar(&cliConfig.ScannerConfig.SPFMaxLookups, "spf-max-lookups", 10, "Máximo de consultas DNS al expandir el SPF (RFC 7208: 10)")
+	flag.BoolVar(&cliConfig.ScannerConfig.Score, "score", false, "Combinar las señales de cada IP candidata en un puntaje de origen por host")
+	flag.Func("score-weights", "Pesos de las señales de -score (ej: http=4,tls=3,favicon=2,spf=1.5,ptr=1,dns=1,hosting=0.5)", func(value string) error {
+		weights, err := parseWeights(value)
+		cliConfig.ScannerConfig.ScoreWeights = weights
+		return err
+	})
+	flag.IntVar(&cliConfig.ScannerConfig.TopCandidates, "top", 5, "Candidatos a origen reportados por host con -score")
+	flag.BoolVar(&cliConfig.ScannerConfig.Favicons, "favicons", false, "Comparar el favicon del sitio (mmh3/SHA-256) con el de las IPs candidatas")
+	flag.BoolVar(&cliConfig.ScannerConfig.TLSProbe, "tls-probe", false, "Obtener certificados TLS de las IPs candidatas (con y sin SNI) y compararlos con el dominio")
+	flag.BoolVar(&cliConfig.ScannerConfig.PortScan, "port-scan", false, "Escanear puertos TCP (connect) de las IPs candidatas; los abiertos guían -verify-origins y -tls-probe")
+	flag.StringVar(&cliConfig.ScannerConfig.ScanPorts, "scan-ports", "top", "Puertos para -port-scan: números, rangos (9000-9010) y conjuntos (cloudflare, web, admin, top)")
+	flag.IntVar(&cliConfig.ScannerConfig.PortConcurrency, "port-concurrency", 200, "Conexiones TCP simultáneas de -port-scan")
+	flag.DurationVar(&cliConfig.ScannerConfig.PortTimeout, "port-timeout", 1500*time.Millisecond, "Timeout por conexión de -port-scan")
+	flag.BoolVar(&cliConfig.ScannerConfig.VHosts, "vhosts", false, "Buscar virtual hosts en los orígenes confirmados con Host de los resultados y del wordlist")
+	flag.IntVar(&cliConfig.ScannerConfig.VHostMax, "vhost-max", 500, "Máximo de nombres a probar por origen con -vhosts")
+	flag.BoolVar(&cliConfig.ScannerConfig.Sweep, "sweep", false, "Barrer el bloque de cada origen confirmado buscando IPs con el certificado del dominio")
+	flag.IntVar(&cliConfig.ScannerConfig.SweepPrefix, "sweep-prefix", 24, "Prefijo IPv4 del bloque a barrer (20-31; IPv6 usa /120)")
+	flag.IntVar(&cliConfig.ScannerConfig.SweepMax, "sweep-max", 256, "Máximo de direcciones a barrer en total (hasta 4096)")
+	flag.IntVar(&cliConfig.ScannerConfig.SweepRate, "sweep-rate", 20, "Handshakes TLS por segundo del barrido (hasta 100)")
+	cliConfig.ScannerConfig.TLSPorts = []int{443}
+	flag.Func("tls-ports", "Puertos HTTPS para -tls-probe y -sweep: números, rangos y conjuntos como en -scan-ports (por defecto 443)", func(value string) error {
+		ports, err := portscan.ParsePorts(value)
+		cliConfig.ScannerConfig.TLSPorts = ports
+		return err
+	})
+	flag.BoolVar(&cliConfig.ScannerConfig.CFAudit, "cf-audit", false, "Auditar la zona en la cuenta Cloudflare: registros sin proxy, con proxy fuera de Cloudflare y nombres no declarados")
+	flag.StringVar(&cliConfig.ScannerConfig.CFAPIToken, "cf-api-token", "", "Token de API de solo lectura (Zone:Read, DNS:Read); por defecto $CLOUDFLARE_API_TOKEN")
+	flag.StringVar(&cliConfig.ScannerConfig.CFAPIURL, "cf-api-url", "", "URL base de la API v4 de Cloudflare (por defecto https://api.cloudflare.com/client/v4)")
 	flag.BoolVar(&cliConfig.ScannerConfig.NoFetchCF, "no-fetch-cf", false, "No intentar actualizar CIDRs de Cloudflare desde Internet")
+	flag.StringVar(&cliConfig.ScannerConfig.CacheDir, "cache-dir", config.DefaultCacheDir(), "Directorio de caché de rangos (vacío para deshabilitar)")
+	flag.DurationVar(&cliConfig.ScannerConfig.RangesMaxAge, "ranges-max-age", 24*time.Hour, "Edad máxima de los rangos en caché antes de revalidar con la API")
+	flag.StringVar(&cliConfig.ScannerConfig.RangesFile, "ranges-file", "", "Archivo local de rangos Cloudflare (respuesta de la API, caché o lista de CIDRs); ignora API y caché")
+	flag.StringVar(&cliConfig.ScannerConfig.Resolver, "resolver", "", "Servidor DNS a usar (IP o IP:puerto); por defecto /etc/resolv.conf")
+	flag.StringVar(&cliConfig.ScannerConfig.SourceIP, "source-ip", "", "IP local de origen para el tráfico saliente (DNS/HTTP)")
+	flag.StringVar(&cliConfig.ScannerConfig.Interface, "interface", "", "Interfaz local de origen para el tráfico saliente (ej: eth1)")
+	flag.StringVar(&cliConfig.ScannerConfig.IPFamily, "ip-family", "any", "Familia de direcciones: any|ipv4|ipv6")
+	flag.StringVar(&cliConfig.ScannerConfig.RecordDNS, "record-dns", "", "Grabar preguntas/respuestas DNS en un transcript (.jsonl o .jsonl.gz)")
+	flag.StringVar(&cliConfig.ScannerConfig.ReplayDNS, "replay-dns", "", "Reproducir respuestas DNS desde un transcript, sin red")
+	flag.StringVar(&cliConfig.ScannerConfig.PcapFile, "pcap", "", "Exportar consultas/respuestas DNS a un archivo pcapng")
+	flag.StringVar(&cliConfig.ScannerConfig.Faults, "faults", "", "Inyectar fallas DNS (ej: latency=uniform:10ms-200ms,timeout=5%,servfail=2%,truncate=1%,drop=3%,seed=42)")
 
 	// Flags adicionales
 	flag.StringVar(&cliConfig.ConfigFile, "config", "", "Ruta al archivo de configuración YAML/JSON")
@@ -57,3 +121,17 @@ func ParseFlags() (*CLIConfig, error) {
 
 	return &cliConfig, nil
 }
+
+// parseWeights interpreta una lista señal=peso separada por comas
+func parseWeights(value string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, field := range strings.Split(value, ",") {
+		name, raw, ok := strings.Cut(strings.TrimSpace(field), "=")
+		weight, err := strconv.ParseFloat(raw, 64)
+		if !ok || name == "" || err != nil {
+			return nil, fmt.Errorf("peso inválido: %q (formato señal=peso)", field)
+		}
+		weights[strings.ToLower(name)] = weight
+	}
+	return weights, nil
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/config"
+)
+
+// Acciones del subcomando ranges
+const (
+	RangesShow  = "show"
+	RangesCheck = "check"
+	RangesDiff  = "diff"
+)
+
+type RangesConfig struct {
+	Action         string
+	Provider       string
+	Format         string
+	CacheDir       string
+	MaxAge         time.Duration
+	RangesFile     string
+	NoFetch        bool
+	ProvidersFiles []string
+	From           string
+	To             string
+	Args           []string
+}
+
+// ParseRangesFlags parsea el subcomando ranges: cloudrip ranges <show|check|diff> [flags] [args]
+func ParseRangesFlags(args []string) (*RangesConfig, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("uso: cloudrip ranges <show|check|diff> [flags]")
+	}
+
+	cfg := RangesConfig{Action: args[0]}
+	switch cfg.Action {
+	case RangesShow, RangesCheck, RangesDiff:
+	default:
+		return nil, fmt.Errorf("acción inválida: %s. Debe ser 'show', 'check' o 'diff'", cfg.Action)
+	}
+
+	var providersFiles stringList
+
+	fs := flag.NewFlagSet("ranges "+cfg.Action, flag.ContinueOnError)
+	fs.StringVar(&cfg.Provider, "provider", "cloudflare", "Proveedor a usar ('all' para todos en show/check)")
+	fs.StringVar(&cfg.Format, "format", "text", "Formato de salida: text|json")
+	fs.StringVar(&cfg.CacheDir, "cache-dir", config.DefaultCacheDir(), "Directorio de caché de rangos")
+	fs.DurationVar(&cfg.MaxAge, "ranges-max-age", 24*time.Hour, "Edad máxima de los rangos en caché antes de revalidar con la API")
+	fs.StringVar(&cfg.RangesFile, "ranges-file", "", "Archivo local de rangos Cloudflare; ignora API y caché")
+	fs.BoolVar(&cfg.NoFetch, "no-fetch", false, "No consultar la API de Cloudflare")
+	fs.Var(&providersFiles, "providers-file", "Archivo YAML/JSON con proveedores CDN/WAF adicionales, repetible")
+	fs.StringVar(&cfg.From, "from", "cache", "diff: origen base (cache|fetch|effective|archivo)")
+	fs.StringVar(&cfg.To, "to", "fetch", "diff: origen a comparar (cache|fetch|effective|archivo)")
+
+	if err := fs.Parse(args[1:]); err != nil {
+		return nil, err
+	}
+	cfg.ProvidersFiles = providersFiles
+	cfg.Args = fs.Args()
+
+	switch cfg.Format {
+	case "text", "json":
+	default:
+		return nil, fmt.Errorf("formato inválido: %s. Debe ser 'text' o 'json'", cfg.Format)
+	}
+	if cfg.Action == RangesDiff && cfg.Provider == "all" {
+		return nil, fmt.Errorf("diff requiere un único proveedor")
+	}
+
+	return &cfg, nil
+}
@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/config"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/portscan"
+	"github.com/alexperezortuno/cloudrip/internal/infrastructure/remediate"
+)
+
+// RemediateAll genera todos los formatos en el directorio -o
+const RemediateAll = "all"
+
+type RemediateConfig struct {
+	Results       string
+	Formats       []string
+	Output        string
+	Ports         []int
+	SecurityGroup string
+	CacheDir      string
+	MaxAge        time.Duration
+	RangesFile    string
+	NoFetch       bool
+}
+
+// ParseRemediateFlags parsea el subcomando remediate: cloudrip remediate [flags] <resultados>
+func ParseRemediateFlags(args []string) (*RemediateConfig, error) {
+	var (
+		cfg    RemediateConfig
+		format string
+		ports  string
+	)
+
+	fs := flag.NewFlagSet("remediate", flag.ContinueOnError)
+	fs.StringVar(&format, "format", remediate.FormatNftables, "Formato: "+strings.Join(remediate.Formats, "|")+"|all")
+	fs.StringVar(&cfg.Output, "o", "", "Archivo de salida (por defecto stdout); con -format all, directorio [requerido]")
+	fs.StringVar(&ports, "ports", "80,443", "Puertos TCP a restringir a Cloudflare: números, rangos y conjuntos como en -scan-ports")
+	fs.StringVar(&cfg.SecurityGroup, "sg-id", "", "ID del security group para aws-sg (por defecto sg-REPLACE_ME)")
+	fs.StringVar(&cfg.CacheDir, "cache-dir", config.DefaultCacheDir(), "Directorio de caché de rangos")
+	fs.DurationVar(&cfg.MaxAge, "ranges-max-age", 24*time.Hour, "Edad máxima de los rangos en caché antes de revalidar con la API")
+	fs.StringVar(&cfg.RangesFile, "ranges-file", "", "Archivo local de rangos Cloudflare; ignora API y caché")
+	fs.BoolVar(&cfg.NoFetch, "no-fetch", false, "No consultar la API de Cloudflare")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if fs.NArg() != 1 {
+		return nil, fmt.Errorf("uso: cloudrip remediate [flags] <resultados.json|resultados.txt>")
+	}
+	cfg.Results = fs.Arg(0)
+
+	if format == RemediateAll {
+		if cfg.Output == "" {
+			return nil, fmt.Errorf("-format all requiere un directorio en -o")
+		}
+		cfg.Formats = remediate.Formats
+	} else {
+		if !slices.Contains(remediate.Formats, format) {
+			return nil, fmt.Errorf("formato inválido: %s. Debe ser %s o all", format, strings.Join(remediate.Formats, ", "))
+		}
+		cfg.Formats = []string{format}
+	}
+
+	var err error
+	if cfg.Ports, err = portscan.ParsePorts(ports); err != nil {
+		return nil, fmt.Errorf("-ports inválido: %w", err)
+	}
+
+	return &cfg, nil
+}
@@ -0,0 +1,33 @@
+package cli
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseRemediatePorts(t *testing.T) {
+	cfg, err := ParseRemediateFlags([]string{"-ports", "8443,80,8000-8002", "results.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{80, 8000, 8001, 8002, 8443}
+	if !slices.Equal(cfg.Ports, want) {
+		t.Errorf("Ports = %v, esperado %v", cfg.Ports, want)
+	}
+
+	for _, spec := range []string{"", "0", "80,abc", "9000-8000"} {
+		if _, err := ParseRemediateFlags([]string{"-ports", spec, "results.json"}); err == nil {
+			t.Errorf("-ports %q: se esperaba error", spec)
+		}
+	}
+}
+
+func TestParseRemediatePortSet(t *testing.T) {
+	cfg, err := ParseRemediateFlags([]string{"-ports", "cloudflare", "results.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(cfg.Ports, 2053) || !slices.Contains(cfg.Ports, 8443) {
+		t.Errorf("Ports = %v, esperado el conjunto cloudflare", cfg.Ports)
+	}
+}